@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/StepOne-ai/pvz_avito/internal/blob"
 	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/events"
+	"github.com/StepOne-ai/pvz_avito/internal/jobs"
 	"github.com/StepOne-ai/pvz_avito/internal/logger"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/notify"
 	"github.com/StepOne-ai/pvz_avito/internal/routes"
+	"github.com/StepOne-ai/pvz_avito/internal/scheduler"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -38,6 +46,7 @@ var Logger *logrus.Logger
 func main() {
 	prometheus.MustRegister(httpRequestsTotal)
 	prometheus.MustRegister(responseTimeHistogram)
+	prometheus.MustRegister(jobs.StaleReceptionsTotal)
 
 	Logger = logger.InitializeLogger()
 
@@ -46,6 +55,21 @@ func main() {
 	}
 	Logger.Info("Database initialized successfully")
 
+	routes.Notifier = notify.NewStub(Logger)
+	routes.BlobStore = newBlobStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobScheduler := scheduler.New(Logger)
+	jobScheduler.Register(&jobs.StorageDeadline{Notifier: routes.Notifier}, time.Hour)
+	jobScheduler.Register(&jobs.StaleReceptions{
+		Events:  events.NewLog(Logger),
+		Timeout: routes.StaleReceptionTimeout,
+		Action:  routes.StaleReceptionAction,
+	}, 10*time.Minute)
+	jobScheduler.Start(ctx)
+
 	r := gin.Default()
 
 	r.Use(func(c *gin.Context) {
@@ -58,6 +82,18 @@ func main() {
 		responseTimeHistogram.WithLabelValues(c.Request.Method, c.FullPath()).Observe(duration)
 	})
 
+	// Staging runs can check that responses still match the published document
+	if os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true" {
+		r.Use(routes.API.ResponseValidator(func(c *gin.Context, errs []models.FieldError) {
+			Logger.WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"path":   c.FullPath(),
+				"status": c.Writer.Status(),
+				"errors": errs,
+			}).Warn("Response does not match the OpenAPI document")
+		}))
+	}
+
 	routes.SetupRoutes(r)
 
 	r.GET("/metrics", func(c *gin.Context) {
@@ -67,3 +103,22 @@ func main() {
 	r.Run(":8080")
 	Logger.Info("Application started")
 }
+
+// newBlobStore keeps attachments in an S3-compatible bucket when S3_ENDPOINT
+// is set, and in the local attachments directory otherwise.
+func newBlobStore() blob.Store {
+	if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+		return &blob.S3Store{
+			Endpoint:  endpoint,
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+	}
+	store, err := blob.NewFileStore("attachments")
+	if err != nil {
+		log.Fatalf("Error creating attachment storage: %v", err)
+	}
+	return store
+}
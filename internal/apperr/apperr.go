@@ -0,0 +1,106 @@
+// Package apperr defines the errors the domain reports to its callers. Each
+// error has a kind, which a front end maps to its own status codes, and a
+// stable code that clients can branch on instead of parsing messages.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// Kind classifies an error by what the caller can do about it.
+type Kind string
+
+const (
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindTooLarge     Kind = "too_large"
+	KindUnsupported  Kind = "unsupported"
+	KindUnavailable  Kind = "unavailable"
+	KindInternal     Kind = "internal"
+)
+
+// Error is a domain error. Sentinel errors are *Error values, so errors.Is
+// matches them by identity, also when wrapped with %w for more detail.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Fields points at the parts of the input that failed validation.
+	Fields []models.FieldError
+	cause  error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func NotFound(code, message string) *Error     { return New(KindNotFound, code, message) }
+func Conflict(code, message string) *Error     { return New(KindConflict, code, message) }
+func Forbidden(code, message string) *Error    { return New(KindForbidden, code, message) }
+func Unauthorized(code, message string) *Error { return New(KindUnauthorized, code, message) }
+
+// Withf returns e with a more specific message; errors.Is still matches e.
+func (e *Error) Withf(format string, args ...interface{}) *Error {
+	detailed := *e
+	detailed.Message = fmt.Sprintf(format, args...)
+	detailed.cause = e
+	return &detailed
+}
+
+// Invalid reports input that fails validation, optionally per field.
+func Invalid(code, message string, fields ...models.FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+// InvalidReference turns a missing entity that the input refers to into
+// invalid input on field, keeping the error code. Other errors are returned
+// as they are.
+func InvalidReference(err error, field string) error {
+	if KindOf(err) != KindNotFound {
+		return err
+	}
+	e := From(err)
+	return Invalid(e.Code, e.Message, models.FieldError{Field: field, Message: e.Message})
+}
+
+// Internal hides err behind a generic message; the cause stays reachable
+// through errors.Unwrap for logging.
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal", Message: "internal error", cause: err}
+}
+
+// From returns the domain error behind err. When err wraps one with more
+// detail, the result keeps its kind and code but takes the full message.
+// Any other error is internal.
+func From(err error) *Error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return Internal(err)
+	}
+	if e.Kind == KindInternal || err.Error() == e.Message {
+		return e
+	}
+	wrapped := *e
+	wrapped.Message = err.Error()
+	wrapped.cause = err
+	return &wrapped
+}
+
+// KindOf returns the kind of err, KindInternal for errors of no kind.
+func KindOf(err error) Kind {
+	return From(err).Kind
+}
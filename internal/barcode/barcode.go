@@ -0,0 +1,64 @@
+package barcode
+
+import (
+	"fmt"
+)
+
+const (
+	FormatEAN13   = "EAN-13"
+	FormatCode128 = "Code128"
+)
+
+// maxCode128Length is the longest payload our scanners emit for Code128 labels.
+const maxCode128Length = 48
+
+// Validate detects the symbology of a scanned barcode and checks it.
+// Thirteen digits are treated as EAN-13 and must carry a valid check digit;
+// anything else must be a Code128 payload of printable ASCII characters.
+func Validate(code string) (string, error) {
+	if code == "" {
+		return "", fmt.Errorf("barcode is empty")
+	}
+	if len(code) == 13 && isDigits(code) {
+		if !validEAN13(code) {
+			return "", fmt.Errorf("invalid EAN-13 check digit in %s", code)
+		}
+		return FormatEAN13, nil
+	}
+
+	if len(code) > maxCode128Length {
+		return "", fmt.Errorf("barcode is longer than %d characters", maxCode128Length)
+	}
+	for _, r := range code {
+		if r < 0x20 || r > 0x7e {
+			return "", fmt.Errorf("barcode contains characters not encodable in Code128")
+		}
+	}
+	return FormatCode128, nil
+}
+
+// EAN13CheckDigit computes the check digit for the first twelve digits of an EAN-13 code.
+func EAN13CheckDigit(digits string) int {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(digits[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+func validEAN13(code string) bool {
+	return EAN13CheckDigit(code) == int(code[12]-'0')
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+var ErrAttachmentNotFound = apperr.NotFound("attachment_not_found", "attachment not found")
+
+const attachmentColumns = `id, entity_type, entity_id, kind, comment, file_name, content_type,
+    size_bytes, width, height, blob_key, thumbnail_key, uploaded_by, created_at`
+
+// InsertAttachments records uploaded images and notes them in the audit log of
+// the product or reception they belong to.
+func InsertAttachments(attachments []models.Attachment) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to insert attachments: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO attachments (` + attachmentColumns + `)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, a := range attachments {
+		_, err := tx.Exec(query, a.ID, a.EntityType, a.EntityId, a.Kind, a.Comment, a.FileName, a.ContentType,
+			a.SizeBytes, a.Width, a.Height, a.BlobKey, a.ThumbnailKey, a.UploadedBy, a.CreatedAt.Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("failed to insert attachment: %v", err)
+		}
+		details := map[string]interface{}{"attachmentId": a.ID, "kind": a.Kind}
+		if err := RecordAudit(tx, a.EntityType, a.EntityId, "attachment_added", a.UploadedBy, details); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to insert attachments: %v", err)
+	}
+	return nil
+}
+
+// GetAttachmentByID retrieves a single attachment.
+func GetAttachmentByID(id string) (*models.Attachment, error) {
+	row := DB.QueryRow(`SELECT `+attachmentColumns+` FROM attachments WHERE id = ?`, id)
+	attachment, err := scanAttachment(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrAttachmentNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %v", err)
+	}
+	return attachment, nil
+}
+
+// GetAttachments retrieves one page of the attachments of a product or
+// reception, oldest first.
+func GetAttachments(entityType, entityId string, req pagination.Request) ([]models.Attachment, pagination.Info, error) {
+	var total *int
+	if req.Count {
+		var count int
+		err := DB.QueryRow(`SELECT COUNT(*) FROM attachments WHERE entity_type = ? AND entity_id = ?`,
+			entityType, entityId).Scan(&count)
+		if err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count attachments: %v", err)
+		}
+		total = &count
+	}
+
+	query := `
+    SELECT ` + attachmentColumns + `
+    FROM attachments
+    WHERE entity_type = ? AND entity_id = ?`
+	args := []interface{}{entityType, entityId}
+	keyset, keysetArgs, order := req.KeysetOrdered("created_at", "id", true)
+	if keyset != "" {
+		query += " AND " + keyset
+		args = append(args, keysetArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, pagination.Info{}, fmt.Errorf("failed to get attachments: %v", err)
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to scan attachment: %v", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+
+	attachments, info := pagination.Finish(attachments, req, func(attachment models.Attachment) (string, string) {
+		return attachment.CreatedAt.Format(time.RFC3339), attachment.ID
+	})
+	info.Total = total
+	return attachments, info, nil
+}
+
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	var a models.Attachment
+	var createdAt string
+	err := row.Scan(&a.ID, &a.EntityType, &a.EntityId, &a.Kind, &a.Comment, &a.FileName, &a.ContentType,
+		&a.SizeBytes, &a.Width, &a.Height, &a.BlobKey, &a.ThumbnailKey, &a.UploadedBy, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	a.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time: %v", err)
+	}
+	return &a, nil
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+// RecordAudit appends an entry to the audit log. Details are stored as JSON.
+func RecordAudit(tx execer, entityType, entityId, action, actor string, details interface{}) error {
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit details: %v", err)
+	}
+
+	query := `
+    INSERT INTO audit_log (entity_type, entity_id, action, actor, details, created_at)
+    VALUES (?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(query, entityType, entityId, action, actor, string(encoded), time.Now().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %v", err)
+	}
+	return nil
+}
+
+// GetAuditLog retrieves the audit entries of an entity, oldest first.
+func GetAuditLog(entityType, entityId string) ([]models.AuditEntry, error) {
+	query := `
+    SELECT id, entity_type, entity_id, action, actor, details, created_at
+    FROM audit_log
+    WHERE entity_type = ? AND entity_id = ?
+    ORDER BY id`
+	return queryAuditLog(query, entityType, entityId)
+}
+
+// GetAuditLogPage retrieves one page of the audit entries of an entity,
+// oldest first.
+func GetAuditLogPage(entityType, entityId string, req pagination.Request) ([]models.AuditEntry, pagination.Info, error) {
+	var total *int
+	if req.Count {
+		var count int
+		err := DB.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE entity_type = ? AND entity_id = ?`,
+			entityType, entityId).Scan(&count)
+		if err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count audit entries: %v", err)
+		}
+		total = &count
+	}
+
+	query := `
+    SELECT id, entity_type, entity_id, action, actor, details, created_at
+    FROM audit_log
+    WHERE entity_type = ? AND entity_id = ?`
+	args := []interface{}{entityType, entityId}
+	keyset, keysetArgs, order := req.KeysetOrdered("id", "id", true)
+	if keyset != "" {
+		query += " AND " + keyset
+		args = append(args, keysetArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	entries, err := queryAuditLog(query, args...)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	entries, info := pagination.Finish(entries, req, func(entry models.AuditEntry) (string, string) {
+		id := strconv.FormatInt(entry.ID, 10)
+		return id, id
+	})
+	info.Total = total
+	return entries, info, nil
+}
+
+func queryAuditLog(query string, args ...interface{}) ([]models.AuditEntry, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var details, createdAt string
+		err := rows.Scan(&entry.ID, &entry.EntityType, &entry.EntityId, &entry.Action, &entry.Actor, &details, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %v", err)
+		}
+		entry.Details = json.RawMessage(details)
+		entry.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// SetPVZCapacity replaces the capacity configuration of a PVZ.
+func SetPVZCapacity(pvzId string, capacity models.Capacity) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to update capacity: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+    UPDATE pvzs
+    SET capacity = ?, capacity_policy = ?, capacity_volume_cm3 = ?
+    WHERE id = ?`, capacity.Total, capacity.Policy, capacity.VolumeCm3, pvzId)
+	if err != nil {
+		return fmt.Errorf("failed to update capacity: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrPVZNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM pvz_type_capacities WHERE pvz_id = ?`, pvzId); err != nil {
+		return fmt.Errorf("failed to update capacity: %v", err)
+	}
+	for productType, limit := range capacity.ByType {
+		query := `
+        INSERT INTO pvz_type_capacities (pvz_id, type, capacity)
+        VALUES (?, ?, ?)`
+		if _, err := tx.Exec(query, pvzId, productType, limit); err != nil {
+			return fmt.Errorf("failed to update capacity: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update capacity: %v", err)
+	}
+	return nil
+}
+
+// GetPVZCapacity retrieves the capacity configuration of a PVZ.
+func GetPVZCapacity(pvzId string) (models.Capacity, error) {
+	return getPVZCapacity(DB, pvzId)
+}
+
+func getPVZCapacity(q queryer, pvzId string) (models.Capacity, error) {
+	capacity := models.Capacity{ByType: map[string]int{}}
+	err := q.QueryRow(`
+    SELECT capacity, capacity_policy, capacity_volume_cm3
+    FROM pvzs
+    WHERE id = ?`, pvzId).Scan(&capacity.Total, &capacity.Policy, &capacity.VolumeCm3)
+	if err == sql.ErrNoRows {
+		return capacity, ErrPVZNotFound
+	} else if err != nil {
+		return capacity, fmt.Errorf("failed to get capacity: %v", err)
+	}
+
+	rows, err := q.Query(`
+    SELECT type, capacity
+    FROM pvz_type_capacities
+    WHERE pvz_id = ?`, pvzId)
+	if err != nil {
+		return capacity, fmt.Errorf("failed to get capacity: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var productType string
+		var limit int
+		if err := rows.Scan(&productType, &limit); err != nil {
+			return capacity, fmt.Errorf("failed to scan capacity: %v", err)
+		}
+		capacity.ByType[productType] = limit
+	}
+	return capacity, nil
+}
+
+// GetPVZOccupancy counts the products currently stored at a PVZ, in total and per type.
+// Issued, returned and lost products no longer take up space.
+func GetPVZOccupancy(pvzId string) (*models.Occupancy, error) {
+	return getPVZOccupancy(DB, pvzId)
+}
+
+func getPVZOccupancy(q queryer, pvzId string) (*models.Occupancy, error) {
+	capacity, err := getPVZCapacity(q, pvzId)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+    SELECT p.type, COUNT(*), COALESCE(SUM(p.weight_grams), 0),
+        COALESCE(SUM(p.length_mm * p.width_mm * p.height_mm), 0)
+    FROM products p
+    JOIN receptions r ON r.id = ` + productReception + `
+    WHERE r.pvz_id = ? AND p.status IN ` + storedStatuses + `
+    GROUP BY p.type`
+	rows, err := q.Query(query, pvzId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get occupancy: %v", err)
+	}
+	defer rows.Close()
+
+	occupancy := &models.Occupancy{
+		PvzId:    pvzId,
+		ByType:   map[string]int{},
+		Capacity: capacity,
+	}
+	var volumeMm3 int64
+	for rows.Next() {
+		var productType string
+		var count int
+		var weight, volume int64
+		if err := rows.Scan(&productType, &count, &weight, &volume); err != nil {
+			return nil, fmt.Errorf("failed to scan occupancy: %v", err)
+		}
+		occupancy.ByType[productType] = count
+		occupancy.Total += count
+		occupancy.WeightGrams += weight
+		volumeMm3 += volume
+	}
+	occupancy.VolumeCm3 = volumeMm3 / 1000
+
+	if capacity.Total > 0 {
+		available := capacity.Total - occupancy.Total
+		if available < 0 {
+			available = 0
+		}
+		occupancy.Available = &available
+	}
+	if capacity.VolumeCm3 > 0 {
+		available := capacity.VolumeCm3 - occupancy.VolumeCm3
+		if available < 0 {
+			available = 0
+		}
+		occupancy.VolumeAvailableCm3 = &available
+	}
+	return occupancy, nil
+}
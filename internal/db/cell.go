@@ -0,0 +1,182 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+var (
+	ErrCellNotFound = apperr.NotFound("cell_not_found", "storage cell not found")
+	ErrCellExists   = apperr.Conflict("cell_exists", "a storage cell with this address already exists")
+)
+
+const cellColumns = `
+    c.id, c.pvz_id, c.zone, c.rack, c.shelf, c.capacity,
+    (SELECT COUNT(*) FROM products p WHERE p.cell_id = c.id AND p.status IN ` + storedStatuses + `)`
+
+// CreateStorageCell inserts a new storage cell into the database.
+func CreateStorageCell(cell models.StorageCell) error {
+	query := `
+    INSERT INTO storage_cells (id, pvz_id, zone, rack, shelf, capacity)
+    VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := DB.Exec(query, cell.ID, cell.PvzId, cell.Zone, cell.Rack, cell.Shelf, cell.Capacity)
+	if isUniqueViolation(err) {
+		return ErrCellExists
+	} else if err != nil {
+		return fmt.Errorf("failed to create storage cell: %v", err)
+	}
+	return nil
+}
+
+// GetStorageCellByID retrieves a storage cell together with its current occupancy.
+func GetStorageCellByID(id string) (*models.StorageCell, error) {
+	query := `
+    SELECT ` + cellColumns + `
+    FROM storage_cells c
+    WHERE c.id = ?`
+	cell, err := scanStorageCell(DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCellNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get storage cell: %v", err)
+	}
+	return cell, nil
+}
+
+// GetStorageCellsByPVZ retrieves all storage cells of a PVZ ordered by address.
+func GetStorageCellsByPVZ(pvzId string) ([]models.StorageCell, error) {
+	query := `
+    SELECT ` + cellColumns + `
+    FROM storage_cells c
+    WHERE c.pvz_id = ?
+    ORDER BY ` + cellAddress + `, c.id`
+	return queryStorageCells(query, pvzId)
+}
+
+// cellAddress orders cells by zone, rack and shelf as a single key. The unit
+// separator sorts before any character of an address, so a shorter zone comes
+// first just as in a column-by-column order.
+const cellAddress = `c.zone || char(31) || c.rack || char(31) || c.shelf`
+
+// GetStorageCellsPage is GetStorageCellsByPVZ paginated with cursors.
+func GetStorageCellsPage(pvzId string, req pagination.Request) ([]models.StorageCell, pagination.Info, error) {
+	var total *int
+	if req.Count {
+		var count int
+		if err := DB.QueryRow(`SELECT COUNT(*) FROM storage_cells WHERE pvz_id = ?`, pvzId).Scan(&count); err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count storage cells: %v", err)
+		}
+		total = &count
+	}
+
+	query := `
+    SELECT ` + cellColumns + `
+    FROM storage_cells c
+    WHERE c.pvz_id = ?`
+	args := []interface{}{pvzId}
+	keyset, keysetArgs, order := req.KeysetOrdered(cellAddress, "c.id", true)
+	if keyset != "" {
+		query += " AND " + keyset
+		args = append(args, keysetArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	cells, err := queryStorageCells(query, args...)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	cells, info := pagination.Finish(cells, req, func(cell models.StorageCell) (string, string) {
+		return cell.Zone + "\x1f" + cell.Rack + "\x1f" + cell.Shelf, cell.ID
+	})
+	info.Total = total
+	return cells, info, nil
+}
+
+func queryStorageCells(query string, args ...interface{}) ([]models.StorageCell, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage cells: %v", err)
+	}
+	defer rows.Close()
+
+	cells := []models.StorageCell{}
+	for rows.Next() {
+		cell, err := scanStorageCell(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan storage cell: %v", err)
+		}
+		cells = append(cells, *cell)
+	}
+	return cells, nil
+}
+
+// FindFreeStorageCell returns the first cell of a PVZ, by address, that still has room.
+// It returns nil when the PVZ has no cells or all of them are full.
+func FindFreeStorageCell(pvzId string) (*models.StorageCell, error) {
+	cells, err := GetStorageCellsByPVZ(pvzId)
+	if err != nil {
+		return nil, err
+	}
+	for _, cell := range cells {
+		if cell.Capacity == 0 || cell.Occupied < cell.Capacity {
+			return &cell, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetProductCell places a product into a storage cell.
+func SetProductCell(productId, cellId string) error {
+	result, err := DB.Exec(`UPDATE products SET cell_id = ? WHERE id = ?`, cellId, productId)
+	if err != nil {
+		return fmt.Errorf("failed to assign storage cell: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// GetProductLocation retrieves the PVZ and storage cell where a product is kept.
+func GetProductLocation(productId string) (*models.ProductLocation, error) {
+	query := `
+    SELECT r.pvz_id, p.cell_id
+    FROM products p
+    JOIN receptions r ON r.id = ` + productReception + `
+    WHERE p.id = ?`
+	var pvzId string
+	var cellId sql.NullString
+	err := DB.QueryRow(query, productId).Scan(&pvzId, &cellId)
+	if err == sql.ErrNoRows {
+		return nil, ErrProductNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get product location: %v", err)
+	}
+
+	location := &models.ProductLocation{ProductId: productId, PvzId: pvzId}
+	if cellId.Valid {
+		location.Cell, err = GetStorageCellByID(cellId.String)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return location, nil
+}
+
+func scanStorageCell(row rowScanner) (*models.StorageCell, error) {
+	var cell models.StorageCell
+	err := row.Scan(&cell.ID, &cell.PvzId, &cell.Zone, &cell.Rack, &cell.Shelf, &cell.Capacity, &cell.Occupied)
+	if err != nil {
+		return nil, err
+	}
+	return &cell, nil
+}
@@ -2,9 +2,11 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"log"
+	"strings"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 var DB *sql.DB
@@ -38,7 +40,37 @@ func createTables() {
     CREATE TABLE IF NOT EXISTS pvzs (
         id TEXT PRIMARY KEY,
         registration_date DATETIME NOT NULL,
-        city TEXT NOT NULL
+        city TEXT NOT NULL,
+        address TEXT NOT NULL DEFAULT '',
+        latitude REAL,
+        longitude REAL,
+        phone TEXT NOT NULL DEFAULT '',
+        capacity INTEGER NOT NULL DEFAULT 0,
+        capacity_policy TEXT NOT NULL DEFAULT 'reject',
+        reception_timeout_minutes INTEGER NOT NULL DEFAULT 0,
+        stale_reception_action TEXT NOT NULL DEFAULT '',
+        capacity_volume_cm3 INTEGER NOT NULL DEFAULT 0
+    );`
+
+	workingHoursTable := `
+    CREATE TABLE IF NOT EXISTS pvz_working_hours (
+        pvz_id TEXT NOT NULL,
+        weekday TEXT NOT NULL,
+        open_time TEXT NOT NULL,
+        close_time TEXT NOT NULL,
+        PRIMARY KEY (pvz_id, weekday),
+        FOREIGN KEY (pvz_id) REFERENCES pvzs(id)
+    );`
+
+	holidayTable := `
+    CREATE TABLE IF NOT EXISTS pvz_holidays (
+        pvz_id TEXT NOT NULL,
+        date TEXT NOT NULL,
+        closed INTEGER NOT NULL,
+        open_time TEXT NOT NULL DEFAULT '',
+        close_time TEXT NOT NULL DEFAULT '',
+        PRIMARY KEY (pvz_id, date),
+        FOREIGN KEY (pvz_id) REFERENCES pvzs(id)
     );`
 
 	receptionTable := `
@@ -47,6 +79,11 @@ func createTables() {
         date_time DATETIME NOT NULL,
         pvz_id TEXT NOT NULL,
         status TEXT NOT NULL,
+        kind TEXT NOT NULL DEFAULT 'delivery',
+        stale_flagged_at DATETIME,
+        opened_by TEXT NOT NULL DEFAULT '',
+        closed_by TEXT NOT NULL DEFAULT '',
+        closed_at DATETIME,
         FOREIGN KEY (pvz_id) REFERENCES pvzs(id)
     );`
 
@@ -56,14 +93,422 @@ func createTables() {
         date_time DATETIME NOT NULL,
         type TEXT NOT NULL,
         reception_id TEXT NOT NULL,
+        cell_id TEXT,
+        barcode TEXT,
+        status TEXT NOT NULL DEFAULT 'received',
+        order_id TEXT,
+        return_batch_id TEXT,
+        weight_grams INTEGER,
+        length_mm INTEGER,
+        width_mm INTEGER,
+        height_mm INTEGER,
+        transfer_reception_id TEXT,
+        FOREIGN KEY (reception_id) REFERENCES receptions(id),
+        FOREIGN KEY (cell_id) REFERENCES storage_cells(id),
+        FOREIGN KEY (transfer_reception_id) REFERENCES receptions(id)
+    );`
+
+	typeCapacityTable := `
+    CREATE TABLE IF NOT EXISTS pvz_type_capacities (
+        pvz_id TEXT NOT NULL,
+        type TEXT NOT NULL,
+        capacity INTEGER NOT NULL,
+        PRIMARY KEY (pvz_id, type),
+        FOREIGN KEY (pvz_id) REFERENCES pvzs(id)
+    );`
+
+	cellTable := `
+    CREATE TABLE IF NOT EXISTS storage_cells (
+        id TEXT PRIMARY KEY,
+        pvz_id TEXT NOT NULL,
+        zone TEXT NOT NULL,
+        rack TEXT NOT NULL,
+        shelf TEXT NOT NULL,
+        capacity INTEGER NOT NULL DEFAULT 0,
+        UNIQUE (pvz_id, zone, rack, shelf),
+        FOREIGN KEY (pvz_id) REFERENCES pvzs(id)
+    );`
+
+	statusHistoryTable := `
+    CREATE TABLE IF NOT EXISTS product_status_history (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        product_id TEXT NOT NULL,
+        status TEXT NOT NULL,
+        changed_at DATETIME NOT NULL,
+        comment TEXT NOT NULL DEFAULT '',
+        FOREIGN KEY (product_id) REFERENCES products(id)
+    );`
+
+	orderTable := `
+    CREATE TABLE IF NOT EXISTS orders (
+        id TEXT PRIMARY KEY,
+        order_number TEXT NOT NULL UNIQUE,
+        pvz_id TEXT NOT NULL,
+        recipient_phone TEXT NOT NULL,
+        status TEXT NOT NULL,
+        created_at DATETIME NOT NULL,
+        issued_at DATETIME,
+        pickup_code_hash TEXT,
+        pickup_code_attempts INTEGER NOT NULL DEFAULT 0,
+        FOREIGN KEY (pvz_id) REFERENCES pvzs(id)
+    );`
+
+	auditTable := `
+    CREATE TABLE IF NOT EXISTS audit_log (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        entity_type TEXT NOT NULL,
+        entity_id TEXT NOT NULL,
+        action TEXT NOT NULL,
+        actor TEXT NOT NULL,
+        details TEXT NOT NULL,
+        created_at DATETIME NOT NULL
+    );`
+
+	transferTable := `
+    CREATE TABLE IF NOT EXISTS transfers (
+        id TEXT PRIMARY KEY,
+        source_pvz_id TEXT NOT NULL,
+        destination_pvz_id TEXT NOT NULL,
+        status TEXT NOT NULL,
+        created_at DATETIME NOT NULL,
+        received_at DATETIME,
+        reception_id TEXT,
+        FOREIGN KEY (source_pvz_id) REFERENCES pvzs(id),
+        FOREIGN KEY (destination_pvz_id) REFERENCES pvzs(id),
+        FOREIGN KEY (reception_id) REFERENCES receptions(id)
+    );`
+
+	transferItemTable := `
+    CREATE TABLE IF NOT EXISTS transfer_items (
+        transfer_id TEXT NOT NULL,
+        product_id TEXT NOT NULL,
+        status TEXT NOT NULL,
+        PRIMARY KEY (transfer_id, product_id),
+        FOREIGN KEY (transfer_id) REFERENCES transfers(id),
+        FOREIGN KEY (product_id) REFERENCES products(id)
+    );`
+
+	storagePeriodTable := `
+    CREATE TABLE IF NOT EXISTS storage_periods (
+        type TEXT PRIMARY KEY,
+        days INTEGER NOT NULL
+    );`
+
+	returnBatchTable := `
+    CREATE TABLE IF NOT EXISTS return_batches (
+        id TEXT PRIMARY KEY,
+        pvz_id TEXT NOT NULL,
+        status TEXT NOT NULL,
+        created_at DATETIME NOT NULL,
+        dispatched_at DATETIME,
+        FOREIGN KEY (pvz_id) REFERENCES pvzs(id)
+    );`
+
+	manifestTable := `
+    CREATE TABLE IF NOT EXISTS reception_manifests (
+        reception_id TEXT PRIMARY KEY,
+        source TEXT NOT NULL,
+        attached_at DATETIME NOT NULL,
+        attached_by TEXT NOT NULL,
+        FOREIGN KEY (reception_id) REFERENCES receptions(id)
+    );`
+
+	manifestItemTable := `
+    CREATE TABLE IF NOT EXISTS manifest_items (
+        reception_id TEXT NOT NULL,
+        position INTEGER NOT NULL,
+        barcode TEXT,
+        type TEXT NOT NULL,
+        quantity INTEGER NOT NULL,
+        PRIMARY KEY (reception_id, position),
+        FOREIGN KEY (reception_id) REFERENCES reception_manifests(reception_id)
+    );`
+
+	reconciliationTable := `
+    CREATE TABLE IF NOT EXISTS reception_reconciliations (
+        reception_id TEXT PRIMARY KEY,
+        created_at DATETIME NOT NULL,
+        report TEXT NOT NULL,
         FOREIGN KEY (reception_id) REFERENCES receptions(id)
     );`
 
-	tables := []string{userTable, pvzTable, receptionTable, productTable}
+	productTypeTable := `
+    CREATE TABLE IF NOT EXISTS product_types (
+        code TEXT PRIMARY KEY,
+        names TEXT NOT NULL,
+        size_class TEXT NOT NULL,
+        fragile BOOLEAN NOT NULL DEFAULT 0,
+        hazardous BOOLEAN NOT NULL DEFAULT 0,
+        active BOOLEAN NOT NULL DEFAULT 1,
+        max_weight_grams INTEGER NOT NULL DEFAULT 0,
+        max_length_mm INTEGER NOT NULL DEFAULT 0,
+        max_width_mm INTEGER NOT NULL DEFAULT 0,
+        max_height_mm INTEGER NOT NULL DEFAULT 0
+    );`
+
+	attachmentTable := `
+    CREATE TABLE IF NOT EXISTS attachments (
+        id TEXT PRIMARY KEY,
+        entity_type TEXT NOT NULL,
+        entity_id TEXT NOT NULL,
+        kind TEXT NOT NULL,
+        comment TEXT NOT NULL DEFAULT '',
+        file_name TEXT NOT NULL DEFAULT '',
+        content_type TEXT NOT NULL,
+        size_bytes INTEGER NOT NULL,
+        width INTEGER NOT NULL,
+        height INTEGER NOT NULL,
+        blob_key TEXT NOT NULL,
+        thumbnail_key TEXT NOT NULL,
+        uploaded_by TEXT NOT NULL,
+        created_at DATETIME NOT NULL
+    );`
+
+	incidentTable := `
+    CREATE TABLE IF NOT EXISTS incidents (
+        id TEXT PRIMARY KEY,
+        reception_id TEXT NOT NULL,
+        product_id TEXT,
+        type TEXT NOT NULL,
+        description TEXT NOT NULL DEFAULT '',
+        status TEXT NOT NULL,
+        reported_by TEXT NOT NULL,
+        reported_at DATETIME NOT NULL,
+        resolved_by TEXT NOT NULL DEFAULT '',
+        resolved_at DATETIME,
+        resolution TEXT NOT NULL DEFAULT '',
+        FOREIGN KEY (reception_id) REFERENCES receptions(id)
+    );`
+
+	tables := []string{userTable, pvzTable, receptionTable, productTable, workingHoursTable, holidayTable,
+		typeCapacityTable, cellTable, statusHistoryTable, orderTable, auditTable, transferTable, transferItemTable,
+		storagePeriodTable, returnBatchTable, manifestTable, manifestItemTable, reconciliationTable,
+		productTypeTable, attachmentTable, incidentTable}
 	for _, table := range tables {
 		_, err := DB.Exec(table)
 		if err != nil {
 			log.Fatalf("Failed to create table: %v", err)
 		}
 	}
+
+	// Columns added after the initial schema; CREATE TABLE IF NOT EXISTS
+	// leaves databases created by older versions untouched.
+	addColumnIfMissing("pvzs", "address", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfMissing("pvzs", "latitude", "REAL")
+	addColumnIfMissing("pvzs", "longitude", "REAL")
+	addColumnIfMissing("pvzs", "phone", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfMissing("pvzs", "capacity", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfMissing("pvzs", "capacity_policy", "TEXT NOT NULL DEFAULT 'reject'")
+	addColumnIfMissing("pvzs", "reception_timeout_minutes", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfMissing("pvzs", "stale_reception_action", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfMissing("receptions", "kind", "TEXT NOT NULL DEFAULT 'delivery'")
+	addColumnIfMissing("receptions", "stale_flagged_at", "DATETIME")
+	addColumnIfMissing("receptions", "opened_by", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfMissing("receptions", "closed_by", "TEXT NOT NULL DEFAULT ''")
+	addColumnIfMissing("receptions", "closed_at", "DATETIME")
+	addColumnIfMissing("products", "cell_id", "TEXT")
+	addColumnIfMissing("products", "barcode", "TEXT")
+	addColumnIfMissing("products", "status", "TEXT NOT NULL DEFAULT 'received'")
+	addColumnIfMissing("products", "order_id", "TEXT")
+	addColumnIfMissing("products", "return_batch_id", "TEXT")
+	addColumnIfMissing("products", "weight_grams", "INTEGER")
+	addColumnIfMissing("products", "length_mm", "INTEGER")
+	addColumnIfMissing("products", "width_mm", "INTEGER")
+	addColumnIfMissing("products", "height_mm", "INTEGER")
+	addColumnIfMissing("products", "transfer_reception_id", "TEXT")
+	addColumnIfMissing("pvzs", "capacity_volume_cm3", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfMissing("product_types", "max_weight_grams", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfMissing("product_types", "max_length_mm", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfMissing("product_types", "max_width_mm", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfMissing("product_types", "max_height_mm", "INTEGER NOT NULL DEFAULT 0")
+	// Per-product pickup codes were kept in plaintext; orders hash theirs
+	dropColumnIfPresent("products", "pickup_code")
+
+	// A barcode identifies exactly one product stored at or travelling between
+	// PVZs; issued and returned products release it
+	indexes := []string{
+		`DROP INDEX IF EXISTS products_barcode_unique`,
+		`DROP INDEX IF EXISTS products_barcode_stored`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS products_barcode_held
+        ON products (barcode)
+        WHERE barcode IS NOT NULL AND status IN ` + heldStatuses,
+		`CREATE INDEX IF NOT EXISTS product_status_history_product
+        ON product_status_history (product_id)`,
+		`CREATE INDEX IF NOT EXISTS audit_log_entity
+        ON audit_log (entity_type, entity_id)`,
+		`CREATE INDEX IF NOT EXISTS attachments_entity
+        ON attachments (entity_type, entity_id)`,
+		`CREATE INDEX IF NOT EXISTS incidents_reception
+        ON incidents (reception_id, status)`,
+	}
+	for _, index := range indexes {
+		if _, err := DB.Exec(index); err != nil {
+			log.Fatalf("Failed to create index: %v", err)
+		}
+	}
+
+	// A PVZ has at most one reception in progress. Databases created before
+	// the index may hold several, so all but the newest are closed first.
+	closeDuplicateReceptions()
+	_, err := DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS receptions_in_progress
+        ON receptions (pvz_id) WHERE status = 'in_progress'`)
+	if err != nil {
+		log.Fatalf("Failed to create index receptions_in_progress: %v", err)
+	}
+
+	createLocationIndex()
+	createSearchIndex()
+	seedProductTypes()
+}
+
+// closeDuplicateReceptions closes every reception in progress that has a newer
+// one in progress at the same PVZ, as the stale reception job would.
+func closeDuplicateReceptions() {
+	rows, err := DB.Query(`
+    SELECT r.id
+    FROM receptions r
+    WHERE r.status = 'in_progress' AND EXISTS (
+        SELECT 1 FROM receptions n
+        WHERE n.pvz_id = r.pvz_id AND n.status = 'in_progress'
+          AND (julianday(n.date_time) > julianday(r.date_time)
+            OR (julianday(n.date_time) = julianday(r.date_time) AND n.id > r.id))
+    )`)
+	if err != nil {
+		log.Fatalf("Failed to find duplicate receptions: %v", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Fatalf("Failed to find duplicate receptions: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := CloseReception(id, "system"); err != nil {
+			log.Fatalf("Failed to close duplicate reception %s: %v", id, err)
+		}
+		log.Printf("Closed reception %s: a newer one is in progress at the same PVZ", id)
+	}
+}
+
+// createLocationIndex maintains an R-tree over PVZ coordinates. R-tree ids
+// must be integers, so pvz_location_ids gives every PVZ one; the pvzs rowid
+// may change on VACUUM and is never used. Triggers keep both tables in sync
+// with pvzs.
+func createLocationIndex() {
+	// Indexes created before pvz_location_ids were keyed by rowid; rebuild them
+	var existing string
+	err := DB.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = 'pvz_locations_insert'`).Scan(&existing)
+	if err != nil && err != sql.ErrNoRows {
+		log.Fatalf("Failed to inspect location index: %v", err)
+	}
+	if strings.Contains(strings.ToLower(existing), "rowid") {
+		for _, statement := range []string{
+			`DROP TRIGGER IF EXISTS pvz_locations_insert`,
+			`DROP TRIGGER IF EXISTS pvz_locations_update`,
+			`DROP TRIGGER IF EXISTS pvz_locations_delete`,
+			`DROP TABLE IF EXISTS pvz_locations`,
+		} {
+			if _, err := DB.Exec(statement); err != nil {
+				log.Fatalf("Failed to rebuild location index: %v", err)
+			}
+		}
+	}
+
+	statements := []string{`
+    CREATE TABLE IF NOT EXISTS pvz_location_ids (
+        id INTEGER PRIMARY KEY,
+        pvz_id TEXT NOT NULL UNIQUE
+    );`, `
+    CREATE VIRTUAL TABLE IF NOT EXISTS pvz_locations USING rtree(
+        id,
+        min_lat, max_lat,
+        min_lon, max_lon
+    );`, `
+    CREATE TRIGGER IF NOT EXISTS pvz_locations_insert AFTER INSERT ON pvzs
+    BEGIN
+        INSERT OR IGNORE INTO pvz_location_ids (pvz_id) VALUES (NEW.id);
+        INSERT INTO pvz_locations
+        SELECT m.id, NEW.latitude, NEW.latitude, NEW.longitude, NEW.longitude
+        FROM pvz_location_ids m
+        WHERE m.pvz_id = NEW.id AND NEW.latitude IS NOT NULL AND NEW.longitude IS NOT NULL;
+    END;`, `
+    CREATE TRIGGER IF NOT EXISTS pvz_locations_update AFTER UPDATE OF latitude, longitude ON pvzs
+    BEGIN
+        DELETE FROM pvz_locations WHERE id = (SELECT id FROM pvz_location_ids WHERE pvz_id = OLD.id);
+        INSERT INTO pvz_locations
+        SELECT m.id, NEW.latitude, NEW.latitude, NEW.longitude, NEW.longitude
+        FROM pvz_location_ids m
+        WHERE m.pvz_id = NEW.id AND NEW.latitude IS NOT NULL AND NEW.longitude IS NOT NULL;
+    END;`, `
+    CREATE TRIGGER IF NOT EXISTS pvz_locations_delete AFTER DELETE ON pvzs
+    BEGIN
+        DELETE FROM pvz_locations WHERE id = (SELECT id FROM pvz_location_ids WHERE pvz_id = OLD.id);
+        DELETE FROM pvz_location_ids WHERE pvz_id = OLD.id;
+    END;`, `
+    INSERT OR IGNORE INTO pvz_location_ids (pvz_id) SELECT id FROM pvzs;`, `
+    INSERT OR REPLACE INTO pvz_locations
+    SELECT m.id, p.latitude, p.latitude, p.longitude, p.longitude
+    FROM pvzs p
+    JOIN pvz_location_ids m ON m.pvz_id = p.id
+    WHERE p.latitude IS NOT NULL AND p.longitude IS NOT NULL;`,
+	}
+	for _, statement := range statements {
+		if _, err := DB.Exec(statement); err != nil {
+			log.Fatalf("Failed to create location index: %v", err)
+		}
+	}
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already there.
+func addColumnIfMissing(table, column, definition string) {
+	if hasColumn(table, column) {
+		return
+	}
+	_, err := DB.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	if err != nil {
+		log.Fatalf("Failed to add column %s.%s: %v", table, column, err)
+	}
+}
+
+func dropColumnIfPresent(table, column string) {
+	if !hasColumn(table, column) {
+		return
+	}
+	_, err := DB.Exec("ALTER TABLE " + table + " DROP COLUMN " + column)
+	if err != nil {
+		log.Fatalf("Failed to drop column %s.%s: %v", table, column, err)
+	}
+}
+
+func hasColumn(table, column string) bool {
+	rows, err := DB.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		log.Fatalf("Failed to inspect table %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			log.Fatalf("Failed to inspect table %s: %v", table, err)
+		}
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// isUniqueViolation reports whether err is SQLite rejecting a duplicate key.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
 }
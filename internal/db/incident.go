@@ -0,0 +1,284 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+var (
+	ErrIncidentNotFound = apperr.NotFound("incident_not_found", "incident not found")
+	ErrIncidentResolved = apperr.Conflict("incident_resolved", "incident is already resolved")
+)
+
+const incidentColumns = `i.id, i.reception_id, COALESCE(i.product_id, ''), r.pvz_id, i.type, i.description,
+    i.status, i.reported_by, i.reported_at, i.resolved_by, i.resolved_at, i.resolution`
+
+// IncidentFilter narrows GetIncidents; empty fields match everything.
+type IncidentFilter struct {
+	Status      string
+	PvzId       string
+	ReceptionId string
+}
+
+// CreateIncident logs an incident and records it in the audit log of its reception.
+func CreateIncident(incident models.Incident) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create incident: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO incidents (id, reception_id, product_id, type, description, status, reported_by, reported_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(query, incident.ID, incident.ReceptionId, nullString(incident.ProductId), incident.Type,
+		incident.Description, incident.Status, incident.ReportedBy, incident.ReportedAt.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to create incident: %v", err)
+	}
+
+	details := map[string]interface{}{"incidentId": incident.ID, "type": incident.Type}
+	if incident.ProductId != "" {
+		details["productId"] = incident.ProductId
+	}
+	if err := RecordAudit(tx, "reception", incident.ReceptionId, "incident_reported", incident.ReportedBy, details); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create incident: %v", err)
+	}
+	return nil
+}
+
+// GetIncidentByID retrieves a single incident.
+func GetIncidentByID(id string) (*models.Incident, error) {
+	query := `
+    SELECT ` + incidentColumns + `
+    FROM incidents i
+    JOIN receptions r ON r.id = i.reception_id
+    WHERE i.id = ?`
+	incident, err := scanIncident(DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %v", err)
+	}
+	return incident, nil
+}
+
+// GetIncidents lists all incidents matching the filter, newest first.
+func GetIncidents(filter IncidentFilter) ([]models.Incident, error) {
+	conditions, args := filter.conditions()
+	query := `
+    SELECT ` + incidentColumns + `
+    FROM incidents i
+    JOIN receptions r ON r.id = i.reception_id`
+	if len(conditions) > 0 {
+		query += " WHERE " + joinConditions(conditions)
+	}
+	query += " ORDER BY i.reported_at DESC, i.id DESC"
+	return queryIncidents(query, args...)
+}
+
+// GetIncidentsPage retrieves one page of the incidents matching the filter.
+func GetIncidentsPage(filter IncidentFilter, req pagination.Request) ([]models.Incident, pagination.Info, error) {
+	conditions, args := filter.conditions()
+
+	var total *int
+	if req.Count {
+		query := `
+        SELECT COUNT(*)
+        FROM incidents i
+        JOIN receptions r ON r.id = i.reception_id`
+		if len(conditions) > 0 {
+			query += " WHERE " + joinConditions(conditions)
+		}
+		var count int
+		if err := DB.QueryRow(query, args...).Scan(&count); err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count incidents: %v", err)
+		}
+		total = &count
+	}
+
+	keyset, keysetArgs, order := req.Keyset("i.reported_at", "i.id")
+	if keyset != "" {
+		conditions = append(conditions, keyset)
+		args = append(args, keysetArgs...)
+	}
+	query := `
+    SELECT ` + incidentColumns + `
+    FROM incidents i
+    JOIN receptions r ON r.id = i.reception_id`
+	if len(conditions) > 0 {
+		query += " WHERE " + joinConditions(conditions)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	incidents, err := queryIncidents(query, args...)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	incidents, info := pagination.Finish(incidents, req, func(incident models.Incident) (string, string) {
+		return incident.ReportedAt.Format(time.RFC3339), incident.ID
+	})
+	info.Total = total
+	return incidents, info, nil
+}
+
+func (f IncidentFilter) conditions() ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.Status != "" {
+		conditions = append(conditions, "i.status = ?")
+		args = append(args, f.Status)
+	}
+	if f.PvzId != "" {
+		conditions = append(conditions, "r.pvz_id = ?")
+		args = append(args, f.PvzId)
+	}
+	if f.ReceptionId != "" {
+		conditions = append(conditions, "i.reception_id = ?")
+		args = append(args, f.ReceptionId)
+	}
+	return conditions, args
+}
+
+func queryIncidents(query string, args ...interface{}) ([]models.Incident, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incidents: %v", err)
+	}
+	defer rows.Close()
+
+	incidents := []models.Incident{}
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %v", err)
+		}
+		incidents = append(incidents, *incident)
+	}
+	return incidents, nil
+}
+
+// ResolveIncident closes an open incident with the moderator's resolution.
+func ResolveIncident(id, actor, resolution string, resolvedAt time.Time) (*models.Incident, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve incident: %v", err)
+	}
+	defer tx.Rollback()
+
+	var receptionId, status string
+	err = tx.QueryRow(`SELECT reception_id, status FROM incidents WHERE id = ?`, id).Scan(&receptionId, &status)
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to resolve incident: %v", err)
+	}
+	if status != models.IncidentStatusOpen {
+		return nil, ErrIncidentResolved
+	}
+
+	query := `
+    UPDATE incidents
+    SET status = ?, resolved_by = ?, resolved_at = ?, resolution = ?
+    WHERE id = ?`
+	_, err = tx.Exec(query, models.IncidentStatusResolved, actor, resolvedAt.Format(time.RFC3339), resolution, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve incident: %v", err)
+	}
+	details := map[string]interface{}{"incidentId": id, "resolution": resolution}
+	if err := RecordAudit(tx, "reception", receptionId, "incident_resolved", actor, details); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to resolve incident: %v", err)
+	}
+	return GetIncidentByID(id)
+}
+
+func scanIncident(row rowScanner) (*models.Incident, error) {
+	var incident models.Incident
+	var reportedAt string
+	var resolvedAt sql.NullString
+	err := row.Scan(&incident.ID, &incident.ReceptionId, &incident.ProductId, &incident.PvzId, &incident.Type,
+		&incident.Description, &incident.Status, &incident.ReportedBy, &reportedAt, &incident.ResolvedBy,
+		&resolvedAt, &incident.Resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	incident.ReportedAt, err = time.Parse(time.RFC3339, reportedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time: %v", err)
+	}
+	if resolvedAt.Valid {
+		parsed, err := time.Parse(time.RFC3339, resolvedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time: %v", err)
+		}
+		incident.ResolvedAt = &parsed
+	}
+	return &incident, nil
+}
+
+// loadIncidentFlags fills in the closed receptions with open incidents for
+// the given PVZs, so listings can highlight them.
+func loadIncidentFlags(pvzs []models.PVZ) error {
+	if len(pvzs) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(pvzs))
+	placeholders := make([]string, 0, len(pvzs))
+	args := []interface{}{models.IncidentStatusOpen}
+	for i, pvz := range pvzs {
+		index[pvz.ID] = i
+		placeholders = append(placeholders, "?")
+		args = append(args, pvz.ID)
+	}
+
+	rows, err := DB.Query(`
+    SELECT r.pvz_id, r.id, r.closed_at, COUNT(*)
+    FROM incidents i
+    JOIN receptions r ON r.id = i.reception_id
+    WHERE i.status = ? AND r.status = 'close' AND r.pvz_id IN (`+joinStrings(placeholders, ", ")+`)
+    GROUP BY r.id
+    ORDER BY r.date_time DESC`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get incident flags: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pvzId string
+		var closedAt sql.NullString
+		var flag models.FlaggedReception
+		if err := rows.Scan(&pvzId, &flag.ReceptionId, &closedAt, &flag.OpenIncidents); err != nil {
+			return fmt.Errorf("failed to scan incident flag: %v", err)
+		}
+		if closedAt.Valid {
+			parsed, err := time.Parse(time.RFC3339, closedAt.String)
+			if err != nil {
+				return fmt.Errorf("failed to parse time: %v", err)
+			}
+			flag.ClosedAt = &parsed
+		}
+		i := index[pvzId]
+		pvzs[i].FlaggedReceptions = append(pvzs[i].FlaggedReceptions, flag)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/manifest"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+var (
+	ErrManifestNotFound       = apperr.NotFound("manifest_not_found", "manifest not found")
+	ErrReconciliationNotFound = apperr.NotFound("reconciliation_not_found", "reconciliation report not found")
+)
+
+// SetManifest attaches the expected manifest to an open or pending reception,
+// replacing any manifest attached before.
+func SetManifest(m models.Manifest) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to attach manifest: %v", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM receptions WHERE id = ?`, m.ReceptionId).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrReceptionNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to attach manifest: %v", err)
+	}
+	if status != models.ReceptionStatusInProgress && status != models.ReceptionStatusPending {
+		return ErrReceptionClosed
+	}
+	if err := writeManifest(tx, m, "manifest_attached"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to attach manifest: %v", err)
+	}
+	return nil
+}
+
+// ImportManifests creates a pending reception for every imported manifest in a
+// single transaction. A pending reception starts when the truck arrives, see
+// OpenReception.
+func ImportManifests(receptions []models.Reception, manifests []models.Manifest) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to import manifests: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO receptions (id, date_time, pvz_id, status, kind)
+    VALUES (?, ?, ?, 'pending', ?)`
+	for i, reception := range receptions {
+		_, err := tx.Exec(query, reception.ID, reception.DateTime.Format(time.RFC3339), reception.PvzId, reception.Kind)
+		if err != nil {
+			return fmt.Errorf("failed to create pending reception: %v", err)
+		}
+		if err := writeManifest(tx, manifests[i], "manifest_imported"); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to import manifests: %v", err)
+	}
+	return nil
+}
+
+// startPendingReception turns the oldest pending reception of a PVZ into the
+// active one and returns its ID, or an empty ID if the PVZ has nothing pending.
+func startPendingReception(tx *sql.Tx, pvzId string, startedAt time.Time, actor string) (string, error) {
+	query := `
+    SELECT id FROM receptions
+    WHERE pvz_id = ? AND status = 'pending'
+    ORDER BY date_time, id
+    LIMIT 1`
+	var receptionId string
+	err := tx.QueryRow(query, pvzId).Scan(&receptionId)
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to find pending reception: %v", err)
+	}
+
+	result, err := tx.Exec(`
+    UPDATE receptions
+    SET status = 'in_progress', date_time = ?, opened_by = ?
+    WHERE id = ? AND status = 'pending'`, startedAt.Format(time.RFC3339), actor, receptionId)
+	if isUniqueViolation(err) {
+		return "", ErrReceptionInProgress.Withf("a reception is already in progress at PVZ %s", pvzId)
+	} else if err != nil {
+		return "", fmt.Errorf("failed to start reception: %v", err)
+	}
+	// Someone else started it in the meantime, so it is in progress now
+	if affected, _ := result.RowsAffected(); affected != 1 {
+		return "", ErrReceptionInProgress.Withf("reception %s was started by someone else", receptionId)
+	}
+	return receptionId, nil
+}
+
+// writeManifest replaces the manifest of a reception and records it in the audit log.
+func writeManifest(tx *sql.Tx, m models.Manifest, action string) error {
+	if _, err := tx.Exec(`DELETE FROM manifest_items WHERE reception_id = ?`, m.ReceptionId); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	query := `
+    INSERT INTO reception_manifests (reception_id, source, attached_at, attached_by)
+    VALUES (?, ?, ?, ?)
+    ON CONFLICT (reception_id) DO UPDATE SET
+        source = excluded.source, attached_at = excluded.attached_at, attached_by = excluded.attached_by`
+	_, err := tx.Exec(query, m.ReceptionId, m.Source, m.AttachedAt.Format(time.RFC3339), m.AttachedBy)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+
+	itemQuery := `
+    INSERT INTO manifest_items (reception_id, position, barcode, type, quantity)
+    VALUES (?, ?, ?, ?, ?)`
+	for i, item := range m.Items {
+		if _, err := tx.Exec(itemQuery, m.ReceptionId, i, nullString(item.Barcode), item.Type, item.Quantity); err != nil {
+			return fmt.Errorf("failed to write manifest: %v", err)
+		}
+	}
+
+	details := map[string]interface{}{"source": m.Source, "items": len(m.Items)}
+	if err := RecordAudit(tx, "reception", m.ReceptionId, action, m.AttachedBy, details); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetManifest retrieves the manifest attached to a reception.
+func GetManifest(receptionId string) (*models.Manifest, error) {
+	m := models.Manifest{ReceptionId: receptionId}
+	var attachedAt string
+	err := DB.QueryRow(`
+    SELECT source, attached_at, attached_by
+    FROM reception_manifests
+    WHERE reception_id = ?`, receptionId).Scan(&m.Source, &attachedAt, &m.AttachedBy)
+	if err == sql.ErrNoRows {
+		return nil, ErrManifestNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get manifest: %v", err)
+	}
+
+	m.AttachedAt, err = time.Parse(time.RFC3339, attachedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time: %v", err)
+	}
+	m.Items, err = queryManifestItems(DB, receptionId)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func queryManifestItems(q queryer, receptionId string) ([]models.ManifestItem, error) {
+	rows, err := q.Query(`
+    SELECT COALESCE(barcode, ''), type, quantity
+    FROM manifest_items
+    WHERE reception_id = ?
+    ORDER BY position`, receptionId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest items: %v", err)
+	}
+	defer rows.Close()
+
+	items := []models.ManifestItem{}
+	for rows.Next() {
+		var item models.ManifestItem
+		if err := rows.Scan(&item.Barcode, &item.Type, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan manifest item: %v", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// reconcileReception compares the products of a closing reception with its
+// manifest and stores the report. Receptions without a manifest are skipped.
+func reconcileReception(tx *sql.Tx, receptionId string) error {
+	var exists int
+	err := tx.QueryRow(`SELECT 1 FROM reception_manifests WHERE reception_id = ?`, receptionId).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to reconcile reception: %v", err)
+	}
+
+	items, err := queryManifestItems(tx, receptionId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+    SELECT `+productColumns+`
+    FROM products p
+    WHERE p.reception_id = ?
+    ORDER BY p.date_time, p.id`, receptionId)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile reception: %v", err)
+	}
+	var products []models.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan product: %v", err)
+		}
+		products = append(products, *product)
+	}
+	rows.Close()
+
+	report := manifest.Reconcile(items, products)
+	report.ReceptionId = receptionId
+	report.CreatedAt = time.Now()
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode reconciliation report: %v", err)
+	}
+
+	query := `
+    INSERT INTO reception_reconciliations (reception_id, created_at, report)
+    VALUES (?, ?, ?)
+    ON CONFLICT (reception_id) DO UPDATE SET created_at = excluded.created_at, report = excluded.report`
+	if _, err := tx.Exec(query, receptionId, report.CreatedAt.Format(time.RFC3339), string(encoded)); err != nil {
+		return fmt.Errorf("failed to store reconciliation report: %v", err)
+	}
+	return nil
+}
+
+// GetReconciliation retrieves the report produced when a reception was closed.
+func GetReconciliation(receptionId string) (*models.Reconciliation, error) {
+	var encoded string
+	err := DB.QueryRow(`SELECT report FROM reception_reconciliations WHERE reception_id = ?`, receptionId).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		return nil, ErrReconciliationNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get reconciliation report: %v", err)
+	}
+
+	var report models.Reconciliation
+	if err := json.Unmarshal([]byte(encoded), &report); err != nil {
+		return nil, fmt.Errorf("failed to decode reconciliation report: %v", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+var (
+	ErrOrderNotFound      = apperr.NotFound("order_not_found", "order not found")
+	ErrPickupCodeMissing  = apperr.Conflict("pickup_code_missing", "no pickup code has been sent for this order")
+	ErrPickupCodeInvalid  = apperr.Forbidden("pickup_code_invalid", "invalid pickup code")
+	ErrPickupCodeBlocked  = apperr.Forbidden("pickup_code_blocked", "too many invalid pickup code attempts, request a new code")
+	ErrOrderNotReady      = apperr.Conflict("order_not_ready", "order has products that are not stored yet")
+	ErrOrderAlreadyIssued = apperr.Conflict("order_already_issued", "order is already issued")
+	ErrOrderNumberTaken   = apperr.Conflict("order_number_taken", "an order with this number already exists")
+)
+
+// CreateOrder inserts a new customer order into the database.
+func CreateOrder(order models.Order) error {
+	query := `
+    INSERT INTO orders (id, order_number, pvz_id, recipient_phone, status, created_at)
+    VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := DB.Exec(query, order.ID, order.OrderNumber, order.PvzId, order.RecipientPhone,
+		order.Status, order.CreatedAt.Format(time.RFC3339))
+	if isUniqueViolation(err) {
+		return ErrOrderNumberTaken
+	} else if err != nil {
+		return fmt.Errorf("failed to create order: %v", err)
+	}
+	return nil
+}
+
+// GetOrderByID retrieves an order together with its products.
+func GetOrderByID(id string) (*models.Order, error) {
+	return getOrder("id", id)
+}
+
+// GetOrderByNumber retrieves an order by its customer-facing number.
+func GetOrderByNumber(number string) (*models.Order, error) {
+	return getOrder("order_number", number)
+}
+
+func getOrder(column, value string) (*models.Order, error) {
+	query := `
+    SELECT id, order_number, pvz_id, recipient_phone, status, created_at, issued_at
+    FROM orders
+    WHERE ` + column + ` = ?`
+
+	var order models.Order
+	var createdAt string
+	var issuedAt sql.NullString
+	err := DB.QueryRow(query, value).Scan(&order.ID, &order.OrderNumber, &order.PvzId,
+		&order.RecipientPhone, &order.Status, &createdAt, &issuedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get order: %v", err)
+	}
+
+	order.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time: %v", err)
+	}
+	if issuedAt.Valid {
+		parsed, err := time.Parse(time.RFC3339, issuedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time: %v", err)
+		}
+		order.IssuedAt = &parsed
+	}
+
+	rows, err := DB.Query(`
+    SELECT `+productColumns+`
+    FROM products p
+    WHERE p.order_id = ?
+    ORDER BY p.date_time`, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order products: %v", err)
+	}
+	defer rows.Close()
+
+	order.Products = []models.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product: %v", err)
+		}
+		order.Products = append(order.Products, *product)
+	}
+	return &order, nil
+}
+
+// SetOrderPickupCode stores the hash of a freshly issued pickup code, replacing
+// any previous code and resetting the failed attempt counter.
+func SetOrderPickupCode(orderId, codeHash string) error {
+	query := `
+    UPDATE orders
+    SET pickup_code_hash = ?, pickup_code_attempts = 0
+    WHERE id = ? AND status = ?`
+	result, err := DB.Exec(query, codeHash, orderId, models.OrderStatusAwaiting)
+	if err != nil {
+		return fmt.Errorf("failed to set pickup code: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrOrderAlreadyIssued
+	}
+	return nil
+}
+
+// IssueOrder verifies the pickup code hash and hands over every stored product of
+// the order in one transaction. The code is consumed on success; after
+// maxAttempts failed tries it is blocked until a new one is sent.
+func IssueOrder(orderId, codeHash string, maxAttempts int) (*models.Order, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue order: %v", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var storedHash sql.NullString
+	var attempts int
+	err = tx.QueryRow(`
+    SELECT status, pickup_code_hash, pickup_code_attempts
+    FROM orders
+    WHERE id = ?`, orderId).Scan(&status, &storedHash, &attempts)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to issue order: %v", err)
+	}
+
+	if status == models.OrderStatusIssued {
+		return nil, ErrOrderAlreadyIssued
+	}
+	if !storedHash.Valid {
+		return nil, ErrPickupCodeMissing
+	}
+	if attempts >= maxAttempts {
+		return nil, ErrPickupCodeBlocked
+	}
+	if subtle.ConstantTimeCompare([]byte(storedHash.String), []byte(codeHash)) != 1 {
+		// The failed attempt must survive the rollback of everything else
+		if _, err := tx.Exec(`UPDATE orders SET pickup_code_attempts = pickup_code_attempts + 1 WHERE id = ?`, orderId); err != nil {
+			return nil, fmt.Errorf("failed to issue order: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to issue order: %v", err)
+		}
+		return nil, ErrPickupCodeInvalid
+	}
+
+	var pending, stored int
+	err = tx.QueryRow(`
+    SELECT
+        COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+        COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0)
+    FROM products
+    WHERE order_id = ?`, models.ProductStatusReceived, models.ProductStatusStored, orderId).Scan(&pending, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue order: %v", err)
+	}
+	if pending > 0 || stored == 0 {
+		return nil, ErrOrderNotReady
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	historyQuery := `
+    INSERT INTO product_status_history (product_id, status, changed_at, comment)
+    SELECT id, ?, ?, 'issued with order'
+    FROM products
+    WHERE order_id = ? AND status = ?`
+	if _, err := tx.Exec(historyQuery, models.ProductStatusIssued, now, orderId, models.ProductStatusStored); err != nil {
+		return nil, fmt.Errorf("failed to issue order: %v", err)
+	}
+	if _, err := tx.Exec(`UPDATE products SET status = ? WHERE order_id = ? AND status = ?`,
+		models.ProductStatusIssued, orderId, models.ProductStatusStored); err != nil {
+		return nil, fmt.Errorf("failed to issue order: %v", err)
+	}
+	if _, err := tx.Exec(`
+    UPDATE orders
+    SET status = ?, issued_at = ?, pickup_code_hash = NULL
+    WHERE id = ?`, models.OrderStatusIssued, now, orderId); err != nil {
+		return nil, fmt.Errorf("failed to issue order: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to issue order: %v", err)
+	}
+	return GetOrderByID(orderId)
+}
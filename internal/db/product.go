@@ -5,28 +5,185 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
 	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
 )
 
+var (
+	ErrReceptionNotFound     = apperr.NotFound("reception_not_found", "reception not found")
+	ErrReceptionClosed       = apperr.Conflict("reception_closed", "reception is closed")
+	ErrCapacityExceeded      = apperr.Conflict("capacity_exceeded", "PVZ capacity exceeded")
+	ErrProductNotInReception = apperr.NotFound("product_not_in_reception", "product not found in this reception")
+	ErrProductNotFound       = apperr.NotFound("product_not_found", "product not found")
+	ErrProductStatus         = apperr.Conflict("product_status", "product status does not allow this change")
+	ErrBarcodeStored         = apperr.Conflict("barcode_stored", "barcode is already stored at a PVZ")
+	ErrReceptionEmpty        = apperr.Conflict("reception_empty", "no products found in the current reception")
+)
+
+// storedStatuses lists the product statuses that still occupy space at a PVZ.
+const storedStatuses = `('received', 'stored')`
+
+// heldStatuses adds in_transit to storedStatuses: a product on its way to
+// another PVZ still holds its barcode.
+const heldStatuses = `('received', 'stored', 'in_transit')`
+
+// productReception is the reception through which a product is kept now: the
+// one of the transfer that last delivered it, or the one it arrived with.
+const productReception = `COALESCE(p.transfer_reception_id, p.reception_id)`
+
+const productColumns = `p.id, p.date_time, p.type, p.reception_id, p.cell_id, p.barcode, p.status, p.order_id,
+    p.return_batch_id, p.weight_grams, p.length_mm, p.width_mm, p.height_mm`
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 // CreateProduct inserts a new product into the database.
 func CreateProduct(id, dateTime, productType, receptionId string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create product: %v", err)
+	}
+	defer tx.Rollback()
+
 	query := `
     INSERT INTO products (id, date_time, type, reception_id)
     VALUES (?, ?, ?, ?)`
-	_, err := DB.Exec(query, id, dateTime, productType, receptionId)
+	_, err = tx.Exec(query, id, dateTime, productType, receptionId)
 	if err != nil {
 		return fmt.Errorf("failed to create product: %v", err)
 	}
+	if err := recordStatus(tx, id, models.ProductStatusReceived, dateTime, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create product: %v", err)
+	}
+	return nil
+}
+
+// InsertProduct inserts a product including its storage cell, barcode and order, if any.
+func InsertProduct(product models.Product) error {
+	return InsertProducts([]models.Product{product})
+}
+
+// InsertProducts stores a set of received products in a single transaction:
+// either all of them are created or none is. The capacity of the PVZ is
+// checked against its occupancy within the transaction, so concurrent
+// receptions cannot together overfill a PVZ whose policy is to reject.
+func InsertProducts(products []models.Product) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create product: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCapacity(tx, products); err != nil {
+		return err
+	}
+
+	query := `
+    INSERT INTO products (id, date_time, type, reception_id, cell_id, barcode, status, order_id,
+        weight_grams, length_mm, width_mm, height_mm)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, product := range products {
+		dateTime := product.DateTime.Format(time.RFC3339)
+		var weight, length, width, height interface{}
+		if product.WeightGrams > 0 {
+			weight = product.WeightGrams
+		}
+		if d := product.Dimensions; d != nil {
+			length, width, height = d.LengthMm, d.WidthMm, d.HeightMm
+		}
+		_, err = tx.Exec(query, product.ID, dateTime, product.Type, product.ReceptionId,
+			nullString(product.CellId), nullString(product.Barcode), models.ProductStatusReceived,
+			nullString(product.OrderId), weight, length, width, height)
+		if isUniqueViolation(err) && product.Barcode != "" {
+			return ErrBarcodeStored.Withf("barcode %s is already stored at a PVZ", product.Barcode)
+		} else if err != nil {
+			return fmt.Errorf("failed to create product %s: %v", product.ID, err)
+		}
+		if err := recordStatus(tx, product.ID, models.ProductStatusReceived, dateTime, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create product: %v", err)
+	}
+	return nil
+}
+
+// checkCapacity applies the capacity policy of each PVZ the products arrive at.
+func checkCapacity(tx *sql.Tx, products []models.Product) error {
+	occupancies := map[string]*models.Occupancy{}
+	receptionPVZ := map[string]string{}
+	for _, product := range products {
+		pvzId, ok := receptionPVZ[product.ReceptionId]
+		if !ok {
+			err := tx.QueryRow(`SELECT pvz_id FROM receptions WHERE id = ?`, product.ReceptionId).Scan(&pvzId)
+			if err == sql.ErrNoRows {
+				return ErrReceptionNotFound
+			} else if err != nil {
+				return fmt.Errorf("failed to check capacity: %v", err)
+			}
+			receptionPVZ[product.ReceptionId] = pvzId
+		}
+		occupancy, ok := occupancies[pvzId]
+		if !ok {
+			var err error
+			occupancy, err = getPVZOccupancy(tx, pvzId)
+			if err != nil {
+				return err
+			}
+			occupancies[pvzId] = occupancy
+		}
+		if err := fitCapacity(occupancy, product); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// fitCapacity counts a product into the occupancy of its PVZ, unless it would
+// overflow a PVZ whose policy rejects overflow.
+func fitCapacity(occupancy *models.Occupancy, product models.Product) error {
+	var volumeCm3 int64
+	if product.Dimensions != nil {
+		volumeCm3 = product.Dimensions.VolumeCm3()
+	}
+	overflow := occupancy.Overflow(product.Type, volumeCm3)
+	if overflow != "" && occupancy.Capacity.Policy != models.CapacityPolicyWarn {
+		return ErrCapacityExceeded.Withf("%s", overflow)
+	}
+	occupancy.Add(product)
+	return nil
+}
+
+// GetProductByID retrieves a product by its ID.
+func GetProductByID(id string) (*models.Product, error) {
+	query := `
+    SELECT ` + productColumns + `
+    FROM products p
+    WHERE p.id = ?`
+	product, err := scanProduct(DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrProductNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get product: %v", err)
+	}
+	return product, nil
+}
+
 // GetProductsByReception retrieves all products for a given reception ID.
 func GetProductsByReception(receptionId string) ([]models.Product, error) {
 	query := `
-    SELECT id, date_time, type
-    FROM products
-    WHERE reception_id = ?
-    ORDER BY date_time DESC`
+    SELECT ` + productColumns + `
+    FROM products p
+    WHERE p.reception_id = ?
+    ORDER BY p.date_time DESC`
 	rows, err := DB.Query(query, receptionId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products: %v", err)
@@ -35,26 +192,198 @@ func GetProductsByReception(receptionId string) ([]models.Product, error) {
 
 	var products []models.Product
 	for rows.Next() {
-		var id, dateTime, productType string
-		err := rows.Scan(&id, &dateTime, &productType)
+		product, err := scanProduct(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %v", err)
 		}
+		products = append(products, *product)
+	}
+	return products, nil
+}
 
-		// Parse the date_time field
-		parsedTime, err := time.Parse(time.RFC3339, dateTime)
+// GetReceptionProducts retrieves one page of the products of a reception,
+// newest first.
+func GetReceptionProducts(receptionId string, req pagination.Request) ([]models.Product, pagination.Info, error) {
+	var total *int
+	if req.Count {
+		var count int
+		err := DB.QueryRow(`SELECT COUNT(*) FROM products WHERE reception_id = ?`, receptionId).Scan(&count)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse time: %v", err)
+			return nil, pagination.Info{}, fmt.Errorf("failed to count products: %v", err)
 		}
+		total = &count
+	}
 
-		products = append(products, models.Product{
-			ID:          id,
-			DateTime:    parsedTime,
-			Type:        productType,
-			ReceptionId: receptionId,
-		})
+	query := `
+    SELECT ` + productColumns + `
+    FROM products p
+    WHERE p.reception_id = ?`
+	args := []interface{}{receptionId}
+	keyset, keysetArgs, order := req.Keyset("p.date_time", "p.id")
+	if keyset != "" {
+		query += " AND " + keyset
+		args = append(args, keysetArgs...)
 	}
-	return products, nil
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, pagination.Info{}, fmt.Errorf("failed to get products: %v", err)
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to scan product: %v", err)
+		}
+		products = append(products, *product)
+	}
+
+	products, info := pagination.Finish(products, req, func(product models.Product) (string, string) {
+		return product.DateTime.Format(time.RFC3339), product.ID
+	})
+	info.Total = total
+	return products, info, nil
+}
+
+// GetProductByBarcode retrieves the product holding the given barcode: stored
+// at a PVZ, or in transit from the PVZ given in the lookup. It returns nil
+// when no product holds that barcode.
+func GetProductByBarcode(code string) (*models.ProductLookup, error) {
+	query := `
+    SELECT ` + productColumns + `, r.pvz_id
+    FROM products p
+    JOIN receptions r ON r.id = ` + productReception + `
+    WHERE p.barcode = ? AND p.status IN ` + heldStatuses
+	var pvzId string
+	product, err := scanProduct(DB.QueryRow(query, code), &pvzId)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get product: %v", err)
+	}
+
+	lookup := &models.ProductLookup{
+		Product: *product,
+		PvzId:   pvzId,
+	}
+	if product.CellId != "" {
+		lookup.Cell, err = GetStorageCellByID(product.CellId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lookup, nil
+}
+
+// UpdateProductStatus moves a product to a new status, provided its current status
+// is one of allowedFrom, and records the change in the status history.
+func UpdateProductStatus(productId, status, comment string, allowedFrom ...string) (*models.Product, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update product status: %v", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow(`SELECT status FROM products WHERE id = ?`, productId).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, ErrProductNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update product status: %v", err)
+	}
+	if !containsString(allowedFrom, current) {
+		return nil, ErrProductStatus.Withf("product is %s and cannot become %s", current, status)
+	}
+
+	if _, err := tx.Exec(`UPDATE products SET status = ? WHERE id = ?`, status, productId); err != nil {
+		return nil, fmt.Errorf("failed to update product status: %v", err)
+	}
+	if err := recordStatus(tx, productId, status, time.Now().Format(time.RFC3339), comment); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update product status: %v", err)
+	}
+	return GetProductByID(productId)
+}
+
+// GetProductStatusHistory retrieves the status changes of a product, oldest
+// first, paginated with cursors.
+func GetProductStatusHistory(productId string, req pagination.Request) ([]models.ProductStatusChange, pagination.Info, error) {
+	if _, err := GetProductByID(productId); err != nil {
+		return nil, pagination.Info{}, err
+	}
+
+	var total *int
+	if req.Count {
+		var count int
+		err := DB.QueryRow(`SELECT COUNT(*) FROM product_status_history WHERE product_id = ?`, productId).Scan(&count)
+		if err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count status history: %v", err)
+		}
+		total = &count
+	}
+
+	query := `
+    SELECT id, status, changed_at, comment
+    FROM product_status_history
+    WHERE product_id = ?`
+	args := []interface{}{productId}
+	keyset, keysetArgs, order := req.KeysetOrdered("id", "id", true)
+	if keyset != "" {
+		query += " AND " + keyset
+		args = append(args, keysetArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, pagination.Info{}, fmt.Errorf("failed to get status history: %v", err)
+	}
+	defer rows.Close()
+
+	// The change IDs only position the cursors; they are not part of the response
+	type entry struct {
+		id     string
+		change models.ProductStatusChange
+	}
+	entries := []entry{}
+	for rows.Next() {
+		var e entry
+		var changedAt string
+		if err := rows.Scan(&e.id, &e.change.Status, &changedAt, &e.change.Comment); err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to scan status history: %v", err)
+		}
+		e.change.ChangedAt, err = time.Parse(time.RFC3339, changedAt)
+		if err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to parse time: %v", err)
+		}
+		entries = append(entries, e)
+	}
+
+	entries, info := pagination.Finish(entries, req, func(e entry) (string, string) {
+		return e.id, e.id
+	})
+	info.Total = total
+	history := make([]models.ProductStatusChange, len(entries))
+	for i, e := range entries {
+		history[i] = e.change
+	}
+	return history, info, nil
 }
 
 // DeleteLastProduct deletes the last product added to the current reception for a given PVZ ID.
@@ -69,7 +398,7 @@ func DeleteLastProduct(pvzId string) error {
 	var receptionId string
 	err := DB.QueryRow(receptionQuery, pvzId).Scan(&receptionId)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("no active reception found for PVZ ID: %s", pvzId)
+		return ErrNoOpenReception.Withf("no active reception found for PVZ ID: %s", pvzId)
 	} else if err != nil {
 		return fmt.Errorf("failed to find active reception: %v", err)
 	}
@@ -84,12 +413,15 @@ func DeleteLastProduct(pvzId string) error {
 	var productId string
 	err = DB.QueryRow(productQuery, receptionId).Scan(&productId)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("no products found in the current reception")
+		return ErrReceptionEmpty
 	} else if err != nil {
 		return fmt.Errorf("failed to find last product: %v", err)
 	}
 
-	// Delete the product
+	// Delete the product together with its history
+	if _, err = DB.Exec(`DELETE FROM product_status_history WHERE product_id = ?`, productId); err != nil {
+		return fmt.Errorf("failed to delete product: %v", err)
+	}
 	deleteQuery := `
     DELETE FROM products
     WHERE id = ?`
@@ -99,3 +431,107 @@ func DeleteLastProduct(pvzId string) error {
 	}
 	return nil
 }
+
+// DeleteProductFromReception removes any product from a reception that is still
+// in progress and records the removal in the reception's audit trail.
+func DeleteProductFromReception(receptionId, productId, actor string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to delete product: %v", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM receptions WHERE id = ?`, receptionId).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrReceptionNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to delete product: %v", err)
+	}
+	if status != models.ReceptionStatusInProgress {
+		return ErrReceptionClosed
+	}
+
+	query := `
+    SELECT ` + productColumns + `
+    FROM products p
+    WHERE p.id = ? AND p.reception_id = ?`
+	product, err := scanProduct(tx.QueryRow(query, productId, receptionId))
+	if err == sql.ErrNoRows {
+		return ErrProductNotInReception
+	} else if err != nil {
+		return fmt.Errorf("failed to delete product: %v", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM product_status_history WHERE product_id = ?`, productId); err != nil {
+		return fmt.Errorf("failed to delete product: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM products WHERE id = ?`, productId); err != nil {
+		return fmt.Errorf("failed to delete product: %v", err)
+	}
+	if err := RecordAudit(tx, "reception", receptionId, "product_deleted", actor, product); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete product: %v", err)
+	}
+	return nil
+}
+
+// scanProduct reads a row selected with productColumns followed by any extra columns.
+func scanProduct(row rowScanner, extra ...interface{}) (*models.Product, error) {
+	var product models.Product
+	var dateTime string
+	var cellId, barcode, orderId, returnBatch sql.NullString
+	var weight, length, width, height sql.NullInt64
+	dest := append([]interface{}{&product.ID, &dateTime, &product.Type, &product.ReceptionId,
+		&cellId, &barcode, &product.Status, &orderId, &returnBatch,
+		&weight, &length, &width, &height}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	// Parse the date_time field
+	parsedTime, err := time.Parse(time.RFC3339, dateTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time: %v", err)
+	}
+	product.DateTime = parsedTime
+	product.CellId = cellId.String
+	product.Barcode = barcode.String
+	product.OrderId = orderId.String
+	product.ReturnBatchId = returnBatch.String
+	product.WeightGrams = int(weight.Int64)
+	if length.Valid && width.Valid && height.Valid {
+		product.Dimensions = &models.Dimensions{LengthMm: int(length.Int64), WidthMm: int(width.Int64), HeightMm: int(height.Int64)}
+	}
+	return &product, nil
+}
+
+func recordStatus(tx execer, productId, status, changedAt, comment string) error {
+	query := `
+    INSERT INTO product_status_history (product_id, status, changed_at, comment)
+    VALUES (?, ?, ?, ?)`
+	if _, err := tx.Exec(query, productId, status, changedAt, comment); err != nil {
+		return fmt.Errorf("failed to record product status: %v", err)
+	}
+	return nil
+}
+
+// nullString stores empty optional values as NULL.
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+var ErrProductTypeNotFound = apperr.NotFound("product_type_not_found", "product type not found")
+
+// defaultProductTypes are the types PVZs accepted before the catalogue existed.
+var defaultProductTypes = []models.ProductType{
+	{Code: "электроника", Names: map[string]string{"ru": "Электроника", "en": "Electronics"}, SizeClass: models.SizeClassMedium, Fragile: true, Active: true},
+	{Code: "одежда", Names: map[string]string{"ru": "Одежда", "en": "Clothes"}, SizeClass: models.SizeClassMedium, Active: true},
+	{Code: "обувь", Names: map[string]string{"ru": "Обувь", "en": "Shoes"}, SizeClass: models.SizeClassMedium, Active: true},
+}
+
+// seedProductTypes adds the default types to an empty catalogue.
+func seedProductTypes() {
+	for _, productType := range defaultProductTypes {
+		names, _ := json.Marshal(productType.Names)
+		query := `
+        INSERT OR IGNORE INTO product_types (code, names, size_class, fragile, hazardous, active)
+        VALUES (?, ?, ?, ?, ?, ?)`
+		_, err := DB.Exec(query, productType.Code, string(names), productType.SizeClass,
+			productType.Fragile, productType.Hazardous, productType.Active)
+		if err != nil {
+			log.Fatalf("Failed to seed product types: %v", err)
+		}
+	}
+}
+
+// SetProductType creates or updates a catalogue entry. The storage period is
+// kept with the other storage periods, so both APIs see the same value: a
+// positive storagePeriodDays sets it, zero removes it and nil leaves it as it is.
+func SetProductType(productType models.ProductType, storagePeriodDays *int) error {
+	names, err := json.Marshal(productType.Names)
+	if err != nil {
+		return fmt.Errorf("failed to encode product type names: %v", err)
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to save product type: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO product_types (code, names, size_class, fragile, hazardous, active,
+        max_weight_grams, max_length_mm, max_width_mm, max_height_mm)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT (code) DO UPDATE SET
+        names = excluded.names, size_class = excluded.size_class, fragile = excluded.fragile,
+        hazardous = excluded.hazardous, active = excluded.active, max_weight_grams = excluded.max_weight_grams,
+        max_length_mm = excluded.max_length_mm, max_width_mm = excluded.max_width_mm,
+        max_height_mm = excluded.max_height_mm`
+	var maxDimensions models.Dimensions
+	if productType.MaxDimensions != nil {
+		maxDimensions = *productType.MaxDimensions
+	}
+	_, err = tx.Exec(query, productType.Code, string(names), productType.SizeClass,
+		productType.Fragile, productType.Hazardous, productType.Active, productType.MaxWeightGrams,
+		maxDimensions.LengthMm, maxDimensions.WidthMm, maxDimensions.HeightMm)
+	if err != nil {
+		return fmt.Errorf("failed to save product type: %v", err)
+	}
+
+	switch {
+	case storagePeriodDays == nil:
+	case *storagePeriodDays > 0:
+		_, err = tx.Exec(`
+        INSERT INTO storage_periods (type, days)
+        VALUES (?, ?)
+        ON CONFLICT (type) DO UPDATE SET days = excluded.days`, productType.Code, *storagePeriodDays)
+	default:
+		_, err = tx.Exec(`DELETE FROM storage_periods WHERE type = ?`, productType.Code)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save product type: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save product type: %v", err)
+	}
+	return nil
+}
+
+const productTypeColumns = `t.code, t.names, t.size_class, t.fragile, t.hazardous, t.active, COALESCE(sp.days, 0),
+    t.max_weight_grams, t.max_length_mm, t.max_width_mm, t.max_height_mm`
+
+func scanProductType(row rowScanner) (*models.ProductType, error) {
+	var productType models.ProductType
+	var names string
+	var maxDimensions models.Dimensions
+	err := row.Scan(&productType.Code, &names, &productType.SizeClass, &productType.Fragile,
+		&productType.Hazardous, &productType.Active, &productType.StoragePeriodDays, &productType.MaxWeightGrams,
+		&maxDimensions.LengthMm, &maxDimensions.WidthMm, &maxDimensions.HeightMm)
+	if err != nil {
+		return nil, err
+	}
+	if maxDimensions != (models.Dimensions{}) {
+		productType.MaxDimensions = &maxDimensions
+	}
+	if err := json.Unmarshal([]byte(names), &productType.Names); err != nil {
+		return nil, fmt.Errorf("failed to decode product type names: %v", err)
+	}
+	return &productType, nil
+}
+
+// GetProductType retrieves a catalogue entry by its code.
+func GetProductType(code string) (*models.ProductType, error) {
+	query := `
+    SELECT ` + productTypeColumns + `
+    FROM product_types t
+    LEFT JOIN storage_periods sp ON sp.type = t.code
+    WHERE t.code = ?`
+	productType, err := scanProductType(DB.QueryRow(query, code))
+	if err == sql.ErrNoRows {
+		return nil, ErrProductTypeNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get product type: %v", err)
+	}
+	return productType, nil
+}
+
+// GetProductTypes retrieves one page of the catalogue, ordered by code.
+// Inactive types are included only on request.
+func GetProductTypes(includeInactive bool, req pagination.Request) ([]models.ProductType, pagination.Info, error) {
+	var conditions []string
+	var args []interface{}
+	if !includeInactive {
+		conditions = append(conditions, "t.active = 1")
+	}
+
+	var total *int
+	if req.Count {
+		query := `SELECT COUNT(*) FROM product_types t`
+		if len(conditions) > 0 {
+			query += " WHERE " + joinConditions(conditions)
+		}
+		var count int
+		if err := DB.QueryRow(query).Scan(&count); err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count product types: %v", err)
+		}
+		total = &count
+	}
+
+	keyset, keysetArgs, order := req.KeysetOrdered("t.code", "t.code", true)
+	if keyset != "" {
+		conditions = append(conditions, keyset)
+		args = append(args, keysetArgs...)
+	}
+	query := `
+    SELECT ` + productTypeColumns + `
+    FROM product_types t
+    LEFT JOIN storage_periods sp ON sp.type = t.code`
+	if len(conditions) > 0 {
+		query += " WHERE " + joinConditions(conditions)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, pagination.Info{}, fmt.Errorf("failed to get product types: %v", err)
+	}
+	defer rows.Close()
+
+	productTypes := []models.ProductType{}
+	for rows.Next() {
+		productType, err := scanProductType(rows)
+		if err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to scan product type: %v", err)
+		}
+		productTypes = append(productTypes, *productType)
+	}
+
+	productTypes, info := pagination.Finish(productTypes, req, func(productType models.ProductType) (string, string) {
+		return productType.Code, productType.Code
+	})
+	info.Total = total
+	return productTypes, info, nil
+}
@@ -3,11 +3,20 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/geo"
 	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
 )
 
+var ErrPVZNotFound = apperr.NotFound("pvz_not_found", "PVZ not found")
+
+const pvzColumns = `id, registration_date, city, address, latitude, longitude, phone`
+
 // CreatePVZ inserts a new PVZ into the database.
 func CreatePVZ(id, city, registrationDate string) error {
 	query := `
@@ -20,94 +29,394 @@ func CreatePVZ(id, city, registrationDate string) error {
 	return nil
 }
 
+// CreatePVZWithDetails inserts a PVZ together with its contact details and schedule.
+func CreatePVZWithDetails(pvz *models.PVZ) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create PVZ: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Coordinates stay NULL for a PVZ without a known location
+	var latitude, longitude interface{}
+	if pvz.Latitude != 0 || pvz.Longitude != 0 {
+		latitude, longitude = pvz.Latitude, pvz.Longitude
+	}
+
+	query := `
+    INSERT INTO pvzs (id, registration_date, city, address, latitude, longitude, phone)
+    VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(query, pvz.ID, pvz.RegistrationDate.Format(time.RFC3339), pvz.City,
+		pvz.Address, latitude, longitude, pvz.Phone)
+	if err != nil {
+		return fmt.Errorf("failed to create PVZ: %v", err)
+	}
+
+	if err := replaceSchedule(tx, pvz.ID, pvz.WorkingHours, pvz.Holidays); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create PVZ: %v", err)
+	}
+	return nil
+}
+
+// SetPVZSchedule replaces the working hours and holiday overrides of a PVZ.
+func SetPVZSchedule(pvzId string, hours []models.WorkingHours, holidays []models.HolidayOverride) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM pvzs WHERE id = ?`, pvzId).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %v", err)
+	}
+	if exists == 0 {
+		return ErrPVZNotFound
+	}
+
+	if err := replaceSchedule(tx, pvzId, hours, holidays); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update schedule: %v", err)
+	}
+	return nil
+}
+
+func replaceSchedule(tx *sql.Tx, pvzId string, hours []models.WorkingHours, holidays []models.HolidayOverride) error {
+	if _, err := tx.Exec(`DELETE FROM pvz_working_hours WHERE pvz_id = ?`, pvzId); err != nil {
+		return fmt.Errorf("failed to update working hours: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM pvz_holidays WHERE pvz_id = ?`, pvzId); err != nil {
+		return fmt.Errorf("failed to update holidays: %v", err)
+	}
+
+	for _, h := range hours {
+		query := `
+        INSERT INTO pvz_working_hours (pvz_id, weekday, open_time, close_time)
+        VALUES (?, ?, ?, ?)`
+		if _, err := tx.Exec(query, pvzId, h.Weekday, h.Open, h.Close); err != nil {
+			return fmt.Errorf("failed to save working hours: %v", err)
+		}
+	}
+	for _, h := range holidays {
+		query := `
+        INSERT INTO pvz_holidays (pvz_id, date, closed, open_time, close_time)
+        VALUES (?, ?, ?, ?, ?)`
+		if _, err := tx.Exec(query, pvzId, h.Date, h.Closed, h.Open, h.Close); err != nil {
+			return fmt.Errorf("failed to save holiday: %v", err)
+		}
+	}
+	return nil
+}
+
 // GetPVZByID retrieves a PVZ by its ID and returns it as a models.PVZ object.
 func GetPVZByID(id string) (*models.PVZ, error) {
 	query := `
-    SELECT id, registration_date, city
+    SELECT ` + pvzColumns + `
     FROM pvzs
     WHERE id = ?`
 
 	row := DB.QueryRow(query, id)
 
-	var pvzID, registrationDate, city string
-	err := row.Scan(&pvzID, &registrationDate, &city)
+	pvz, err := scanPVZ(row)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("PVZ not found")
+		return nil, ErrPVZNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to get PVZ: %v", err)
 	}
 
-	// Parse the registration date
-	parsedDate, err := time.Parse(time.RFC3339, registrationDate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse registration date: %v", err)
+	pvzs := []models.PVZ{*pvz}
+	if err := loadSchedules(pvzs); err != nil {
+		return nil, err
 	}
+	if err := loadIncidentFlags(pvzs); err != nil {
+		return nil, err
+	}
+	return &pvzs[0], nil
+}
 
-	return &models.PVZ{
-		ID:               pvzID,
-		RegistrationDate: parsedDate,
-		City:             city,
-	}, nil
+// PVZ sort orders for GetPVZsFiltered.
+const (
+	PVZSortRegistrationDate = "registrationDate"
+	PVZSortCity             = "city"
+	PVZSortActivity         = "activity"
+)
+
+// PVZFilter narrows GetPVZsFiltered. Empty fields match everything; date
+// bounds are inclusive RFC3339 strings, compared as instants since stored
+// times carry the offset they were written with.
+type PVZFilter struct {
+	RegisteredFrom string
+	RegisteredTo   string
+	Cities         []string
+	// Status is the status of the latest reception, or "none" for a PVZ
+	// without receptions.
+	Status           string
+	HasOpenReception *bool
+	// ProductTypes keeps PVZs that received products of these types, within
+	// the reception date range when one is given.
+	ProductTypes  []string
+	ReceptionFrom string
+	ReceptionTo   string
+	Sort          string
+	Ascending     bool
 }
 
-// GetPVZsFiltered retrieves a filtered and paginated list of PVZs.
-func GetPVZsFiltered(startDate, endDate string, page, limit int) ([]models.PVZ, error) {
-	query := `
-    SELECT id, registration_date, city
-    FROM pvzs`
+// pvzActivity is the last time a PVZ opened or closed a reception or received
+// a product, or an empty string if it never did.
+const pvzActivity = `COALESCE((
+        SELECT MAX(MAX(r.date_time, COALESCE(r.closed_at, '')))
+        FROM receptions r
+        WHERE r.pvz_id = p.id AND r.status != 'pending'), '')`
 
+const pvzProductActivity = `COALESCE((
+        SELECT MAX(pr.date_time)
+        FROM products pr
+        JOIN receptions r ON r.id = pr.reception_id
+        WHERE r.pvz_id = p.id), '')`
+
+var pvzSortKeys = map[string]string{
+	PVZSortRegistrationDate: "p.registration_date",
+	PVZSortCity:             "p.city",
+	PVZSortActivity:         "MAX(" + pvzActivity + ", " + pvzProductActivity + ")",
+}
+
+func (f PVZFilter) conditions() ([]string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 
-	// Add filtering conditions
-	if startDate != "" {
-		conditions = append(conditions, "registration_date >= ?")
-		args = append(args, startDate)
+	if f.RegisteredFrom != "" {
+		conditions = append(conditions, "julianday(p.registration_date) >= julianday(?)")
+		args = append(args, f.RegisteredFrom)
+	}
+	if f.RegisteredTo != "" {
+		conditions = append(conditions, "julianday(p.registration_date) <= julianday(?)")
+		args = append(args, f.RegisteredTo)
+	}
+	if len(f.Cities) > 0 {
+		conditions = append(conditions, "p.city IN ("+inPlaceholders(len(f.Cities))+")")
+		for _, city := range f.Cities {
+			args = append(args, city)
+		}
+	}
+
+	switch f.Status {
+	case "":
+	case "none":
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM receptions r WHERE r.pvz_id = p.id AND r.status != 'pending')")
+	default:
+		conditions = append(conditions, `(
+        SELECT r.status FROM receptions r
+        WHERE r.pvz_id = p.id AND r.status != 'pending'
+        ORDER BY r.date_time DESC, r.id DESC
+        LIMIT 1) = ?`)
+		args = append(args, f.Status)
+	}
+	if f.HasOpenReception != nil {
+		open := "EXISTS (SELECT 1 FROM receptions r WHERE r.pvz_id = p.id AND r.status = 'in_progress')"
+		if !*f.HasOpenReception {
+			open = "NOT " + open
+		}
+		conditions = append(conditions, open)
+	}
+
+	var receptionConditions []string
+	var receptionArgs []interface{}
+	if f.ReceptionFrom != "" {
+		receptionConditions = append(receptionConditions, "julianday(r.date_time) >= julianday(?)")
+		receptionArgs = append(receptionArgs, f.ReceptionFrom)
+	}
+	if f.ReceptionTo != "" {
+		receptionConditions = append(receptionConditions, "julianday(r.date_time) <= julianday(?)")
+		receptionArgs = append(receptionArgs, f.ReceptionTo)
+	}
+	if len(f.ProductTypes) > 0 {
+		receptionConditions = append(receptionConditions, "pr.type IN ("+inPlaceholders(len(f.ProductTypes))+")")
+		for _, productType := range f.ProductTypes {
+			receptionArgs = append(receptionArgs, productType)
+		}
+		conditions = append(conditions, `EXISTS (
+        SELECT 1 FROM products pr
+        JOIN receptions r ON r.id = pr.reception_id
+        WHERE r.pvz_id = p.id AND `+joinConditions(receptionConditions)+`)`)
+		args = append(args, receptionArgs...)
+	} else if len(receptionConditions) > 0 {
+		conditions = append(conditions, `EXISTS (
+        SELECT 1 FROM receptions r
+        WHERE r.pvz_id = p.id AND r.status != 'pending' AND `+joinConditions(receptionConditions)+`)`)
+		args = append(args, receptionArgs...)
+	}
+	return conditions, args
+}
+
+// GetPVZsFiltered retrieves one page of the PVZs matching the filter, in the
+// filter's sort order.
+func GetPVZsFiltered(filter PVZFilter, req pagination.Request) ([]models.PVZ, pagination.Info, error) {
+	sortKey, ok := pvzSortKeys[filter.Sort]
+	if !ok {
+		sortKey = pvzSortKeys[PVZSortRegistrationDate]
 	}
-	if endDate != "" {
-		conditions = append(conditions, "registration_date <= ?")
-		args = append(args, endDate)
+	conditions, args := filter.conditions()
+
+	var total *int
+	if req.Count {
+		query := `SELECT COUNT(*) FROM pvzs p`
+		if len(conditions) > 0 {
+			query += " WHERE " + joinConditions(conditions)
+		}
+		var count int
+		if err := DB.QueryRow(query, args...).Scan(&count); err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count PVZs: %v", err)
+		}
+		total = &count
+	}
+
+	keyset, keysetArgs, order := req.KeysetOrdered(sortKey, "p.id", filter.Ascending)
+	if keyset != "" {
+		conditions = append(conditions, keyset)
+		args = append(args, keysetArgs...)
 	}
 
-	// Join conditions with AND
+	query := `
+    SELECT ` + prefixColumns("p", pvzColumns) + `, ` + sortKey + `
+    FROM pvzs p`
 	if len(conditions) > 0 {
 		query += " WHERE " + joinConditions(conditions)
 	}
-
-	// Add pagination
-	offset := (page - 1) * limit
-	query += " ORDER BY registration_date DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
 
 	// Execute the query
 	rows, err := DB.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get PVZs: %v", err)
+		return nil, pagination.Info{}, fmt.Errorf("failed to get PVZs: %v", err)
 	}
 	defer rows.Close()
 
-	var pvzs []models.PVZ
+	pvzs := []models.PVZ{}
+	keys := map[string]string{}
 	for rows.Next() {
-		var id, registrationDate, city string
-		err := rows.Scan(&id, &registrationDate, &city)
+		var key string
+		pvz, err := scanPVZ(rows, &key)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan PVZ: %v", err)
+			return nil, pagination.Info{}, fmt.Errorf("failed to scan PVZ: %v", err)
 		}
+		pvzs = append(pvzs, *pvz)
+		keys[pvz.ID] = key
+	}
+	rows.Close()
 
-		// Parse the registration date
-		parsedDate, err := time.Parse(time.RFC3339, registrationDate)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse registration date: %v", err)
-		}
+	pvzs, info := pagination.Finish(pvzs, req, func(pvz models.PVZ) (string, string) {
+		return keys[pvz.ID], pvz.ID
+	})
+	info.Total = total
+
+	if err := loadSchedules(pvzs); err != nil {
+		return nil, pagination.Info{}, err
+	}
+	if err := loadIncidentFlags(pvzs); err != nil {
+		return nil, pagination.Info{}, err
+	}
+	return pvzs, info, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPVZ reads a row selected with pvzColumns, followed by any extra columns.
+func scanPVZ(row rowScanner, extra ...interface{}) (*models.PVZ, error) {
+	var id, registrationDate, city, address, phone string
+	var latitude, longitude sql.NullFloat64
+	dest := append([]interface{}{&id, &registrationDate, &city, &address, &latitude, &longitude, &phone}, extra...)
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the registration date
+	parsedDate, err := time.Parse(time.RFC3339, registrationDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registration date: %v", err)
+	}
+
+	return &models.PVZ{
+		ID:               id,
+		RegistrationDate: parsedDate,
+		City:             city,
+		Address:          address,
+		Latitude:         latitude.Float64,
+		Longitude:        longitude.Float64,
+		Phone:            phone,
+	}, nil
+}
+
+// loadSchedules fills in working hours and holiday overrides for the given PVZs.
+func loadSchedules(pvzs []models.PVZ) error {
+	if len(pvzs) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(pvzs))
+	placeholders := make([]string, 0, len(pvzs))
+	args := make([]interface{}, 0, len(pvzs))
+	for i, pvz := range pvzs {
+		index[pvz.ID] = i
+		placeholders = append(placeholders, "?")
+		args = append(args, pvz.ID)
+	}
+	in := "(" + joinStrings(placeholders, ", ") + ")"
 
-		pvzs = append(pvzs, models.PVZ{
-			ID:               id,
-			RegistrationDate: parsedDate,
-			City:             city,
-		})
+	rows, err := DB.Query(`
+    SELECT pvz_id, weekday, open_time, close_time
+    FROM pvz_working_hours
+    WHERE pvz_id IN `+in+`
+    ORDER BY rowid`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get working hours: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pvzId string
+		var h models.WorkingHours
+		if err := rows.Scan(&pvzId, &h.Weekday, &h.Open, &h.Close); err != nil {
+			return fmt.Errorf("failed to scan working hours: %v", err)
+		}
+		i := index[pvzId]
+		pvzs[i].WorkingHours = append(pvzs[i].WorkingHours, h)
 	}
+	rows.Close()
 
-	return pvzs, nil
+	rows, err = DB.Query(`
+    SELECT pvz_id, date, closed, open_time, close_time
+    FROM pvz_holidays
+    WHERE pvz_id IN `+in+`
+    ORDER BY date`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get holidays: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pvzId string
+		var h models.HolidayOverride
+		if err := rows.Scan(&pvzId, &h.Date, &h.Closed, &h.Open, &h.Close); err != nil {
+			return fmt.Errorf("failed to scan holiday: %v", err)
+		}
+		i := index[pvzId]
+		pvzs[i].Holidays = append(pvzs[i].Holidays, h)
+	}
+	return nil
 }
 
 // Helper function to join conditions with a separator
@@ -126,3 +435,62 @@ func joinStrings(items []string, sep string) string {
 	}
 	return result
 }
+
+// inPlaceholders returns "?, ?, ..." with n placeholders for an IN list.
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// GetPVZsNearby returns PVZs within radius meters of the given point, closest first.
+// Candidates are taken from the pvz_locations R-tree and then filtered by exact distance.
+func GetPVZsNearby(lat, lon, radius float64) ([]models.NearbyPVZ, error) {
+	minLat, maxLat, minLon, maxLon := geo.BoundingBox(lat, lon, radius)
+
+	query := `
+    SELECT ` + prefixColumns("p", pvzColumns) + `
+    FROM pvz_locations l
+    JOIN pvz_location_ids m ON m.id = l.id
+    JOIN pvzs p ON p.id = m.pvz_id
+    WHERE l.max_lat >= ? AND l.min_lat <= ?
+      AND l.max_lon >= ? AND l.min_lon <= ?`
+	rows, err := DB.Query(query, minLat, maxLat, minLon, maxLon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search PVZs: %v", err)
+	}
+	defer rows.Close()
+
+	var pvzs []models.PVZ
+	for rows.Next() {
+		pvz, err := scanPVZ(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan PVZ: %v", err)
+		}
+		pvzs = append(pvzs, *pvz)
+	}
+	rows.Close()
+
+	if err := loadSchedules(pvzs); err != nil {
+		return nil, err
+	}
+
+	nearby := []models.NearbyPVZ{}
+	for _, pvz := range pvzs {
+		distance := geo.Distance(lat, lon, pvz.Latitude, pvz.Longitude)
+		if distance <= radius {
+			nearby = append(nearby, models.NearbyPVZ{PVZ: pvz, Distance: distance})
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		return nearby[i].Distance < nearby[j].Distance
+	})
+	return nearby, nil
+}
+
+// prefixColumns qualifies a comma separated column list with a table alias.
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, part := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -5,9 +5,15 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
 	"github.com/StepOne-ai/pvz_avito/internal/models"
 )
 
+var (
+	ErrNoOpenReception     = apperr.Conflict("no_open_reception", "no active reception found")
+	ErrReceptionInProgress = apperr.Conflict("reception_in_progress", "a reception is already in progress")
+)
+
 // CreateReception inserts a new reception into the database.
 func CreateReception(id, dateTime, pvzId, status string) error {
 	query := `
@@ -20,6 +26,50 @@ func CreateReception(id, dateTime, pvzId, status string) error {
 	return nil
 }
 
+// OpenReception makes a reception the active one of its PVZ on behalf of the
+// staff member who opened it. A pending reception announced by a manifest is
+// started instead of inserting the new one; the reception that became active
+// is returned. A PVZ has at most one reception in progress, so opening
+// another fails with ErrReceptionInProgress.
+func OpenReception(reception models.Reception, actor string) (*models.Reception, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reception: %v", err)
+	}
+	defer tx.Rollback()
+
+	var activeId string
+	err = tx.QueryRow(`SELECT id FROM receptions WHERE pvz_id = ? AND status = 'in_progress'`, reception.PvzId).Scan(&activeId)
+	if err == nil {
+		return nil, ErrReceptionInProgress.Withf("reception %s is already in progress at PVZ %s", activeId, reception.PvzId)
+	} else if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to open reception: %v", err)
+	}
+
+	receptionId, err := startPendingReception(tx, reception.PvzId, reception.DateTime, actor)
+	if err != nil {
+		return nil, err
+	}
+	if receptionId == "" {
+		receptionId = reception.ID
+		query := `
+    INSERT INTO receptions (id, date_time, pvz_id, status, kind, opened_by)
+    VALUES (?, ?, ?, ?, ?, ?)`
+		_, err = tx.Exec(query, reception.ID, reception.DateTime.Format(time.RFC3339), reception.PvzId,
+			reception.Status, reception.Kind, actor)
+		if isUniqueViolation(err) {
+			return nil, ErrReceptionInProgress.Withf("a reception is already in progress at PVZ %s", reception.PvzId)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to create reception: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to open reception: %v", err)
+	}
+	return GetReceptionByID(receptionId)
+}
+
 // GetReceptionsByPVZ retrieves all receptions for a given PVZ ID.
 func GetReceptionsByPVZ(pvzID string) ([]models.Reception, error) {
 	query := `
@@ -39,7 +89,7 @@ func GetReceptionsByPVZ(pvzID string) ([]models.Reception, error) {
 		if err != nil {
 			return []models.Reception{}, fmt.Errorf("failed to scan reception: %v", err)
 		}
-		parsedTime, err := time.Parse("2006-01-02 15:04:05 MST", dateTime)
+		parsedTime, err := time.Parse(time.RFC3339, dateTime)
 		if err != nil {
 			return []models.Reception{}, fmt.Errorf("failed to parse time: %v", err)
 		}
@@ -54,63 +104,199 @@ func GetReceptionsByPVZ(pvzID string) ([]models.Reception, error) {
 }
 
 // CloseLastReception closes the last active reception for a given PVZ ID.
+// Products received during the reception become stored, and if a manifest is
+// attached the reception is reconciled against it.
 func CloseLastReception(pvzId string) error {
+	_, err := CloseLastReceptionBy(pvzId, "")
+	return err
+}
+
+// CloseLastReceptionBy works like CloseLastReception, records who closed the
+// reception and returns its ID.
+func CloseLastReceptionBy(pvzId, actor string) (string, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to close reception: %v", err)
+	}
+	defer tx.Rollback()
+
 	query := `
     SELECT id FROM receptions
     WHERE pvz_id = ? AND status = 'in_progress'
     ORDER BY date_time DESC
     LIMIT 1`
 	var receptionId string
-	err := DB.QueryRow(query, pvzId).Scan(&receptionId)
+	err = tx.QueryRow(query, pvzId).Scan(&receptionId)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("no active reception found for PVZ ID: %s", pvzId)
+		return "", ErrNoOpenReception.Withf("no active reception found for PVZ ID: %s", pvzId)
 	} else if err != nil {
-		return fmt.Errorf("failed to find active reception: %v", err)
+		return "", fmt.Errorf("failed to find active reception: %v", err)
 	}
 
+	if err := closeReception(tx, receptionId, actor); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to close reception: %v", err)
+	}
+	return receptionId, nil
+}
+
+// CloseReception closes a specific open reception the same way CloseLastReception
+// does and records who closed it.
+func CloseReception(receptionId, actor string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to close reception: %v", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM receptions WHERE id = ?`, receptionId).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrReceptionNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to close reception: %v", err)
+	}
+	if status != models.ReceptionStatusInProgress {
+		return ErrReceptionClosed
+	}
+
+	if err := closeReception(tx, receptionId, actor); err != nil {
+		return err
+	}
+	if err := RecordAudit(tx, "reception", receptionId, "closed", actor, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to close reception: %v", err)
+	}
+	return nil
+}
+
+func closeReception(tx *sql.Tx, receptionId, actor string) error {
 	updateQuery := `
     UPDATE receptions
-    SET status = 'close'
+    SET status = 'close', closed_at = ?, closed_by = ?
     WHERE id = ?`
-	_, err = DB.Exec(updateQuery, receptionId)
-	if err != nil {
+	if _, err := tx.Exec(updateQuery, time.Now().Format(time.RFC3339), actor, receptionId); err != nil {
 		return fmt.Errorf("failed to close reception: %v", err)
 	}
 
+	if err := storeReceivedProducts(tx, receptionId); err != nil {
+		return err
+	}
+	return reconcileReception(tx, receptionId)
+}
+
+// storeReceivedProducts moves the received products of a reception to stored.
+func storeReceivedProducts(tx *sql.Tx, receptionId string) error {
+	now := time.Now().Format(time.RFC3339)
+	historyQuery := `
+    INSERT INTO product_status_history (product_id, status, changed_at, comment)
+    SELECT id, ?, ?, 'reception closed'
+    FROM products
+    WHERE reception_id = ? AND status = ?`
+	_, err := tx.Exec(historyQuery, models.ProductStatusStored, now, receptionId, models.ProductStatusReceived)
+	if err != nil {
+		return fmt.Errorf("failed to store products: %v", err)
+	}
+
+	updateQuery := `
+    UPDATE products
+    SET status = ?
+    WHERE reception_id = ? AND status = ?`
+	_, err = tx.Exec(updateQuery, models.ProductStatusStored, receptionId, models.ProductStatusReceived)
+	if err != nil {
+		return fmt.Errorf("failed to store products: %v", err)
+	}
 	return nil
 }
 
 // GetReceptionByID retrieves a reception by its ID and returns it as a models.Reception object.
 func GetReceptionByID(id string) (*models.Reception, error) {
 	query := `
-    SELECT id, date_time, pvz_id, status
+    SELECT id, date_time, pvz_id, status, kind, opened_by, closed_by, closed_at
     FROM receptions
     WHERE id = ?`
 
-	row := DB.QueryRow(query, id)
-
-	// Variables to store the result
-	var receptionID, dateTime, pvzID, status string
-
-	// Scan the row into variables
-	err := row.Scan(&receptionID, &dateTime, &pvzID, &status)
+	var reception models.Reception
+	var dateTime string
+	var closedAt sql.NullString
+	err := DB.QueryRow(query, id).Scan(&reception.ID, &dateTime, &reception.PvzId, &reception.Status,
+		&reception.Kind, &reception.OpenedBy, &reception.ClosedBy, &closedAt)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("reception not found")
+		return nil, ErrReceptionNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to get reception: %v", err)
 	}
 
-	// Parse the date_time field
-	parsedTime, err := time.Parse("2006-01-02 15:04:05 MST", dateTime)
+	reception.DateTime, err = time.Parse(time.RFC3339, dateTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse time: %v", err)
 	}
+	if closedAt.Valid {
+		parsed, err := time.Parse(time.RFC3339, closedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time: %v", err)
+		}
+		reception.ClosedAt = &parsed
+	}
+	return &reception, nil
+}
 
-	// Return the reception data as a models.Reception object
-	return &models.Reception{
-		ID:       receptionID,
-		DateTime: parsedTime,
-		PvzId:    pvzID,
-		Status:   status,
-	}, nil
+// GetReceptionTotals sums the weight and volume of the measured products of a reception.
+func GetReceptionTotals(receptionId string) (weightGrams, volumeCm3 int64, err error) {
+	err = DB.QueryRow(`
+    SELECT COALESCE(SUM(weight_grams), 0), COALESCE(SUM(length_mm * width_mm * height_mm), 0) / 1000
+    FROM products
+    WHERE reception_id = ?`, receptionId).Scan(&weightGrams, &volumeCm3)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get reception totals: %v", err)
+	}
+	return weightGrams, volumeCm3, nil
+}
+
+// GetReceptionProductCounts counts the products of a reception per type.
+func GetReceptionProductCounts(receptionId string) (map[string]int, error) {
+	rows, err := DB.Query(`
+    SELECT type, COUNT(*)
+    FROM products
+    WHERE reception_id = ?
+    GROUP BY type`, receptionId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count products: %v", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var productType string
+		var count int
+		if err := rows.Scan(&productType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan product count: %v", err)
+		}
+		counts[productType] = count
+	}
+	return counts, nil
+}
+
+// GetActiveReception retrieves the reception currently in progress for a given PVZ ID.
+func GetActiveReception(pvzId string) (*models.Reception, error) {
+	query := `
+    SELECT id
+    FROM receptions
+    WHERE pvz_id = ? AND status = 'in_progress'
+    ORDER BY date_time DESC
+    LIMIT 1`
+	var receptionId string
+	err := DB.QueryRow(query, pvzId).Scan(&receptionId)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoOpenReception.Withf("no active reception found for PVZ ID: %s", pvzId)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find active reception: %v", err)
+	}
+	return GetReceptionByID(receptionId)
 }
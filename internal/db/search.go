@@ -0,0 +1,332 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// Search result kinds.
+const (
+	SearchKindPVZ       = "pvz"
+	SearchKindReception = "reception"
+	SearchKindProduct   = "product"
+	SearchKindOrder     = "order"
+)
+
+// SearchKinds lists the searchable kinds in the order results are merged.
+var SearchKinds = []string{SearchKindPVZ, SearchKindReception, SearchKindProduct, SearchKindOrder}
+
+// searchModule is the full-text module behind the search tables: fts5 when
+// the driver is built with the sqlite_fts5 tag, fts4 otherwise.
+var searchModule string
+
+// searchSource describes one search table. Every indexed row keeps the id of
+// the row it was built from in an unindexed column; the implicit rowid of a
+// table with a TEXT primary key may change on VACUUM, so it is never used.
+// The title is weighted above the body when ranking.
+type searchSource struct {
+	kind  string
+	table string
+	// source is the indexed table, aliased s in queries
+	source string
+	// title and body are expressions over NEW or the source alias, which
+	// replaces every %s
+	title, body string
+	// columns lists the source columns whose change requires reindexing
+	columns string
+	// pvzId is the expression giving the PVZ a result belongs to
+	pvzId string
+	joins string
+}
+
+var searchSources = []searchSource{
+	{
+		kind: SearchKindPVZ, table: "search_pvzs", source: "pvzs",
+		title: "%s.city", body: "%s.address", columns: "city, address",
+		pvzId: "s.id",
+	},
+	{
+		kind: SearchKindReception, table: "search_receptions", source: "receptions",
+		title: "%s.id", body: "%s.opened_by || ' ' || %s.closed_by", columns: "opened_by, closed_by",
+		pvzId: "s.pvz_id",
+	},
+	{
+		kind: SearchKindProduct, table: "search_products", source: "products",
+		title: "COALESCE(%s.barcode, '')", body: "%s.type || ' ' || %s.id", columns: "barcode, type",
+		pvzId: "r.pvz_id", joins: "JOIN receptions r ON r.id = COALESCE(s.transfer_reception_id, s.reception_id)",
+	},
+	{
+		kind: SearchKindOrder, table: "search_orders", source: "orders",
+		title: "%s.order_number", body: "''", columns: "order_number",
+		pvzId: "s.pvz_id",
+	},
+}
+
+// createSearchIndex creates the full-text tables with the triggers that keep
+// them in sync, and fills tables created for an existing database.
+func createSearchIndex() {
+	searchModule = ""
+	for _, source := range searchSources {
+		var existing string
+		err := DB.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, source.table).Scan(&existing)
+		if err != nil && err != sql.ErrNoRows {
+			log.Fatalf("Failed to inspect search index: %v", err)
+		}
+
+		// Tables created before the id column joined on rowid; rebuild them
+		if existing != "" && !strings.Contains(strings.ToLower(existing), "indexed") {
+			if err := dropSearchTable(source); err != nil {
+				log.Fatalf("Failed to rebuild search index: %v", err)
+			}
+			existing = ""
+		}
+
+		if existing != "" {
+			if strings.Contains(strings.ToLower(existing), "fts5") {
+				searchModule = "fts5"
+			} else {
+				searchModule = "fts4"
+			}
+		} else {
+			if err := createSearchTable(source); err != nil {
+				log.Fatalf("Failed to create search index: %v", err)
+			}
+			fill := fmt.Sprintf(`INSERT INTO %s (id, title, body) SELECT s.id, %s, %s FROM %s s`,
+				source.table, source.expr(source.title, "s"), source.expr(source.body, "s"), source.source)
+			if _, err := DB.Exec(fill); err != nil {
+				log.Fatalf("Failed to fill search index: %v", err)
+			}
+		}
+
+		newTitle, newBody := source.expr(source.title, "NEW"), source.expr(source.body, "NEW")
+		triggers := []string{
+			fmt.Sprintf(`
+    CREATE TRIGGER IF NOT EXISTS %[1]s_insert AFTER INSERT ON %[2]s
+    BEGIN
+        INSERT INTO %[1]s (id, title, body) VALUES (NEW.id, %[3]s, %[4]s);
+    END;`, source.table, source.source, newTitle, newBody),
+			fmt.Sprintf(`
+    CREATE TRIGGER IF NOT EXISTS %[1]s_update AFTER UPDATE OF %[5]s ON %[2]s
+    BEGIN
+        DELETE FROM %[1]s WHERE id = OLD.id;
+        INSERT INTO %[1]s (id, title, body) VALUES (NEW.id, %[3]s, %[4]s);
+    END;`, source.table, source.source, newTitle, newBody, source.columns),
+			fmt.Sprintf(`
+    CREATE TRIGGER IF NOT EXISTS %[1]s_delete AFTER DELETE ON %[2]s
+    BEGIN
+        DELETE FROM %[1]s WHERE id = OLD.id;
+    END;`, source.table, source.source),
+		}
+		for _, trigger := range triggers {
+			if _, err := DB.Exec(trigger); err != nil {
+				log.Fatalf("Failed to create search index: %v", err)
+			}
+		}
+	}
+}
+
+func (s searchSource) expr(format, alias string) string {
+	return strings.ReplaceAll(format, "%s", alias)
+}
+
+// createSearchTable prefers FTS5 and falls back to FTS4, which the driver
+// always includes.
+func createSearchTable(source searchSource) error {
+	if searchModule != "fts4" {
+		_, err := DB.Exec(`CREATE VIRTUAL TABLE ` + source.table + ` USING fts5(id UNINDEXED, title, body, tokenize = 'unicode61')`)
+		if err == nil {
+			searchModule = "fts5"
+			return nil
+		}
+		if !strings.Contains(err.Error(), "no such module") {
+			return err
+		}
+	}
+	if _, err := DB.Exec(`CREATE VIRTUAL TABLE ` + source.table + ` USING fts4(id, title, body, notindexed=id, tokenize=unicode61)`); err != nil {
+		return err
+	}
+	searchModule = "fts4"
+	return nil
+}
+
+// dropSearchTable removes a search table with the triggers that fill it.
+func dropSearchTable(source searchSource) error {
+	for _, suffix := range []string{"_insert", "_update", "_delete"} {
+		if _, err := DB.Exec(`DROP TRIGGER IF EXISTS ` + source.table + suffix); err != nil {
+			return err
+		}
+	}
+	_, err := DB.Exec(`DROP TABLE IF EXISTS ` + source.table)
+	return err
+}
+
+// SearchModule reports which full-text module backs search.
+func SearchModule() string {
+	return searchModule
+}
+
+var searchTokenPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// maxSearchTokens bounds the size of the generated MATCH expression.
+const maxSearchTokens = 10
+
+// searchCandidates is how many matches per kind FTS4 ranks in Go, since it
+// has no ranking function to sort by in SQL.
+const searchCandidates = 500
+
+// Highlight markers are control characters that do not occur in addresses,
+// barcodes or order numbers; highlight turns them into <mark> tags.
+const (
+	markOpen  = "\x02"
+	markClose = "\x03"
+)
+
+// SearchQuery turns free text into a MATCH expression that requires every
+// word, each as a prefix. It returns an empty string if there are no words.
+func SearchQuery(text string) string {
+	tokens := searchTokenPattern.FindAllString(strings.ToLower(text), maxSearchTokens)
+	for i, token := range tokens {
+		tokens[i] = token + "*"
+	}
+	return strings.Join(tokens, " ")
+}
+
+// Search finds PVZs, receptions, products and orders matching the text, best
+// matches first. Titles and snippets mark the matched words with <mark>.
+func Search(text string, kinds []string, limit int) ([]models.SearchResult, error) {
+	match := SearchQuery(text)
+	if match == "" {
+		return []models.SearchResult{}, nil
+	}
+
+	wanted := map[string]bool{}
+	for _, kind := range kinds {
+		wanted[kind] = true
+	}
+
+	results := []models.SearchResult{}
+	for _, source := range searchSources {
+		if len(wanted) > 0 && !wanted[source.kind] {
+			continue
+		}
+		found, err := searchTable(source, match, limit)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func searchTable(source searchSource, match string, limit int) ([]models.SearchResult, error) {
+	var query string
+	if searchModule == "fts5" {
+		query = fmt.Sprintf(`
+    SELECT s.id, %[2]s, highlight(%[1]s, 1, ?, ?), highlight(%[1]s, 2, ?, ?), -bm25(%[1]s, 0.0, 10.0, 1.0)
+    FROM %[1]s
+    JOIN %[3]s s ON s.id = %[1]s.id
+    %[4]s
+    WHERE %[1]s MATCH ?
+    ORDER BY bm25(%[1]s, 0.0, 10.0, 1.0)
+    LIMIT ?`, source.table, source.pvzId, source.source, source.joins)
+	} else {
+		query = fmt.Sprintf(`
+    SELECT s.id, %[2]s, snippet(%[1]s, ?, ?, '…', 1, 64), snippet(%[1]s, ?, ?, '…', 2, 64), matchinfo(%[1]s, 'pcnalx')
+    FROM %[1]s
+    JOIN %[3]s s ON s.id = %[1]s.id
+    %[4]s
+    WHERE %[1]s MATCH ?
+    LIMIT ?`, source.table, source.pvzId, source.source, source.joins)
+		limit = searchCandidates
+	}
+
+	rows, err := DB.Query(query, markOpen, markClose, markOpen, markClose, match, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %v", err)
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		result := models.SearchResult{Kind: source.kind}
+		var title, body string
+		var rank interface{}
+		if err := rows.Scan(&result.ID, &result.PvzId, &title, &body, &rank); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %v", err)
+		}
+		switch rank := rank.(type) {
+		case float64:
+			result.Score = rank
+		case []byte:
+			result.Score = bm25(rank, []float64{0, 10.0, 1.0})
+		}
+		result.Title = highlight(title)
+		result.Snippet = highlight(body)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// highlight escapes indexed text for HTML and turns the match markers into
+// <mark> tags.
+func highlight(text string) string {
+	text = html.EscapeString(strings.TrimSpace(text))
+	text = strings.ReplaceAll(text, markOpen, "<mark>")
+	return strings.ReplaceAll(text, markClose, "</mark>")
+}
+
+// bm25 scores an FTS4 match from matchinfo(..., 'pcnalx') the way FTS5's
+// bm25() does, with per-column weights. Higher is better.
+func bm25(info []byte, weights []float64) float64 {
+	const k1, b = 1.2, 0.75
+	if len(info)%4 != 0 || len(info) < 12 {
+		return 0
+	}
+	values := make([]float64, len(info)/4)
+	for i := range values {
+		values[i] = float64(binary.NativeEndian.Uint32(info[i*4:]))
+	}
+
+	phrases, columns, rows := int(values[0]), int(values[1]), values[2]
+	if len(values) < 3+2*columns+3*phrases*columns {
+		return 0
+	}
+	averages := values[3 : 3+columns]
+	lengths := values[3+columns : 3+2*columns]
+	hits := values[3+2*columns:]
+
+	score := 0.0
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns && c < len(weights); c++ {
+			x := hits[3*(p*columns+c):]
+			inRow, docsWithHit := x[0], x[2]
+			if inRow == 0 {
+				continue
+			}
+			idf := math.Log((rows - docsWithHit + 0.5) / (docsWithHit + 0.5))
+			if idf < 1e-6 {
+				idf = 1e-6
+			}
+			average := averages[c]
+			if average == 0 {
+				average = 1
+			}
+			score += weights[c] * idf * inRow * (k1 + 1) / (inRow + k1*(1-b+b*lengths[c]/average))
+		}
+	}
+	return score
+}
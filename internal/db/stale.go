@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+// SetReceptionTimeout overrides how long receptions of a PVZ may stay open
+// and what happens to them afterwards.
+func SetReceptionTimeout(pvzId string, timeout models.ReceptionTimeout) error {
+	result, err := DB.Exec(`
+    UPDATE pvzs
+    SET reception_timeout_minutes = ?, stale_reception_action = ?
+    WHERE id = ?`, timeout.Minutes, timeout.Action, pvzId)
+	if err != nil {
+		return fmt.Errorf("failed to update reception timeout: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrPVZNotFound
+	}
+	return nil
+}
+
+// staleReceptionCondition selects the receptions in progress for longer than
+// their PVZ's timeout. It takes the current moment and the default timeout in
+// minutes.
+const staleReceptionCondition = `r.status = 'in_progress'
+        AND (julianday(?) - julianday(r.date_time)) * 1440 > COALESCE(NULLIF(p.reception_timeout_minutes, 0), ?)`
+
+const staleReceptionColumns = `r.id, r.date_time, r.pvz_id, r.status, r.kind, r.stale_flagged_at,
+        p.reception_timeout_minutes, p.stale_reception_action`
+
+// GetStaleReceptions returns the receptions that have been in progress longer
+// than their PVZ's timeout at the given moment, oldest first. PVZs without an
+// override use the given defaults.
+func GetStaleReceptions(now time.Time, defaultTimeout time.Duration, defaultAction string) ([]models.StaleReception, error) {
+	query := `
+    SELECT ` + staleReceptionColumns + `
+    FROM receptions r
+    JOIN pvzs p ON p.id = r.pvz_id
+    WHERE ` + staleReceptionCondition + `
+    ORDER BY r.date_time, r.id`
+	return queryStaleReceptions(query, now, defaultTimeout, defaultAction,
+		now.Format(time.RFC3339), int(defaultTimeout/time.Minute))
+}
+
+// GetStaleReceptionsPage is GetStaleReceptions one page at a time.
+func GetStaleReceptionsPage(now time.Time, defaultTimeout time.Duration, defaultAction string, req pagination.Request) ([]models.StaleReception, pagination.Info, error) {
+	args := []interface{}{now.Format(time.RFC3339), int(defaultTimeout / time.Minute)}
+
+	var total *int
+	if req.Count {
+		query := `
+        SELECT COUNT(*)
+        FROM receptions r
+        JOIN pvzs p ON p.id = r.pvz_id
+        WHERE ` + staleReceptionCondition
+		var count int
+		if err := DB.QueryRow(query, args...).Scan(&count); err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count stale receptions: %v", err)
+		}
+		total = &count
+	}
+
+	query := `
+    SELECT ` + staleReceptionColumns + `
+    FROM receptions r
+    JOIN pvzs p ON p.id = r.pvz_id
+    WHERE ` + staleReceptionCondition
+	keyset, keysetArgs, order := req.KeysetOrdered("r.date_time", "r.id", true)
+	if keyset != "" {
+		query += " AND " + keyset
+		args = append(args, keysetArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	stale, err := queryStaleReceptions(query, now, defaultTimeout, defaultAction, args...)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	stale, info := pagination.Finish(stale, req, func(reception models.StaleReception) (string, string) {
+		return reception.DateTime.Format(time.RFC3339), reception.ID
+	})
+	info.Total = total
+	return stale, info, nil
+}
+
+func queryStaleReceptions(query string, now time.Time, defaultTimeout time.Duration, defaultAction string, args ...interface{}) ([]models.StaleReception, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale receptions: %v", err)
+	}
+	defer rows.Close()
+
+	stale := []models.StaleReception{}
+	for rows.Next() {
+		var reception models.StaleReception
+		var dateTime string
+		var flaggedAt sql.NullString
+		err := rows.Scan(&reception.ID, &dateTime, &reception.PvzId, &reception.Status, &reception.Kind,
+			&flaggedAt, &reception.TimeoutMinutes, &reception.Action)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reception: %v", err)
+		}
+
+		reception.DateTime, err = time.Parse(time.RFC3339, dateTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time: %v", err)
+		}
+		if flaggedAt.Valid {
+			parsed, err := time.Parse(time.RFC3339, flaggedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse time: %v", err)
+			}
+			reception.FlaggedAt = &parsed
+		}
+		if reception.TimeoutMinutes == 0 {
+			reception.TimeoutMinutes = int(defaultTimeout / time.Minute)
+		}
+		if reception.Action == "" {
+			reception.Action = defaultAction
+		}
+		reception.OpenMinutes = int(now.Sub(reception.DateTime) / time.Minute)
+		stale = append(stale, reception)
+	}
+	return stale, nil
+}
+
+// FlagStaleReception marks an open reception as stale without closing it.
+func FlagStaleReception(receptionId string, at time.Time) error {
+	_, err := DB.Exec(`
+    UPDATE receptions
+    SET stale_flagged_at = ?
+    WHERE id = ? AND status = 'in_progress'`, at.Format(time.RFC3339), receptionId)
+	if err != nil {
+		return fmt.Errorf("failed to flag reception: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+var (
+	ErrReturnBatchNotFound = apperr.NotFound("return_batch_not_found", "return batch not found")
+	ErrReturnBatchStatus   = apperr.Conflict("return_batch_dispatched", "return batch is already dispatched")
+)
+
+// SetStoragePeriod configures how many days products of a type are kept.
+func SetStoragePeriod(period models.StoragePeriod) error {
+	query := `
+    INSERT INTO storage_periods (type, days)
+    VALUES (?, ?)
+    ON CONFLICT (type) DO UPDATE SET days = excluded.days`
+	if _, err := DB.Exec(query, period.Type, period.Days); err != nil {
+		return fmt.Errorf("failed to set storage period: %v", err)
+	}
+	return nil
+}
+
+// GetStoragePeriods retrieves all configured storage periods.
+func GetStoragePeriods() ([]models.StoragePeriod, error) {
+	rows, err := DB.Query(`SELECT type, days FROM storage_periods ORDER BY type`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage periods: %v", err)
+	}
+	defer rows.Close()
+
+	periods := []models.StoragePeriod{}
+	for rows.Next() {
+		var period models.StoragePeriod
+		if err := rows.Scan(&period.Type, &period.Days); err != nil {
+			return nil, fmt.Errorf("failed to scan storage period: %v", err)
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// expiringDeadline is the storage deadline of a product in UTC as RFC 3339,
+// so that deadlines compare as strings. Intake times are stored to the second.
+const expiringDeadline = `strftime('%Y-%m-%dT%H:%M:%SZ', p.date_time, '+' || COALESCE(sp.days, ?) || ' days')`
+
+// expiringFrom selects the stored products whose deadline falls before the
+// given moment as e, with their PVZ, storage period and deadline.
+func expiringFrom(pvzId string, before time.Time, includeBatched bool) (string, []interface{}) {
+	inner := `
+        SELECT ` + productColumns + `, r.pvz_id, COALESCE(sp.days, ?) AS days, ` + expiringDeadline + ` AS deadline
+        FROM products p
+        JOIN receptions r ON r.id = ` + productReception + `
+        LEFT JOIN storage_periods sp ON sp.type = p.type
+        WHERE p.status = ?`
+	args := []interface{}{models.DefaultStoragePeriodDays, models.DefaultStoragePeriodDays, models.ProductStatusStored}
+	if pvzId != "" {
+		inner += " AND r.pvz_id = ?"
+		args = append(args, pvzId)
+	}
+	if !includeBatched {
+		inner += " AND p.return_batch_id IS NULL"
+	}
+
+	// Deadlines are whole seconds, so rounding the moment up keeps the comparison exact
+	bound := before.Add(time.Second - 1).Truncate(time.Second).UTC().Format(time.RFC3339)
+	return `
+    FROM (` + inner + `
+    ) e
+    WHERE e.deadline < ?`, append(args, bound)
+}
+
+// GetExpiringProducts returns stored products whose storage deadline falls before
+// the given moment, soonest deadline first. An empty pvzId searches all PVZs;
+// includeBatched also returns products already assigned to a return batch.
+func GetExpiringProducts(pvzId string, before time.Time, now time.Time, includeBatched bool) ([]models.ExpiringProduct, error) {
+	from, args := expiringFrom(pvzId, before, includeBatched)
+	return queryExpiringProducts(`SELECT e.*`+from+` ORDER BY e.deadline, e.id`, now, args...)
+}
+
+// GetExpiringProductsPage is GetExpiringProducts for a single PVZ, paginated
+// with cursors.
+func GetExpiringProductsPage(pvzId string, before time.Time, now time.Time, req pagination.Request) ([]models.ExpiringProduct, pagination.Info, error) {
+	from, args := expiringFrom(pvzId, before, true)
+
+	var total *int
+	if req.Count {
+		var count int
+		if err := DB.QueryRow(`SELECT COUNT(*)`+from, args...).Scan(&count); err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count expiring products: %v", err)
+		}
+		total = &count
+	}
+
+	query := `SELECT e.*` + from
+	keyset, keysetArgs, order := req.KeysetOrdered("e.deadline", "e.id", true)
+	if keyset != "" {
+		query += " AND " + keyset
+		args = append(args, keysetArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	expiring, err := queryExpiringProducts(query, now, args...)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	expiring, info := pagination.Finish(expiring, req, func(product models.ExpiringProduct) (string, string) {
+		return product.Deadline.UTC().Format(time.RFC3339), product.ID
+	})
+	info.Total = total
+	return expiring, info, nil
+}
+
+func queryExpiringProducts(query string, now time.Time, args ...interface{}) ([]models.ExpiringProduct, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring products: %v", err)
+	}
+	defer rows.Close()
+
+	expiring := []models.ExpiringProduct{}
+	for rows.Next() {
+		var productPvzId, sortKey string
+		var days int
+		product, err := scanProduct(rows, &productPvzId, &days, &sortKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product: %v", err)
+		}
+
+		deadline := product.DateTime.AddDate(0, 0, days)
+		expiring = append(expiring, models.ExpiringProduct{
+			Product:  *product,
+			PvzId:    productPvzId,
+			Deadline: deadline,
+			Overdue:  !deadline.After(now),
+		})
+	}
+	return expiring, nil
+}
+
+// CreateReturnBatch groups overdue products of a PVZ into a new return batch.
+func CreateReturnBatch(batch models.ReturnBatch) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create return batch: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO return_batches (id, pvz_id, status, created_at)
+    VALUES (?, ?, ?, ?)`
+	_, err = tx.Exec(query, batch.ID, batch.PvzId, batch.Status, batch.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to create return batch: %v", err)
+	}
+
+	for _, productId := range batch.ProductIds {
+		_, err := tx.Exec(`
+        UPDATE products
+        SET return_batch_id = ?
+        WHERE id = ? AND return_batch_id IS NULL`, batch.ID, productId)
+		if err != nil {
+			return fmt.Errorf("failed to create return batch: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create return batch: %v", err)
+	}
+	return nil
+}
+
+// GetReturnBatchesByPVZ retrieves the return batches of a PVZ, newest first.
+func GetReturnBatchesByPVZ(pvzId string) ([]models.ReturnBatch, error) {
+	return queryReturnBatches(`
+    SELECT id
+    FROM return_batches
+    WHERE pvz_id = ?
+    ORDER BY created_at DESC, id DESC`, pvzId)
+}
+
+// GetReturnBatchesPage is GetReturnBatchesByPVZ paginated with cursors.
+func GetReturnBatchesPage(pvzId string, req pagination.Request) ([]models.ReturnBatch, pagination.Info, error) {
+	var total *int
+	if req.Count {
+		var count int
+		if err := DB.QueryRow(`SELECT COUNT(*) FROM return_batches WHERE pvz_id = ?`, pvzId).Scan(&count); err != nil {
+			return nil, pagination.Info{}, fmt.Errorf("failed to count return batches: %v", err)
+		}
+		total = &count
+	}
+
+	query := `
+    SELECT id
+    FROM return_batches
+    WHERE pvz_id = ?`
+	args := []interface{}{pvzId}
+	keyset, keysetArgs, order := req.Keyset("created_at", "id")
+	if keyset != "" {
+		query += " AND " + keyset
+		args = append(args, keysetArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	offset := req.Offset
+	if req.Cursor != nil {
+		offset = 0
+	}
+	args = append(args, req.Limit+1, offset)
+
+	batches, err := queryReturnBatches(query, args...)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	batches, info := pagination.Finish(batches, req, func(batch models.ReturnBatch) (string, string) {
+		return batch.CreatedAt.Format(time.RFC3339), batch.ID
+	})
+	info.Total = total
+	return batches, info, nil
+}
+
+// queryReturnBatches loads the return batches whose IDs the query selects.
+func queryReturnBatches(query string, args ...interface{}) ([]models.ReturnBatch, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return batches: %v", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan return batch: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	batches := []models.ReturnBatch{}
+	for _, id := range ids {
+		batch, err := GetReturnBatchByID(id)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, *batch)
+	}
+	return batches, nil
+}
+
+// GetReturnBatchByID retrieves a return batch with the IDs of its products.
+func GetReturnBatchByID(id string) (*models.ReturnBatch, error) {
+	var batch models.ReturnBatch
+	var createdAt string
+	var dispatchedAt sql.NullString
+	err := DB.QueryRow(`
+    SELECT id, pvz_id, status, created_at, dispatched_at
+    FROM return_batches
+    WHERE id = ?`, id).Scan(&batch.ID, &batch.PvzId, &batch.Status, &createdAt, &dispatchedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrReturnBatchNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get return batch: %v", err)
+	}
+
+	batch.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time: %v", err)
+	}
+	if dispatchedAt.Valid {
+		parsed, err := time.Parse(time.RFC3339, dispatchedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time: %v", err)
+		}
+		batch.DispatchedAt = &parsed
+	}
+
+	rows, err := DB.Query(`SELECT id FROM products WHERE return_batch_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return batch products: %v", err)
+	}
+	defer rows.Close()
+	batch.ProductIds = []string{}
+	for rows.Next() {
+		var productId string
+		if err := rows.Scan(&productId); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %v", err)
+		}
+		batch.ProductIds = append(batch.ProductIds, productId)
+	}
+	return &batch, nil
+}
+
+// DispatchReturnBatch hands a pending batch to the courier: its stored products
+// become returned_to_sender.
+func DispatchReturnBatch(id string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to dispatch return batch: %v", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM return_batches WHERE id = ?`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrReturnBatchNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to dispatch return batch: %v", err)
+	}
+	if status != models.ReturnBatchPending {
+		return ErrReturnBatchStatus
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	historyQuery := `
+    INSERT INTO product_status_history (product_id, status, changed_at, comment)
+    SELECT id, ?, ?, 'storage period expired'
+    FROM products
+    WHERE return_batch_id = ? AND status = ?`
+	if _, err := tx.Exec(historyQuery, models.ProductStatusReturnedToSender, now, id, models.ProductStatusStored); err != nil {
+		return fmt.Errorf("failed to dispatch return batch: %v", err)
+	}
+	if _, err := tx.Exec(`UPDATE products SET status = ? WHERE return_batch_id = ? AND status = ?`,
+		models.ProductStatusReturnedToSender, id, models.ProductStatusStored); err != nil {
+		return fmt.Errorf("failed to dispatch return batch: %v", err)
+	}
+	if _, err := tx.Exec(`UPDATE return_batches SET status = ?, dispatched_at = ? WHERE id = ?`,
+		models.ReturnBatchDispatched, now, id); err != nil {
+		return fmt.Errorf("failed to dispatch return batch: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to dispatch return batch: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+var (
+	ErrTransferNotFound        = apperr.NotFound("transfer_not_found", "transfer not found")
+	ErrTransferStatus          = apperr.Conflict("transfer_status", "transfer is not in a state that allows this action")
+	ErrProductNotTransferrable = apperr.Conflict("product_not_transferrable", "product is not stored at the source PVZ")
+)
+
+// CreateTransfer dispatches stored products from the source PVZ. The products
+// become in_transit and stop counting towards the source occupancy.
+func CreateTransfer(transfer models.Transfer, actor string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create transfer: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO transfers (id, source_pvz_id, destination_pvz_id, status, created_at)
+    VALUES (?, ?, ?, ?, ?)`
+	createdAt := transfer.CreatedAt.Format(time.RFC3339)
+	_, err = tx.Exec(query, transfer.ID, transfer.SourcePvzId, transfer.DestinationPvzId, transfer.Status, createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer: %v", err)
+	}
+
+	sourceQuery := `
+    SELECT r.pvz_id, p.status
+    FROM products p
+    JOIN receptions r ON r.id = ` + productReception + `
+    WHERE p.id = ?`
+	for _, item := range transfer.Items {
+		var pvzId, status string
+		err := tx.QueryRow(sourceQuery, item.ProductId).Scan(&pvzId, &status)
+		if err == sql.ErrNoRows || (err == nil && (pvzId != transfer.SourcePvzId || status != models.ProductStatusStored)) {
+			return fmt.Errorf("%w: %s", ErrProductNotTransferrable, item.ProductId)
+		} else if err != nil {
+			return fmt.Errorf("failed to create transfer: %v", err)
+		}
+
+		if _, err := tx.Exec(`
+        INSERT INTO transfer_items (transfer_id, product_id, status)
+        VALUES (?, ?, ?)`, transfer.ID, item.ProductId, models.TransferItemPending); err != nil {
+			return fmt.Errorf("failed to create transfer: %v", err)
+		}
+		if _, err := tx.Exec(`UPDATE products SET status = ?, cell_id = NULL WHERE id = ?`,
+			models.ProductStatusInTransit, item.ProductId); err != nil {
+			return fmt.Errorf("failed to create transfer: %v", err)
+		}
+		comment := "dispatched to " + transfer.DestinationPvzId + " by transfer " + transfer.ID
+		if err := recordStatus(tx, item.ProductId, models.ProductStatusInTransit, createdAt, comment); err != nil {
+			return err
+		}
+	}
+
+	if err := RecordAudit(tx, "transfer", transfer.ID, "dispatched", actor, transfer.Items); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create transfer: %v", err)
+	}
+	return nil
+}
+
+// MarkTransferInTransit records that the courier has picked up a dispatched transfer.
+func MarkTransferInTransit(transferId, actor string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to update transfer: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkTransferStatus(tx, transferId, models.TransferStatusDispatched); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE transfers SET status = ? WHERE id = ?`, models.TransferStatusInTransit, transferId); err != nil {
+		return fmt.Errorf("failed to update transfer: %v", err)
+	}
+	if err := RecordAudit(tx, "transfer", transferId, "in_transit", actor, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update transfer: %v", err)
+	}
+	return nil
+}
+
+// ReceiveTransfer accepts the arrived products at the destination PVZ through a
+// new reception of kind "transfer". Products that were sent but did not arrive
+// are marked missing and lost; scanned products that were not part of the
+// transfer are reported as unexpected and left untouched.
+func ReceiveTransfer(transferId, receptionId string, arrived []string, actor string) (*models.TransferDiscrepancy, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive transfer: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkTransferStatus(tx, transferId, models.TransferStatusDispatched, models.TransferStatusInTransit); err != nil {
+		return nil, err
+	}
+
+	var destination string
+	if err := tx.QueryRow(`SELECT destination_pvz_id FROM transfers WHERE id = ?`, transferId).Scan(&destination); err != nil {
+		return nil, fmt.Errorf("failed to receive transfer: %v", err)
+	}
+
+	items, err := queryTransferItems(tx, transferId)
+	if err != nil {
+		return nil, err
+	}
+	// Products in transit count nowhere, so arriving ones must fit the destination
+	occupancy, err := getPVZOccupancy(tx, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	_, err = tx.Exec(`
+    INSERT INTO receptions (id, date_time, pvz_id, status, kind, opened_by, closed_by, closed_at)
+    VALUES (?, ?, ?, 'close', ?, ?, ?, ?)`, receptionId, now, destination, models.ReceptionKindTransfer, actor, actor, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive transfer: %v", err)
+	}
+
+	arrivedSet := make(map[string]bool, len(arrived))
+	for _, id := range arrived {
+		arrivedSet[id] = true
+	}
+
+	discrepancy := &models.TransferDiscrepancy{Missing: []string{}, Unexpected: []string{}}
+	sent := make(map[string]bool, len(items))
+	for _, item := range items {
+		sent[item.ProductId] = true
+
+		itemStatus, productStatus := models.TransferItemReceived, models.ProductStatusStored
+		comment := "received by transfer " + transferId
+		if !arrivedSet[item.ProductId] {
+			itemStatus, productStatus = models.TransferItemMissing, models.ProductStatusLost
+			comment = "missing from transfer " + transferId
+			discrepancy.Missing = append(discrepancy.Missing, item.ProductId)
+		}
+
+		if _, err := tx.Exec(`UPDATE transfer_items SET status = ? WHERE transfer_id = ? AND product_id = ?`,
+			itemStatus, transferId, item.ProductId); err != nil {
+			return nil, fmt.Errorf("failed to receive transfer: %v", err)
+		}
+		if itemStatus == models.TransferItemReceived {
+			if err := fitTransferredProduct(tx, occupancy, item.ProductId); err != nil {
+				return nil, err
+			}
+			_, err = tx.Exec(`UPDATE products SET status = ?, transfer_reception_id = ? WHERE id = ?`, productStatus, receptionId, item.ProductId)
+		} else {
+			_, err = tx.Exec(`UPDATE products SET status = ? WHERE id = ?`, productStatus, item.ProductId)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive transfer: %v", err)
+		}
+		if err := recordStatus(tx, item.ProductId, productStatus, now, comment); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range arrived {
+		if !sent[id] {
+			discrepancy.Unexpected = append(discrepancy.Unexpected, id)
+		}
+	}
+
+	_, err = tx.Exec(`
+    UPDATE transfers
+    SET status = ?, received_at = ?, reception_id = ?
+    WHERE id = ?`, models.TransferStatusReceived, now, receptionId, transferId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive transfer: %v", err)
+	}
+	if err := RecordAudit(tx, "transfer", transferId, "received", actor, discrepancy); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to receive transfer: %v", err)
+	}
+	return discrepancy, nil
+}
+
+// fitTransferredProduct counts an arriving product into the occupancy of the
+// destination PVZ.
+func fitTransferredProduct(tx *sql.Tx, occupancy *models.Occupancy, productId string) error {
+	query := `
+    SELECT ` + productColumns + `
+    FROM products p
+    WHERE p.id = ?`
+	product, err := scanProduct(tx.QueryRow(query, productId))
+	if err != nil {
+		return fmt.Errorf("failed to receive transfer: %v", err)
+	}
+	return fitCapacity(occupancy, *product)
+}
+
+// GetTransferByID retrieves a transfer together with its items.
+func GetTransferByID(id string) (*models.Transfer, error) {
+	query := `
+    SELECT id, source_pvz_id, destination_pvz_id, status, created_at, received_at, reception_id
+    FROM transfers
+    WHERE id = ?`
+
+	var transfer models.Transfer
+	var createdAt string
+	var receivedAt, receptionId sql.NullString
+	err := DB.QueryRow(query, id).Scan(&transfer.ID, &transfer.SourcePvzId, &transfer.DestinationPvzId,
+		&transfer.Status, &createdAt, &receivedAt, &receptionId)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransferNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %v", err)
+	}
+
+	transfer.CreatedAt, err = time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time: %v", err)
+	}
+	if receivedAt.Valid {
+		parsed, err := time.Parse(time.RFC3339, receivedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time: %v", err)
+		}
+		transfer.ReceivedAt = &parsed
+	}
+	transfer.ReceptionId = receptionId.String
+
+	transfer.Items, err = queryTransferItems(DB, id)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func queryTransferItems(q queryer, transferId string) ([]models.TransferItem, error) {
+	rows, err := q.Query(`
+    SELECT product_id, status
+    FROM transfer_items
+    WHERE transfer_id = ?
+    ORDER BY rowid`, transferId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer items: %v", err)
+	}
+	defer rows.Close()
+
+	items := []models.TransferItem{}
+	for rows.Next() {
+		var item models.TransferItem
+		if err := rows.Scan(&item.ProductId, &item.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer item: %v", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func checkTransferStatus(tx *sql.Tx, transferId string, allowed ...string) error {
+	var status string
+	err := tx.QueryRow(`SELECT status FROM transfers WHERE id = ?`, transferId).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrTransferNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get transfer: %v", err)
+	}
+	if !containsString(allowed, status) {
+		return fmt.Errorf("%w: transfer is %s", ErrTransferStatus, status)
+	}
+	return nil
+}
@@ -4,10 +4,17 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
 	"github.com/StepOne-ai/pvz_avito/internal/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrUserNotFound       = apperr.NotFound("user_not_found", "user not found")
+	ErrEmailTaken         = apperr.Conflict("email_taken", "a user with this email already exists")
+	ErrInvalidCredentials = apperr.Unauthorized("invalid_credentials", "Invalid credentials")
+)
+
 func CreateUser(id, email, password, role string) error {
 	hashedPassword, err := HashPassword(password)
 	if err != nil {
@@ -16,7 +23,9 @@ func CreateUser(id, email, password, role string) error {
 
 	query := `INSERT INTO users (id, email, password, role) VALUES (?, ?, ?, ?)`
 	_, err = DB.Exec(query, id, email, hashedPassword, role)
-	if err != nil {
+	if isUniqueViolation(err) {
+		return ErrEmailTaken
+	} else if err != nil {
 		return fmt.Errorf("failed to create user: %v", err)
 	}
 	return nil
@@ -29,14 +38,14 @@ func CheckCredentials(r_email, r_password string) error {
 	var id, email, role, password string
 	err := row.Scan(&id, &email, &role, &password)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("user not found")
+		return ErrInvalidCredentials
 	} else if err != nil {
 		return fmt.Errorf("failed to get user: %v", err)
 	}
 
 	err = CompareHashAndPassword(password, r_password)
 	if err != nil {
-		return fmt.Errorf("Error: %v", err)
+		return ErrInvalidCredentials
 	}
 
 	return nil
@@ -49,7 +58,7 @@ func GetUserByEmail(email string) (models.User, error) {
 	var id, userEmail, role, password string
 	err := row.Scan(&id, &userEmail, &role, &password)
 	if err == sql.ErrNoRows {
-		return models.User{}, fmt.Errorf("user not found")
+		return models.User{}, ErrUserNotFound
 	} else if err != nil {
 		return models.User{}, fmt.Errorf("failed to get user: %v", err)
 	}
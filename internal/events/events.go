@@ -0,0 +1,53 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event is something that happened in the system and may interest other services.
+type Event struct {
+	Type     string                 `json:"type"`
+	EntityId string                 `json:"entityId"`
+	PvzId    string                 `json:"pvzId,omitempty"`
+	At       time.Time              `json:"at"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// Publisher delivers events, e.g. to a message broker.
+type Publisher interface {
+	Publish(event Event) error
+}
+
+// Log is a local Publisher that logs events and keeps them in memory so they
+// can be inspected.
+type Log struct {
+	logger *logrus.Logger
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewLog creates a Log publisher. A nil logger disables logging.
+func NewLog(logger *logrus.Logger) *Log {
+	return &Log{logger: logger}
+}
+
+func (l *Log) Publish(event Event) error {
+	l.mu.Lock()
+	l.events = append(l.events, event)
+	l.mu.Unlock()
+
+	if l.logger != nil {
+		l.logger.WithFields(logrus.Fields{"event": event.Type, "entity": event.EntityId, "pvz": event.PvzId}).Info("Event published")
+	}
+	return nil
+}
+
+// Events returns the events published so far.
+func (l *Log) Events() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Event(nil), l.events...)
+}
@@ -0,0 +1,42 @@
+package geo
+
+import "math"
+
+const earthRadiusMeters = 6371000.0
+
+// metersPerDegreeLatitude is the length of one degree along a meridian.
+const metersPerDegreeLatitude = 111320.0
+
+// Distance returns the great-circle distance in meters between two points
+// given in decimal degrees.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// BoundingBox returns the latitude/longitude box enclosing a circle of the
+// given radius in meters. It is used to narrow a spatial index lookup before
+// exact distances are computed.
+func BoundingBox(lat, lon, radius float64) (minLat, maxLat, minLon, maxLon float64) {
+	dLat := radius / metersPerDegreeLatitude
+	minLat = math.Max(lat-dLat, -90)
+	maxLat = math.Min(lat+dLat, 90)
+
+	// Near the poles a degree of longitude shrinks to nothing; search all of them
+	cos := math.Cos(lat * math.Pi / 180)
+	if cos < 0.01 || maxLat >= 90 || minLat <= -90 {
+		return minLat, maxLat, -180, 180
+	}
+	dLon := radius / (metersPerDegreeLatitude * cos)
+	// Boxes crossing the antimeridian are widened instead of split
+	if lon-dLon < -180 || lon+dLon > 180 {
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, lon - dLon, lon + dLon
+}
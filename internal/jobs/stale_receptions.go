@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/events"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StaleReceptionsTotal counts receptions handled by the StaleReceptions job by action.
+var StaleReceptionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "stale_receptions_total",
+		Help: "Total number of receptions auto-closed or flagged for staying open too long",
+	},
+	[]string{"action"},
+)
+
+// Event types published by the StaleReceptions job.
+const (
+	EventReceptionAutoClosed = "reception.auto_closed"
+	EventReceptionStale      = "reception.stale"
+)
+
+// StaleReceptions closes or flags receptions that have been in progress longer
+// than the timeout of their PVZ.
+type StaleReceptions struct {
+	Events events.Publisher
+	// Timeout and Action apply to PVZs without an override; they default to
+	// models.DefaultReceptionTimeout and models.StaleActionClose.
+	Timeout time.Duration
+	Action  string
+	// Now returns the current time; it defaults to time.Now.
+	Now func() time.Time
+}
+
+func (j *StaleReceptions) Name() string {
+	return "stale_receptions"
+}
+
+func (j *StaleReceptions) Run(ctx context.Context) error {
+	now := time.Now()
+	if j.Now != nil {
+		now = j.Now()
+	}
+	timeout, action := j.Timeout, j.Action
+	if timeout == 0 {
+		timeout = models.DefaultReceptionTimeout
+	}
+	if action == "" {
+		action = models.StaleActionClose
+	}
+
+	stale, err := db.GetStaleReceptions(now, timeout, action)
+	if err != nil {
+		return err
+	}
+
+	for _, reception := range stale {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var eventType string
+		switch reception.Action {
+		case models.StaleActionClose:
+			if err := db.CloseReception(reception.ID, "system"); err != nil {
+				return err
+			}
+			eventType = EventReceptionAutoClosed
+		case models.StaleActionFlag:
+			if reception.FlaggedAt != nil {
+				continue
+			}
+			if err := db.FlagStaleReception(reception.ID, now); err != nil {
+				return err
+			}
+			eventType = EventReceptionStale
+		default:
+			continue
+		}
+
+		StaleReceptionsTotal.WithLabelValues(reception.Action).Inc()
+		if j.Events == nil {
+			continue
+		}
+		err := j.Events.Publish(events.Event{
+			Type:     eventType,
+			EntityId: reception.ID,
+			PvzId:    reception.PvzId,
+			At:       now,
+			Data:     map[string]interface{}{"openMinutes": reception.OpenMinutes, "timeoutMinutes": reception.TimeoutMinutes},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
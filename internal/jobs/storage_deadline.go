@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/notify"
+)
+
+// StorageDeadline finds stored products whose storage period has run out,
+// groups them per PVZ into return batches and notifies the PVZ.
+type StorageDeadline struct {
+	Notifier notify.Notifier
+	// Now returns the current time; it defaults to time.Now.
+	Now func() time.Time
+}
+
+var batchSequence uint64
+
+func (j *StorageDeadline) Name() string {
+	return "storage_deadline"
+}
+
+func (j *StorageDeadline) Run(ctx context.Context) error {
+	now := time.Now()
+	if j.Now != nil {
+		now = j.Now()
+	}
+
+	overdue, err := db.GetExpiringProducts("", now, now, false)
+	if err != nil {
+		return err
+	}
+
+	byPVZ := make(map[string][]string)
+	var order []string
+	for _, product := range overdue {
+		if _, ok := byPVZ[product.PvzId]; !ok {
+			order = append(order, product.PvzId)
+		}
+		byPVZ[product.PvzId] = append(byPVZ[product.PvzId], product.ID)
+	}
+
+	for _, pvzId := range order {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch := models.ReturnBatch{
+			ID:         fmt.Sprintf("return-%d-%d", now.UnixNano(), atomic.AddUint64(&batchSequence, 1)),
+			PvzId:      pvzId,
+			Status:     models.ReturnBatchPending,
+			CreatedAt:  now,
+			ProductIds: byPVZ[pvzId],
+		}
+		if err := db.CreateReturnBatch(batch); err != nil {
+			return err
+		}
+		if err := j.notify(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notify tells the PVZ about the new batch. PVZs without a contact phone are skipped.
+func (j *StorageDeadline) notify(batch models.ReturnBatch) error {
+	if j.Notifier == nil {
+		return nil
+	}
+	pvz, err := db.GetPVZByID(batch.PvzId)
+	if err != nil {
+		return err
+	}
+	if pvz.Phone == "" {
+		return nil
+	}
+
+	message := fmt.Sprintf("Срок хранения истёк у %d товаров. Сформирована партия возврата %s.", len(batch.ProductIds), batch.ID)
+	if err := j.Notifier.Notify(pvz.Phone, message); err != nil {
+		return fmt.Errorf("failed to notify PVZ %s: %v", pvz.ID, err)
+	}
+	return nil
+}
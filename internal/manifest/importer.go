@@ -0,0 +1,237 @@
+package manifest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/barcode"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// Supported manifest file formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Mapping names the CSV columns or JSON keys that hold each manifest field.
+// Empty names fall back to DefaultMapping.
+type Mapping struct {
+	Barcode  string `json:"barcode"`
+	Type     string `json:"type"`
+	Quantity string `json:"quantity"`
+	PvzId    string `json:"pvzId"`
+}
+
+var DefaultMapping = Mapping{Barcode: "barcode", Type: "type", Quantity: "quantity", PvzId: "pvzId"}
+
+func (m Mapping) withDefaults() Mapping {
+	if m.Barcode == "" {
+		m.Barcode = DefaultMapping.Barcode
+	}
+	if m.Type == "" {
+		m.Type = DefaultMapping.Type
+	}
+	if m.Quantity == "" {
+		m.Quantity = DefaultMapping.Quantity
+	}
+	if m.PvzId == "" {
+		m.PvzId = DefaultMapping.PvzId
+	}
+	return m
+}
+
+// Row is a parsed manifest line. Line is 1-based and counts the CSV header,
+// so it matches what a supplier sees in a spreadsheet; for JSON it is the
+// position of the item.
+type Row struct {
+	Line  int
+	PvzId string
+	Item  models.ManifestItem
+}
+
+// Parse reads a manifest in the given format. Rows that cannot be read are
+// reported as row errors; the returned error means the file as a whole is unusable.
+func Parse(format string, r io.Reader, mapping Mapping) ([]Row, []models.ImportRowError, error) {
+	mapping = mapping.withDefaults()
+	switch format {
+	case FormatCSV:
+		return parseCSV(r, mapping)
+	case FormatJSON:
+		return parseJSON(r, mapping)
+	default:
+		return nil, nil, fmt.Errorf("unsupported manifest format %q", format)
+	}
+}
+
+func parseCSV(r io.Reader, mapping Mapping) ([]Row, []models.ImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("manifest is empty")
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := columns[mapping.Type]; !ok {
+		return nil, nil, fmt.Errorf("manifest has no %q column", mapping.Type)
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []Row
+	var rowErrors []models.ImportRowError
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+
+		row := Row{Line: line, PvzId: field(record, mapping.PvzId)}
+		row.Item.Barcode = field(record, mapping.Barcode)
+		row.Item.Type = field(record, mapping.Type)
+		if raw := field(record, mapping.Quantity); raw != "" {
+			quantity, err := strconv.Atoi(raw)
+			if err != nil {
+				rowErrors = append(rowErrors, models.ImportRowError{Line: line, Field: mapping.Quantity, Message: "quantity must be a whole number"})
+				continue
+			}
+			row.Item.Quantity = quantity
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+// parseJSON accepts either an array of objects or an object with an "items" array.
+func parseJSON(r io.Reader, mapping Mapping) ([]Row, []models.ImportRowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		var wrapped struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, nil, fmt.Errorf("manifest is not valid JSON: %v", err)
+		}
+		objects = wrapped.Items
+	}
+
+	var rows []Row
+	var rowErrors []models.ImportRowError
+	for i, object := range objects {
+		line := i + 1
+		row := Row{Line: line}
+		var err error
+		if row.PvzId, err = jsonString(object, mapping.PvzId); err == nil {
+			if row.Item.Barcode, err = jsonString(object, mapping.Barcode); err == nil {
+				row.Item.Type, err = jsonString(object, mapping.Type)
+			}
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+
+		switch quantity := object[mapping.Quantity].(type) {
+		case nil:
+		case float64:
+			if quantity != float64(int(quantity)) {
+				rowErrors = append(rowErrors, models.ImportRowError{Line: line, Field: mapping.Quantity, Message: "quantity must be a whole number"})
+				continue
+			}
+			row.Item.Quantity = int(quantity)
+		default:
+			rowErrors = append(rowErrors, models.ImportRowError{Line: line, Field: mapping.Quantity, Message: "quantity must be a number"})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+// jsonString reads a string field. Numbers are accepted too, since suppliers
+// often send EAN barcodes as numbers.
+func jsonString(object map[string]interface{}, key string) (string, error) {
+	switch value := object[key].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("%s must be a string", key)
+	}
+}
+
+// Validate checks parsed rows against the accepted product types and barcode
+// formats, fills in the default quantity and PVZ, and reports every problem
+// with the line it was found on.
+func Validate(rows []Row, defaultPvzId string, validType func(string) bool) []models.ImportRowError {
+	var rowErrors []models.ImportRowError
+	seen := make(map[string]int)
+	for i := range rows {
+		row := &rows[i]
+		fail := func(field, format string, args ...interface{}) {
+			rowErrors = append(rowErrors, models.ImportRowError{Line: row.Line, Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if row.PvzId == "" {
+			row.PvzId = defaultPvzId
+		}
+		if row.PvzId == "" {
+			fail("pvzId", "PVZ is not specified")
+		}
+		if row.Item.Type == "" {
+			fail("type", "type is required")
+		} else if !validType(row.Item.Type) {
+			fail("type", "unknown product type %q", row.Item.Type)
+		}
+		if row.Item.Quantity == 0 {
+			row.Item.Quantity = 1
+		}
+		if row.Item.Quantity < 0 {
+			fail("quantity", "quantity must be positive")
+		}
+
+		if row.Item.Barcode == "" {
+			continue
+		}
+		if row.Item.Quantity != 1 {
+			fail("quantity", "an item with a barcode has quantity 1")
+		}
+		if _, err := barcode.Validate(row.Item.Barcode); err != nil {
+			fail("barcode", "%v", err)
+		}
+		if first, ok := seen[row.Item.Barcode]; ok {
+			fail("barcode", "barcode %s repeats line %d", row.Item.Barcode, first)
+		} else {
+			seen[row.Item.Barcode] = row.Line
+		}
+	}
+	return rowErrors
+}
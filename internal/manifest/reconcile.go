@@ -0,0 +1,64 @@
+package manifest
+
+import (
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// Reconcile matches the products of a reception against its manifest. Products
+// are first matched by barcode; the rest are counted against the manifest lines
+// without a barcode of the same type. Whatever is left on either side ends up as
+// missing or extra.
+func Reconcile(items []models.ManifestItem, products []models.Product) models.Reconciliation {
+	report := models.Reconciliation{
+		Received:   len(products),
+		Missing:    []models.ManifestItem{},
+		Extra:      []models.ReconciledProduct{},
+		Mismatched: []models.TypeMismatch{},
+	}
+
+	byBarcode := make(map[string]models.ManifestItem)
+	byType := make(map[string]int)
+	var types []string
+	for _, item := range items {
+		report.Expected += item.Quantity
+		if item.Barcode != "" {
+			byBarcode[item.Barcode] = item
+			continue
+		}
+		if _, ok := byType[item.Type]; !ok {
+			types = append(types, item.Type)
+		}
+		byType[item.Type] += item.Quantity
+	}
+
+	for _, product := range products {
+		reconciled := models.ReconciledProduct{ProductId: product.ID, Barcode: product.Barcode, Type: product.Type}
+		if item, ok := byBarcode[product.Barcode]; ok && product.Barcode != "" {
+			delete(byBarcode, product.Barcode)
+			if item.Type != product.Type {
+				report.Mismatched = append(report.Mismatched, models.TypeMismatch{ReconciledProduct: reconciled, ExpectedType: item.Type})
+				continue
+			}
+			report.Matched++
+			continue
+		}
+		if byType[product.Type] > 0 {
+			byType[product.Type]--
+			report.Matched++
+			continue
+		}
+		report.Extra = append(report.Extra, reconciled)
+	}
+
+	for _, item := range items {
+		if _, ok := byBarcode[item.Barcode]; ok && item.Barcode != "" {
+			report.Missing = append(report.Missing, item)
+		}
+	}
+	for _, productType := range types {
+		if left := byType[productType]; left > 0 {
+			report.Missing = append(report.Missing, models.ManifestItem{Type: productType, Quantity: left})
+		}
+	}
+	return report
+}
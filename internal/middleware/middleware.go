@@ -1,17 +1,13 @@
 package middleware
 
 import (
-	"fmt"
-	"net/http"
-	"time"
-
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
 	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/token"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("some_super_secret_key")
-
 type jwtCustomClaims struct {
 	Role string `json:"role"`
 	jwt.RegisteredClaims
@@ -23,21 +19,26 @@ func JWTMiddleware() gin.HandlerFunc {
 		if err != nil || tokenString == "" {
 			authHeader := c.GetHeader("Authorization")
 			if authHeader == "" || len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-				c.JSON(http.StatusBadRequest, models.Error{Message: "Token missing in both cookies and Authorization header"})
-				c.Abort()
+				Problem(c, apperr.Unauthorized("token_missing", "Token missing in both cookies and Authorization header"))
 				return
 			}
 			tokenString = authHeader[7:]
 		}
 
-		token, err := verifyToken(tokenString)
+		parsed, err := token.Verify(tokenString)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.Error{Message: "Error verifying token"})
-			c.Abort()
+			Problem(c, apperr.Unauthorized("token_invalid", "Error verifying token"))
 			return
 		}
 
-		fmt.Printf("Token verified successfully. Claims: %+v\n", token.Claims)
+		if claims, ok := parsed.Claims.(jwt.MapClaims); ok {
+			if email, ok := claims["email"].(string); ok {
+				c.Set("email", email)
+			}
+			if role, ok := claims["role"].(string); ok {
+				c.Set("role", role)
+			}
+		}
 
 		c.Next()
 	}
@@ -47,8 +48,7 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, err := c.Cookie("role")
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.Error{Message: "No cookies named role"})
-			c.Abort()
+			Problem(c, apperr.Unauthorized("role_missing", "No cookies named role"))
 			return
 		}
 		isValid := false
@@ -61,8 +61,7 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 		}
 
 		if !isValid {
-			c.JSON(http.StatusBadRequest, models.Error{Message: "Role is not enough"})
-			c.Abort()
+			Problem(c, apperr.Forbidden("role_forbidden", "Role is not enough"))
 			return
 		}
 
@@ -70,38 +69,22 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
-func JwtSecret() []byte {
-	return jwtSecret
+// CurrentUser returns the email of the authenticated user, or an empty string.
+func CurrentUser(c *gin.Context) string {
+	return c.GetString("email")
 }
 
-func GenerateToken(user models.User) (string, error) {
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": user.Email,
-		"role":  user.Role,
-		"exp":   time.Now().Add(time.Hour * 24).Unix(),
-		"iat":   time.Now().Unix(),
-	})
-
-	tokenString, err := claims.SignedString(jwtSecret)
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+// CurrentRole returns the role from the verified token, or an empty string.
+// Unlike the role cookie, the client cannot change it.
+func CurrentRole(c *gin.Context) string {
+	return c.GetString("role")
 }
 
-func verifyToken(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		return jwtSecret, nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
-	}
+func JwtSecret() []byte {
+	return token.Secret()
+}
 
-	return token, nil
+// GenerateToken is token.Generate, kept for callers of this package.
+func GenerateToken(user models.User) (string, error) {
+	return token.Generate(user)
 }
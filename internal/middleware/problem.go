@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Request IDs from clients are kept when they are short and printable.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID tags each request with the X-Request-ID the client sent, or a new
+// one, and echoes it in the response so a failure can be found in the logs.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set("requestId", id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// RequestIDOf returns the ID RequestID gave the request, or an empty string.
+func RequestIDOf(c *gin.Context) string {
+	return c.GetString("requestId")
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+var kindStatus = map[apperr.Kind]int{
+	apperr.KindValidation:   http.StatusBadRequest,
+	apperr.KindUnauthorized: http.StatusUnauthorized,
+	apperr.KindForbidden:    http.StatusForbidden,
+	apperr.KindNotFound:     http.StatusNotFound,
+	apperr.KindConflict:     http.StatusConflict,
+	apperr.KindTooLarge:     http.StatusRequestEntityTooLarge,
+	apperr.KindUnsupported:  http.StatusUnsupportedMediaType,
+	apperr.KindUnavailable:  http.StatusServiceUnavailable,
+	apperr.KindInternal:     http.StatusInternalServerError,
+}
+
+// StatusOf maps the kind of err to an HTTP status.
+func StatusOf(err error) int {
+	if status, ok := kindStatus[apperr.KindOf(err)]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Problem aborts the request with err as an application/problem+json body.
+// Internal errors are attached to the context for the logger and answered
+// with a generic message, so database text never reaches the client.
+func Problem(c *gin.Context, err error) {
+	problem := ProblemOf(c, err)
+	if apperr.KindOf(err) == apperr.KindInternal {
+		c.Error(err)
+	}
+	// gin keeps a content type that is already set
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// ProblemOf describes err as a problem document of the request, for responses
+// that report errors inside a body of their own, such as a stream.
+func ProblemOf(c *gin.Context, err error) models.Error {
+	e := apperr.From(err)
+	status := StatusOf(e)
+	return models.Error{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    e.Message,
+		Code:      e.Code,
+		Message:   e.Message,
+		Fields:    e.Fields,
+		RequestID: RequestIDOf(c),
+	}
+}
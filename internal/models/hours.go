@@ -0,0 +1,145 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PVZLocation is the time zone working hours are expressed in. All supported
+// cities share Moscow time.
+var PVZLocation = time.FixedZone("MSK", 3*60*60)
+
+// Weekdays maps the weekday names accepted by the API to time.Weekday.
+var Weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// WorkingHours is the regular opening interval of a PVZ on one weekday.
+// Open and Close are "HH:MM" in PVZLocation; Close may be "24:00".
+type WorkingHours struct {
+	Weekday string `json:"weekday"`
+	Open    string `json:"open"`
+	Close   string `json:"close"`
+}
+
+// HolidayOverride replaces the regular working hours on a specific date.
+// A closed override keeps the PVZ shut for the whole day.
+type HolidayOverride struct {
+	Date   string `json:"date"`
+	Closed bool   `json:"closed"`
+	Open   string `json:"open,omitempty"`
+	Close  string `json:"close,omitempty"`
+}
+
+// ParseClock converts "HH:MM" into minutes since midnight.
+func ParseClock(value string) (int, error) {
+	var hours, minutes int
+	if len(value) != 5 || value[2] != ':' {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	if _, err := fmt.Sscanf(value, "%02d:%02d", &hours, &minutes); err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	if minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return hours*60 + minutes, nil
+}
+
+// ValidateSchedule checks working hours and holiday overrides for consistency.
+func ValidateSchedule(hours []WorkingHours, holidays []HolidayOverride) error {
+	seen := make(map[string]bool)
+	for _, h := range hours {
+		day := strings.ToLower(h.Weekday)
+		if _, ok := Weekdays[day]; !ok {
+			return fmt.Errorf("invalid weekday %q", h.Weekday)
+		}
+		if seen[day] {
+			return fmt.Errorf("duplicate working hours for %s", day)
+		}
+		seen[day] = true
+		if err := validateInterval(h.Open, h.Close); err != nil {
+			return err
+		}
+	}
+
+	seenDates := make(map[string]bool)
+	for _, h := range holidays {
+		if _, err := time.Parse("2006-01-02", h.Date); err != nil {
+			return fmt.Errorf("invalid holiday date %q, expected YYYY-MM-DD", h.Date)
+		}
+		if seenDates[h.Date] {
+			return fmt.Errorf("duplicate holiday override for %s", h.Date)
+		}
+		seenDates[h.Date] = true
+		if h.Closed {
+			continue
+		}
+		if err := validateInterval(h.Open, h.Close); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateInterval(open, close string) error {
+	from, err := ParseClock(open)
+	if err != nil {
+		return err
+	}
+	to, err := ParseClock(close)
+	if err != nil {
+		return err
+	}
+	if from >= to {
+		return fmt.Errorf("opening time %s must be before closing time %s", open, close)
+	}
+	return nil
+}
+
+// IsOpenAt reports whether the PVZ is open at the given moment. A PVZ without
+// a weekly schedule is considered open around the clock, except on holidays.
+func (p *PVZ) IsOpenAt(t time.Time) bool {
+	local := t.In(PVZLocation)
+	minute := local.Hour()*60 + local.Minute()
+	date := local.Format("2006-01-02")
+
+	for _, h := range p.Holidays {
+		if h.Date != date {
+			continue
+		}
+		if h.Closed {
+			return false
+		}
+		return inInterval(minute, h.Open, h.Close)
+	}
+
+	if len(p.WorkingHours) == 0 {
+		return true
+	}
+	for _, h := range p.WorkingHours {
+		if Weekdays[strings.ToLower(h.Weekday)] == local.Weekday() {
+			return inInterval(minute, h.Open, h.Close)
+		}
+	}
+	return false
+}
+
+func inInterval(minute int, open, close string) bool {
+	from, err := ParseClock(open)
+	if err != nil {
+		return false
+	}
+	to, err := ParseClock(close)
+	if err != nil {
+		return false
+	}
+	return minute >= from && minute < to
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
 	"time"
 )
 
@@ -14,25 +17,546 @@ type User struct {
 }
 
 type PVZ struct {
-	ID               string    `json:"id"`
-	RegistrationDate time.Time `json:"registrationDate"`
-	City             string    `json:"city"`
+	ID               string            `json:"id"`
+	RegistrationDate time.Time         `json:"registrationDate"`
+	City             string            `json:"city"`
+	Address          string            `json:"address,omitempty"`
+	Latitude         float64           `json:"latitude,omitempty"`
+	Longitude        float64           `json:"longitude,omitempty"`
+	Phone            string            `json:"phone,omitempty"`
+	WorkingHours     []WorkingHours    `json:"workingHours,omitempty"`
+	Holidays         []HolidayOverride `json:"holidays,omitempty"`
+	// FlaggedReceptions are closed receptions that still have open incidents.
+	FlaggedReceptions []FlaggedReception `json:"flaggedReceptions,omitempty"`
 }
 
+// NearbyPVZ is a PVZ found by a geo search together with its distance in
+// meters from the requested point.
+type NearbyPVZ struct {
+	PVZ
+	Distance float64 `json:"distance"`
+}
+
+// Capacity limits how many products a PVZ may hold. Zero means unlimited.
+// Policy decides what happens when a new product would exceed a limit:
+// "reject" refuses it, "warn" accepts it and reports the overflow.
+type Capacity struct {
+	Total  int            `json:"total"`
+	ByType map[string]int `json:"byType"`
+	Policy string         `json:"policy"`
+	// VolumeCm3 limits the total volume of stored products; zero means no limit.
+	VolumeCm3 int64 `json:"volumeCm3,omitempty"`
+}
+
+// Capacity policies: reject products beyond the capacity of a PVZ, or accept
+// them with a warning.
+const (
+	CapacityPolicyReject = "reject"
+	CapacityPolicyWarn   = "warn"
+)
+
+// Occupancy is the number of products currently stored at a PVZ. Weight and
+// volume only include products whose measurements were captured.
+type Occupancy struct {
+	PvzId              string         `json:"pvzId"`
+	Total              int            `json:"total"`
+	ByType             map[string]int `json:"byType"`
+	WeightGrams        int64          `json:"weightGrams"`
+	VolumeCm3          int64          `json:"volumeCm3"`
+	Capacity           Capacity       `json:"capacity"`
+	Available          *int           `json:"available,omitempty"`
+	VolumeAvailableCm3 *int64         `json:"volumeAvailableCm3,omitempty"`
+}
+
+// Overflow describes how one more product of the type and volume would exceed
+// the capacity, or returns an empty string if it fits.
+func (o *Occupancy) Overflow(productType string, volumeCm3 int64) string {
+	if limit := o.Capacity.Total; limit > 0 && o.Total+1 > limit {
+		return fmt.Sprintf("PVZ capacity exceeded: %d of %d items", o.Total+1, limit)
+	}
+	if limit := o.Capacity.ByType[productType]; limit > 0 && o.ByType[productType]+1 > limit {
+		return fmt.Sprintf("PVZ capacity for %s exceeded: %d of %d items", productType, o.ByType[productType]+1, limit)
+	}
+	if limit := o.Capacity.VolumeCm3; limit > 0 && o.VolumeCm3+volumeCm3 > limit {
+		return fmt.Sprintf("PVZ volume capacity exceeded: %d of %d cm3", o.VolumeCm3+volumeCm3, limit)
+	}
+	return ""
+}
+
+// Add counts one more stored product.
+func (o *Occupancy) Add(product Product) {
+	o.Total++
+	o.ByType[product.Type]++
+	o.WeightGrams += int64(product.WeightGrams)
+	if product.Dimensions != nil {
+		o.VolumeCm3 += product.Dimensions.VolumeCm3()
+	}
+}
+
+// Reception kinds: a regular delivery from a supplier or the acceptance of a
+// transfer from another PVZ.
+const (
+	ReceptionKindDelivery = "delivery"
+	ReceptionKindTransfer = "transfer"
+)
+
+// Reception statuses. A pending reception was announced by a manifest and
+// waits for the delivery to arrive.
+const (
+	ReceptionStatusPending    = "pending"
+	ReceptionStatusInProgress = "in_progress"
+	ReceptionStatusClosed     = "close"
+)
+
 type Reception struct {
-	ID       string    `json:"id"`
-	DateTime time.Time `json:"dateTime"`
-	PvzId    string    `json:"pvzId"`
-	Status   string    `json:"status"`
+	ID       string     `json:"id"`
+	DateTime time.Time  `json:"dateTime"`
+	PvzId    string     `json:"pvzId"`
+	Status   string     `json:"status"`
+	Kind     string     `json:"kind,omitempty"`
+	OpenedBy string     `json:"openedBy,omitempty"`
+	ClosedBy string     `json:"closedBy,omitempty"`
+	ClosedAt *time.Time `json:"closedAt,omitempty"`
+}
+
+// ReceptionDetails is a reception together with its PVZ and a page of its products.
+type ReceptionDetails struct {
+	Reception
+	Pvz *PVZ `json:"pvz"`
+	// DurationSeconds runs until the reception was closed, or until now while it is open.
+	DurationSeconds  int64          `json:"durationSeconds"`
+	ProductCount     int            `json:"productCount"`
+	CountsByType     map[string]int `json:"countsByType"`
+	TotalWeightGrams int64          `json:"totalWeightGrams"`
+	TotalVolumeCm3   int64          `json:"totalVolumeCm3"`
+	OpenIncidents    int            `json:"openIncidents"`
+	Incidents        []Incident     `json:"incidents"`
+	Products         []Product      `json:"products"`
+	Page             int            `json:"page"`
+	Limit            int            `json:"limit"`
+}
+
+// What happens to a reception left open longer than its timeout.
+const (
+	StaleActionClose = "close"
+	StaleActionFlag  = "flag"
+)
+
+// DefaultReceptionTimeout applies to PVZs without their own reception timeout.
+const DefaultReceptionTimeout = 12 * time.Hour
+
+// ReceptionTimeout is a per-PVZ override of how long a reception may stay open.
+// Zero minutes or an empty action fall back to the defaults.
+type ReceptionTimeout struct {
+	Minutes int    `json:"minutes"`
+	Action  string `json:"action"`
+}
+
+// StaleReception is an open reception that has outlived its timeout.
+type StaleReception struct {
+	Reception
+	OpenMinutes    int        `json:"openMinutes"`
+	TimeoutMinutes int        `json:"timeoutMinutes"`
+	Action         string     `json:"action"`
+	FlaggedAt      *time.Time `json:"flaggedAt,omitempty"`
+}
+
+// Product lifecycle statuses. A product is received during an open reception,
+// stored once the reception is closed, and leaves the PVZ as issued,
+// returned_to_sender or lost. A product moving to another PVZ is in_transit.
+const (
+	ProductStatusReceived         = "received"
+	ProductStatusStored           = "stored"
+	ProductStatusIssued           = "issued"
+	ProductStatusReturnedToSender = "returned_to_sender"
+	ProductStatusLost             = "lost"
+	ProductStatusInTransit        = "in_transit"
+)
+
+// Size classes of the product type catalogue, from parcels that fit a shelf
+// bin to goods that need floor space.
+const (
+	SizeClassSmall     = "small"
+	SizeClassMedium    = "medium"
+	SizeClassLarge     = "large"
+	SizeClassOversized = "oversized"
+)
+
+// ProductType is an entry of the product type catalogue. Code is the value
+// products carry in their type field.
+type ProductType struct {
+	Code      string            `json:"code"`
+	Names     map[string]string `json:"names"`
+	SizeClass string            `json:"sizeClass"`
+	Fragile   bool              `json:"fragile"`
+	Hazardous bool              `json:"hazardous"`
+	Active    bool              `json:"active"`
+	// StoragePeriodDays is the storage period of the type; zero means the default.
+	StoragePeriodDays int `json:"storagePeriodDays,omitempty"`
+	// MaxWeightGrams and MaxDimensions limit a single product; zero means no limit.
+	MaxWeightGrams int         `json:"maxWeightGrams,omitempty"`
+	MaxDimensions  *Dimensions `json:"maxDimensions,omitempty"`
+}
+
+// Dimensions of a product in millimetres.
+type Dimensions struct {
+	LengthMm int `json:"lengthMm"`
+	WidthMm  int `json:"widthMm"`
+	HeightMm int `json:"heightMm"`
+}
+
+// VolumeCm3 returns the volume of the bounding box in cubic centimetres.
+func (d Dimensions) VolumeCm3() int64 {
+	return int64(d.LengthMm) * int64(d.WidthMm) * int64(d.HeightMm) / 1000
+}
+
+// FitsWithin reports whether a box of these dimensions fits into the limit in
+// some orientation: its sides, sorted, must not exceed the sorted limit sides.
+// A zero limit side is not checked.
+func (d Dimensions) FitsWithin(limit Dimensions) bool {
+	sides, limits := d.sorted(), limit.sorted()
+	for i := range sides {
+		if limits[i] > 0 && sides[i] > limits[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (d Dimensions) sorted() [3]int {
+	sides := [3]int{d.LengthMm, d.WidthMm, d.HeightMm}
+	sort.Sort(sort.Reverse(sort.IntSlice(sides[:])))
+	return sides
 }
 
 type Product struct {
-	ID          string    `json:"id"`
-	DateTime    time.Time `json:"dateTime"`
-	Type        string    `json:"type"`
-	ReceptionId string    `json:"receptionId"`
+	ID            string    `json:"id"`
+	DateTime      time.Time `json:"dateTime"`
+	Type          string    `json:"type"`
+	ReceptionId   string    `json:"receptionId"`
+	CellId        string    `json:"cellId,omitempty"`
+	Barcode       string    `json:"barcode,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	OrderId       string    `json:"orderId,omitempty"`
+	ReturnBatchId string    `json:"returnBatchId,omitempty"`
+	// WeightGrams and Dimensions are optional and zero or nil when not measured.
+	WeightGrams int         `json:"weightGrams,omitempty"`
+	Dimensions  *Dimensions `json:"dimensions,omitempty"`
+}
+
+// Outcomes of a single item in a bulk intake request.
+const (
+	BatchItemCreated  = "created"
+	BatchItemRejected = "rejected"
+	BatchItemSkipped  = "skipped"
+)
+
+// BatchItemResult reports what happened to one item of a bulk intake request.
+type BatchItemResult struct {
+	Index     int    `json:"index"`
+	Status    string `json:"status"`
+	ProductId string `json:"productId,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// Code is the stable error code of a rejected or skipped item.
+	Code    string `json:"code,omitempty"`
+	Warning string `json:"warning,omitempty"`
 }
 
+// BatchSummary totals a bulk intake request.
+type BatchSummary struct {
+	Created  int               `json:"created"`
+	Rejected int               `json:"rejected"`
+	Items    []BatchItemResult `json:"items,omitempty"`
+}
+
+// ManifestItem is one line of the expected delivery. Items with a barcode stand
+// for a single product; items without one expect Quantity products of the type.
+type ManifestItem struct {
+	Barcode  string `json:"barcode,omitempty"`
+	Type     string `json:"type"`
+	Quantity int    `json:"quantity"`
+}
+
+// Manifest lists what a reception is supposed to receive.
+type Manifest struct {
+	ReceptionId string         `json:"receptionId"`
+	Source      string         `json:"source"`
+	AttachedAt  time.Time      `json:"attachedAt"`
+	AttachedBy  string         `json:"attachedBy"`
+	Items       []ManifestItem `json:"items"`
+}
+
+// ImportRowError points at a manifest row that cannot be imported.
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportedReception is a pending reception created from a manifest file.
+type ImportedReception struct {
+	ReceptionId string `json:"receptionId,omitempty"`
+	PvzId       string `json:"pvzId"`
+	Items       int    `json:"items"`
+	Expected    int    `json:"expected"`
+}
+
+// ManifestImport is the outcome of a manifest import or its dry run.
+type ManifestImport struct {
+	DryRun     bool                `json:"dryRun"`
+	Rows       int                 `json:"rows"`
+	Errors     []ImportRowError    `json:"errors"`
+	Receptions []ImportedReception `json:"receptions"`
+}
+
+// ReconciledProduct identifies a received product in a reconciliation report.
+type ReconciledProduct struct {
+	ProductId string `json:"productId"`
+	Barcode   string `json:"barcode,omitempty"`
+	Type      string `json:"type"`
+}
+
+// TypeMismatch is a product whose barcode was expected with another type.
+type TypeMismatch struct {
+	ReconciledProduct
+	ExpectedType string `json:"expectedType"`
+}
+
+// Reconciliation compares what a reception received with its manifest.
+type Reconciliation struct {
+	ReceptionId string              `json:"receptionId"`
+	CreatedAt   time.Time           `json:"createdAt"`
+	Expected    int                 `json:"expected"`
+	Received    int                 `json:"received"`
+	Matched     int                 `json:"matched"`
+	Missing     []ManifestItem      `json:"missing"`
+	Extra       []ReconciledProduct `json:"extra"`
+	Mismatched  []TypeMismatch      `json:"mismatched"`
+}
+
+// DefaultStoragePeriodDays applies to product types without a configured period.
+const DefaultStoragePeriodDays = 7
+
+// StoragePeriod is how long products of a type are kept before they go back to the sender.
+type StoragePeriod struct {
+	Type string `json:"type"`
+	Days int    `json:"days"`
+}
+
+// ExpiringProduct is a stored product together with its storage deadline.
+type ExpiringProduct struct {
+	Product
+	PvzId    string    `json:"pvzId"`
+	Deadline time.Time `json:"deadline"`
+	Overdue  bool      `json:"overdue"`
+}
+
+const (
+	ReturnBatchPending    = "pending"
+	ReturnBatchDispatched = "dispatched"
+)
+
+// ReturnBatch collects overdue products of a PVZ to be sent back to the sender.
+type ReturnBatch struct {
+	ID           string     `json:"id"`
+	PvzId        string     `json:"pvzId"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	DispatchedAt *time.Time `json:"dispatchedAt,omitempty"`
+	ProductIds   []string   `json:"productIds"`
+}
+
+const (
+	OrderStatusAwaiting = "awaiting"
+	OrderStatusIssued   = "issued"
+)
+
+// Order groups the products of one customer delivered to a PVZ. The customer
+// collects it with a one-time pickup code sent to the recipient phone.
+type Order struct {
+	ID             string     `json:"id"`
+	OrderNumber    string     `json:"orderNumber"`
+	PvzId          string     `json:"pvzId"`
+	RecipientPhone string     `json:"recipientPhone"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	IssuedAt       *time.Time `json:"issuedAt,omitempty"`
+	Products       []Product  `json:"products"`
+}
+
+// ProductStatusChange is one entry of a product's status history.
+type ProductStatusChange struct {
+	Status    string    `json:"status"`
+	ChangedAt time.Time `json:"changedAt"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+// ProductLookup is the result of finding a product by its barcode.
+type ProductLookup struct {
+	Product Product      `json:"product"`
+	PvzId   string       `json:"pvzId"`
+	Cell    *StorageCell `json:"cell"`
+}
+
+// StorageCell is a shelf position inside a PVZ addressed as zone/rack/shelf.
+// Capacity limits the number of products in the cell; zero means unlimited.
+type StorageCell struct {
+	ID       string `json:"id"`
+	PvzId    string `json:"pvzId"`
+	Zone     string `json:"zone"`
+	Rack     string `json:"rack"`
+	Shelf    string `json:"shelf"`
+	Capacity int    `json:"capacity"`
+	Occupied int    `json:"occupied"`
+}
+
+// ProductLocation tells staff where a product is stored.
+type ProductLocation struct {
+	ProductId string       `json:"productId"`
+	PvzId     string       `json:"pvzId"`
+	Cell      *StorageCell `json:"cell"`
+}
+
+const (
+	TransferStatusDispatched = "dispatched"
+	TransferStatusInTransit  = "in_transit"
+	TransferStatusReceived   = "received"
+)
+
+// Transfer item statuses: pending until the destination reports it as
+// received or missing.
+const (
+	TransferItemPending  = "pending"
+	TransferItemReceived = "received"
+	TransferItemMissing  = "missing"
+)
+
+// Transfer moves stored products from one PVZ to another. The destination
+// accepts them through a reception of kind "transfer".
+type Transfer struct {
+	ID               string         `json:"id"`
+	SourcePvzId      string         `json:"sourcePvzId"`
+	DestinationPvzId string         `json:"destinationPvzId"`
+	Status           string         `json:"status"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	ReceivedAt       *time.Time     `json:"receivedAt,omitempty"`
+	ReceptionId      string         `json:"receptionId,omitempty"`
+	Items            []TransferItem `json:"items"`
+}
+
+type TransferItem struct {
+	ProductId string `json:"productId"`
+	Status    string `json:"status"`
+}
+
+// TransferDiscrepancy lists what went wrong when a transfer was received:
+// products that did not arrive and products scanned that were not sent.
+type TransferDiscrepancy struct {
+	Missing    []string `json:"missing"`
+	Unexpected []string `json:"unexpected"`
+}
+
+// AuditEntry records who changed what and when.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	EntityType string          `json:"entityType"`
+	EntityId   string          `json:"entityId"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor"`
+	Details    json.RawMessage `json:"details"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// Attachment kinds: a general photo, or evidence of damage on arrival.
+const (
+	AttachmentPhoto  = "photo"
+	AttachmentDamage = "damage"
+)
+
+// Attachment is an image uploaded for a product or a reception. The file and
+// its thumbnail live in the blob store under BlobKey and ThumbnailKey.
+type Attachment struct {
+	ID           string    `json:"id"`
+	EntityType   string    `json:"entityType"`
+	EntityId     string    `json:"entityId"`
+	Kind         string    `json:"kind"`
+	Comment      string    `json:"comment,omitempty"`
+	FileName     string    `json:"fileName"`
+	ContentType  string    `json:"contentType"`
+	SizeBytes    int64     `json:"sizeBytes"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	BlobKey      string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	UploadedBy   string    `json:"uploadedBy"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Incident types staff can report.
+const (
+	IncidentDamaged     = "damaged"
+	IncidentWrongPvz    = "wrong_pvz"
+	IncidentMissingSeal = "missing_seal"
+	IncidentOther       = "other"
+)
+
+const (
+	IncidentStatusOpen     = "open"
+	IncidentStatusResolved = "resolved"
+)
+
+// Incident is a problem found with a delivery, logged against a reception or
+// one of its products and kept open until a moderator resolves it.
+type Incident struct {
+	ID          string     `json:"id"`
+	ReceptionId string     `json:"receptionId"`
+	ProductId   string     `json:"productId,omitempty"`
+	PvzId       string     `json:"pvzId"`
+	Type        string     `json:"type"`
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status"`
+	ReportedBy  string     `json:"reportedBy"`
+	ReportedAt  time.Time  `json:"reportedAt"`
+	ResolvedBy  string     `json:"resolvedBy,omitempty"`
+	ResolvedAt  *time.Time `json:"resolvedAt,omitempty"`
+	Resolution  string     `json:"resolution,omitempty"`
+}
+
+// FlaggedReception is a closed reception with incidents nobody has resolved yet.
+type FlaggedReception struct {
+	ReceptionId   string     `json:"receptionId"`
+	ClosedAt      *time.Time `json:"closedAt,omitempty"`
+	OpenIncidents int        `json:"openIncidents"`
+}
+
+// SearchResult is one hit of a full-text search. Title and Snippet are HTML
+// escaped, with the matched words wrapped in <mark> tags.
+type SearchResult struct {
+	Kind    string  `json:"kind"`
+	ID      string  `json:"id"`
+	PvzId   string  `json:"pvzId"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet,omitempty"`
+	Score   float64 `json:"score"`
+}
+
+// Error is an RFC 7807 problem document. Code is stable across releases and
+// Message repeats Detail for clients written before the other members.
 type Error struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Detail  string `json:"detail"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields points at the parts of the request that failed validation.
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+// FieldError is a validation failure of one request field. Field is a path
+// such as items[2].type, or the parameter name for query parameters.
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier delivers short text messages to customers, e.g. by SMS.
+type Notifier interface {
+	Notify(recipient, message string) error
+}
+
+// Message is a notification accepted by the Stub notifier.
+type Message struct {
+	Recipient string
+	Text      string
+}
+
+// Stub is a local Notifier that logs messages instead of sending them and
+// keeps them in memory so they can be inspected.
+type Stub struct {
+	logger   *logrus.Logger
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewStub creates a Stub notifier. A nil logger disables logging.
+func NewStub(logger *logrus.Logger) *Stub {
+	return &Stub{logger: logger}
+}
+
+func (s *Stub) Notify(recipient, message string) error {
+	s.mu.Lock()
+	s.messages = append(s.messages, Message{Recipient: recipient, Text: message})
+	s.mu.Unlock()
+
+	if s.logger != nil {
+		s.logger.WithField("recipient", recipient).Info(message)
+	}
+	return nil
+}
+
+// Messages returns the notifications sent so far.
+func (s *Stub) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
@@ -0,0 +1,217 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxValidatedBody limits the JSON bodies the validator reads into memory.
+const maxValidatedBody = 10 << 20
+
+// RequestValidator rejects requests whose query parameters or JSON body do not
+// match the operation documented for the matched route, with 400 and the
+// offending fields. The body is put back for the handler to bind.
+func (d *Document) RequestValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := d.Operation(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		errs := d.validateQuery(op, c)
+		if op.RequestBody != nil {
+			bodyErrs, err := d.validateBody(op, c)
+			if err != nil {
+				middleware.Problem(c, err)
+				return
+			}
+			errs = append(errs, bodyErrs...)
+		}
+		if len(errs) > 0 {
+			middleware.Problem(c, apperr.Invalid("invalid_request", "Invalid request", errs...))
+			return
+		}
+		c.Next()
+	}
+}
+
+func (d *Document) validateQuery(op *Operation, c *gin.Context) []models.FieldError {
+	var errs []models.FieldError
+	query := c.Request.URL.Query()
+	for _, param := range op.Parameters {
+		if param.In != "query" {
+			continue
+		}
+		values, ok := query[param.Name]
+		if !ok {
+			if param.Required {
+				errs = append(errs, models.FieldError{Field: param.Name, Message: "is required"})
+			}
+			continue
+		}
+		schema := param.Schema
+		if schema != nil && schema.Type == "array" {
+			items := make([]interface{}, len(values))
+			for i, value := range values {
+				items[i] = queryValue(schema.Items, value)
+			}
+			errs = append(errs, d.Validate(schema, items, param.Name)...)
+			continue
+		}
+		for _, value := range values {
+			errs = append(errs, d.Validate(schema, queryValue(schema, value), param.Name)...)
+		}
+	}
+	return errs
+}
+
+// queryValue converts a query string value into what the JSON decoder would
+// produce for the schema type, leaving values that do not convert as strings
+// so they fail validation.
+func queryValue(s *Schema, value string) interface{} {
+	if s == nil {
+		return value
+	}
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil && (value == "true" || value == "false") {
+			return b
+		}
+	}
+	return value
+}
+
+// validateBody checks a JSON body; bodies of other content types are left to
+// the handler. The only failure of its own kind is an oversized body.
+func (d *Document) validateBody(op *Operation, c *gin.Context) ([]models.FieldError, error) {
+	contentType := c.ContentType()
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	media := op.RequestBody.Content[contentType]
+	if contentType != "application/json" || media.Schema == nil {
+		return nil, nil
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxValidatedBody+1))
+	if err != nil {
+		return []models.FieldError{{Message: "failed to read request body"}}, nil
+	}
+	if len(raw) > maxValidatedBody {
+		return nil, apperr.New(apperr.KindTooLarge, "body_too_large", "request body is too large")
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if op.RequestBody.Required {
+			return []models.FieldError{{Message: "request body is required"}}, nil
+		}
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []models.FieldError{{Message: "invalid JSON: " + err.Error()}}, nil
+	}
+	if decoder.More() {
+		return []models.FieldError{{Message: "invalid JSON: unexpected data after the body"}}, nil
+	}
+	return d.Validate(media.Schema, value, ""), nil
+}
+
+// ResponseValidator checks JSON responses against the documented responses
+// and hands any mismatch to report. Responses are sent unchanged, so it is
+// meant for tests and staging rather than for rejecting anything.
+func (d *Document) ResponseValidator(report func(c *gin.Context, errs []models.FieldError)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := d.Operation(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := c.Writer.Status()
+		response := op.Responses[strconv.Itoa(status)]
+		if response == nil {
+			response = op.Responses["default"]
+		}
+		if response == nil {
+			report(c, []models.FieldError{{Message: fmt.Sprintf("status %d is not documented", status)}})
+			return
+		}
+
+		contentType := strings.TrimSpace(strings.Split(c.Writer.Header().Get("Content-Type"), ";")[0])
+		media, ok := response.Content[contentType]
+		if !ok {
+			if recorder.size > 0 {
+				report(c, []models.FieldError{{Message: fmt.Sprintf("content type %q is not documented for status %d", contentType, status)}})
+			}
+			return
+		}
+		if !isJSON(contentType) || media.Schema == nil || recorder.overflow {
+			return
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(recorder.body.Bytes()))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			report(c, []models.FieldError{{Message: "invalid JSON: " + err.Error()}})
+			return
+		}
+		if errs := d.Validate(media.Schema, value, ""); len(errs) > 0 {
+			report(c, errs)
+		}
+	}
+}
+
+// isJSON also accepts JSON based types such as application/problem+json.
+func isJSON(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
+
+// bodyRecorder keeps a copy of what a handler writes, up to maxValidatedBody.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	size     int
+	overflow bool
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyRecorder) record(data []byte) {
+	w.size += len(data)
+	if w.overflow || w.body.Len()+len(data) > maxValidatedBody {
+		w.overflow = true
+		return
+	}
+	w.body.Write(data)
+}
@@ -0,0 +1,257 @@
+// Package openapi describes the HTTP API as an OpenAPI 3 document and checks
+// requests and responses against it.
+package openapi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// routes maps "METHOD gin-path" to the documented operation.
+	routes map[string]*Operation
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem holds the operations of one path keyed by lower-case method.
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	// Roles lists the user roles allowed to call the operation.
+	Roles []string `json:"x-roles,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// New returns an empty document. Operations authenticated with the token
+// cookie declare the "cookieAuth" security scheme.
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{
+				"cookieAuth": {Type: "apiKey", In: "cookie", Name: "token"},
+			},
+		},
+		routes: make(map[string]*Operation),
+	}
+}
+
+// Add documents the operation served by a gin route. Path parameters of the
+// route become required string parameters unless the operation declares them.
+func (d *Document) Add(method, route string, op *Operation) {
+	d.AddAt(method, route, PathFromRoute(route), op)
+}
+
+// AddAt documents a route under a path of its own, for routes whose gin
+// pattern does not translate into the public path.
+func (d *Document) AddAt(method, route, path string, op *Operation) {
+	for _, name := range pathParams(route) {
+		if op.param(name, "path") == nil {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: String()})
+		}
+	}
+	if len(op.Roles) > 0 {
+		op.Security = []map[string][]string{{"cookieAuth": {}}}
+	}
+	if op.Responses == nil {
+		op.Responses = make(map[string]*Response)
+	}
+
+	item := d.Paths[path]
+	if item == nil {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+	d.routes[method+" "+route] = op
+}
+
+// Operation returns the operation documented for a gin route, or nil.
+func (d *Document) Operation(method, route string) *Operation {
+	return d.routes[method+" "+route]
+}
+
+// CheckRoutes compares the document with the registered routes. It lists the
+// routes without an operation and the operations without a route, both as
+// "METHOD gin-path".
+func (d *Document) CheckRoutes(routes []Route) (undocumented, unrouted []string) {
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if d.routes[key] == nil {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for key := range d.routes {
+		if !registered[key] {
+			unrouted = append(unrouted, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unrouted)
+	return undocumented, unrouted
+}
+
+// Route is a registered method and gin path, as in gin.RouteInfo.
+type Route struct {
+	Method string
+	Path   string
+}
+
+// PathFromRoute turns gin parameters into OpenAPI templates: /pvz/:pvzId
+// becomes /pvz/{pvzId}. Parameters inside a segment are left as they are.
+func PathFromRoute(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParams(route string) []string {
+	var names []string
+	for _, segment := range strings.Split(route, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			names = append(names, segment[1:])
+		}
+	}
+	return names
+}
+
+func (op *Operation) param(name, in string) *Parameter {
+	for i := range op.Parameters {
+		if op.Parameters[i].Name == name && op.Parameters[i].In == in {
+			return &op.Parameters[i]
+		}
+	}
+	return nil
+}
+
+// Query declares a query parameter.
+func (op *Operation) Query(name string, schema *Schema, description string) *Operation {
+	op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Description: description, Schema: schema})
+	return op
+}
+
+// RequiredQuery declares a query parameter that must be present.
+func (op *Operation) RequiredQuery(name string, schema *Schema, description string) *Operation {
+	op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Description: description, Required: true, Schema: schema})
+	return op
+}
+
+// Body declares a required JSON request body.
+func (op *Operation) Body(schema *Schema) *Operation {
+	op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: schema}}}
+	return op
+}
+
+// OptionalBody declares a JSON request body that may be left out.
+func (op *Operation) OptionalBody(schema *Schema) *Operation {
+	op.Body(schema)
+	op.RequestBody.Required = false
+	return op
+}
+
+// Accepts adds another request content type. The validator only checks JSON
+// bodies, so the schema serves as documentation.
+func (op *Operation) Accepts(contentType string, schema *Schema) *Operation {
+	if op.RequestBody == nil {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{}}
+	}
+	op.RequestBody.Content[contentType] = MediaType{Schema: schema}
+	return op
+}
+
+// Returns documents a JSON response; a nil schema documents a response
+// without a body.
+func (op *Operation) Returns(status int, description string, schema *Schema) *Operation {
+	return op.ReturnsContent(status, description, "application/json", schema)
+}
+
+// ReturnsContent documents a response of another content type. Calling it
+// again for the same status adds a content type.
+func (op *Operation) ReturnsContent(status int, description, contentType string, schema *Schema) *Operation {
+	if op.Responses == nil {
+		op.Responses = make(map[string]*Response)
+	}
+	key := strconv.Itoa(status)
+	response := op.Responses[key]
+	if response == nil {
+		response = &Response{Description: description}
+		op.Responses[key] = response
+	}
+	if schema != nil {
+		if response.Content == nil {
+			response.Content = make(map[string]MediaType)
+		}
+		response.Content[contentType] = MediaType{Schema: schema}
+	}
+	return op
+}
+
+// Fails documents the problem document of every status not documented
+// otherwise.
+func (op *Operation) Fails(description string, schema *Schema) *Operation {
+	if op.Responses == nil {
+		op.Responses = make(map[string]*Response)
+	}
+	op.Responses["default"] = &Response{Description: description, Content: map[string]MediaType{"application/problem+json": {Schema: schema}}}
+	return op
+}
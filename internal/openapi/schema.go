@@ -0,0 +1,214 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI schema object the API uses.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer"} }
+func Number() *Schema  { return &Schema{Type: "number"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+// DateTime is an RFC 3339 timestamp.
+func DateTime() *Schema { return &Schema{Type: "string", Format: "date-time"} }
+
+// Date is a YYYY-MM-DD calendar date.
+func Date() *Schema { return &Schema{Type: "string", Format: "date"} }
+
+func ArrayOf(items *Schema) *Schema { return &Schema{Type: "array", Items: items} }
+
+// MapOf is an object with arbitrary keys and values of one schema.
+func MapOf(values *Schema) *Schema { return &Schema{Type: "object", AdditionalProperties: values} }
+
+// Object is an object with the given properties, of which the required ones
+// must be present.
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+// Length bounds the length of a string in characters; a negative max leaves
+// it unbounded.
+func (s *Schema) Length(min, max int) *Schema {
+	if min > 0 {
+		s.MinLength = &min
+	}
+	if max >= 0 {
+		s.MaxLength = &max
+	}
+	return s
+}
+
+// Between bounds a number.
+func (s *Schema) Between(min, max float64) *Schema {
+	s.Minimum, s.Maximum = &min, &max
+	return s
+}
+
+// AtLeast sets a lower bound on a number.
+func (s *Schema) AtLeast(min float64) *Schema {
+	s.Minimum = &min
+	return s
+}
+
+// Count bounds the number of array items; a negative max leaves it unbounded.
+func (s *Schema) Count(min, max int) *Schema {
+	if min > 0 {
+		s.MinItems = &min
+	}
+	if max >= 0 {
+		s.MaxItems = &max
+	}
+	return s
+}
+
+// OneOf restricts a value to the listed ones.
+func (s *Schema) OneOf(values ...string) *Schema {
+	for _, value := range values {
+		s.Enum = append(s.Enum, value)
+	}
+	return s
+}
+
+// Matching requires a string to match a regular expression.
+func (s *Schema) Matching(pattern string) *Schema {
+	s.Pattern = pattern
+	return s
+}
+
+// OrNull also accepts null, as encoding/json does for any field.
+func (s *Schema) OrNull() *Schema {
+	s.Nullable = true
+	return s
+}
+
+// Describe sets the description.
+func (s *Schema) Describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaOf describes how a Go value is encoded by encoding/json. Named
+// struct types are added to the components and referenced, so each model is
+// described once. Fields without omitempty are required; slices, maps and
+// pointers may also be null.
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return DateTime()
+	case t == rawJSONType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := d.schemaOf(t.Elem())
+		if s.Ref != "" {
+			// A $ref takes no siblings, so a nullable reference wraps it
+			return &Schema{Nullable: true, AllOf: []*Schema{s}}
+		}
+		s.Nullable = true
+		return s
+	case reflect.String:
+		return String()
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return Number()
+	case reflect.Slice, reflect.Array:
+		s := ArrayOf(d.schemaOf(t.Elem()))
+		s.Nullable = t.Kind() == reflect.Slice
+		return s
+	case reflect.Map:
+		s := MapOf(d.schemaOf(t.Elem()))
+		s.Nullable = true
+		return s
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Register before describing the fields so recursive types end
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := Object(map[string]*Schema{})
+	d.addFields(s, t)
+	return s
+}
+
+// addFields follows encoding/json: embedded structs without a name tag
+// contribute their fields to the outer object.
+func (d *Document) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.addFields(s, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = d.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
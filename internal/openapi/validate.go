@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// Validate checks a value decoded with json.Decoder.UseNumber against a
+// schema and returns one error per offending field, in field order.
+func (d *Document) Validate(s *Schema, value interface{}, field string) []models.FieldError {
+	var errs []models.FieldError
+	d.validate(s, value, field, &errs)
+	return errs
+}
+
+func (d *Document) validate(s *Schema, value interface{}, field string, errs *[]models.FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, models.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s == nil {
+		return
+	}
+	if s.Ref != "" {
+		d.validate(d.resolve(s.Ref), value, field, errs)
+		return
+	}
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			fail("must not be null")
+		}
+		return
+	}
+	for _, part := range s.AllOf {
+		d.validate(part, value, field, errs)
+	}
+
+	switch s.Type {
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		d.validateString(s, text, fail)
+	case "integer", "number":
+		kind := "a number"
+		if s.Type == "integer" {
+			kind = "an integer"
+		}
+		number, ok := value.(json.Number)
+		if !ok {
+			fail("must be %s", kind)
+			return
+		}
+		n, err := number.Float64()
+		if err != nil || (s.Type == "integer" && n != math.Trunc(n)) {
+			fail("must be %s", kind)
+			return
+		}
+		switch {
+		case s.Minimum != nil && s.Maximum != nil && (n < *s.Minimum || n > *s.Maximum):
+			fail("must be between %v and %v", *s.Minimum, *s.Maximum)
+		case s.Minimum != nil && n < *s.Minimum:
+			fail("must be at least %v", *s.Minimum)
+		case s.Maximum != nil && n > *s.Maximum:
+			fail("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be true or false")
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		switch {
+		case s.MinItems != nil && len(items) < *s.MinItems:
+			fail("must contain at least %d items", *s.MinItems)
+		case s.MaxItems != nil && len(items) > *s.MaxItems:
+			fail("must contain at most %d items", *s.MaxItems)
+		}
+		for i, item := range items {
+			d.validate(s.Items, item, fmt.Sprintf("%s[%d]", field, i), errs)
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				*errs = append(*errs, models.FieldError{Field: join(field, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				d.validate(property, object[name], join(field, name), errs)
+			} else if s.AdditionalProperties != nil {
+				d.validate(s.AdditionalProperties, object[name], join(field, name), errs)
+			}
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		for _, allowed := range s.Enum {
+			if allowed == value {
+				return
+			}
+		}
+		quoted := make([]string, len(s.Enum))
+		for i, allowed := range s.Enum {
+			quoted[i] = fmt.Sprint(allowed)
+		}
+		fail("must be one of %s", strings.Join(quoted, ", "))
+	}
+}
+
+func (d *Document) validateString(s *Schema, text string, fail func(string, ...interface{})) {
+	length := utf8.RuneCountInString(text)
+	switch {
+	case s.MinLength != nil && s.MaxLength != nil && (length < *s.MinLength || length > *s.MaxLength):
+		if *s.MinLength == 1 {
+			fail("must not be empty and must be at most %d characters", *s.MaxLength)
+		} else {
+			fail("must be between %d and %d characters", *s.MinLength, *s.MaxLength)
+		}
+		return
+	case s.MinLength != nil && length < *s.MinLength:
+		if *s.MinLength == 1 {
+			fail("must not be empty")
+		} else {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		return
+	case s.MaxLength != nil && length > *s.MaxLength:
+		fail("must be at most %d characters", *s.MaxLength)
+		return
+	}
+
+	switch s.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, text); err != nil {
+			fail("must be an RFC 3339 date-time")
+			return
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", text); err != nil {
+			fail("must be a date in YYYY-MM-DD format")
+			return
+		}
+	}
+	if s.Pattern != "" && !compile(s.Pattern).MatchString(text) {
+		fail("must match %s", s.Pattern)
+	}
+}
+
+func (d *Document) resolve(ref string) *Schema {
+	return d.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+}
+
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+var patterns sync.Map
+
+func compile(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(pattern)
+	patterns.Store(pattern, re)
+	return re
+}
@@ -0,0 +1,119 @@
+// Package pagination implements keyset pagination with opaque cursors for
+// lists ordered by a sort key and an ID.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+)
+
+var ErrInvalidCursor = apperr.Invalid("invalid_cursor", "invalid cursor")
+
+// Cursor marks a position in a list by the sort key and ID of an item.
+// A backward cursor asks for the items before that position.
+type Cursor struct {
+	Key      string `json:"k"`
+	ID       string `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Encode returns the opaque form handed to clients.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor produced by Encode.
+func Decode(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Request describes the page to fetch. Offset is only used without a cursor
+// and keeps old page-numbered clients working.
+type Request struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+	// Count asks for the total number of items matching the filters.
+	Count bool
+}
+
+// Info links a fetched page to its neighbours.
+type Info struct {
+	Next  *Cursor
+	Prev  *Cursor
+	Total *int
+}
+
+// Keyset returns the condition selecting the items past the cursor, with its
+// arguments, and the ORDER BY clause to fetch them in, for a list ordered by
+// keyColumn and idColumn descending. The condition is empty on the first
+// page. Fetch Limit+1 rows and pass them to Finish.
+func (r Request) Keyset(keyColumn, idColumn string) (condition string, args []interface{}, order string) {
+	return r.KeysetOrdered(keyColumn, idColumn, false)
+}
+
+// KeysetOrdered is Keyset for a list that may be ordered ascending.
+func (r Request) KeysetOrdered(keyColumn, idColumn string, ascending bool) (condition string, args []interface{}, order string) {
+	op, direction := "<", "DESC"
+	if ascending {
+		op, direction = ">", "ASC"
+	}
+	if r.Cursor != nil && r.Cursor.Backward {
+		if ascending {
+			op, direction = "<", "DESC"
+		} else {
+			op, direction = ">", "ASC"
+		}
+	}
+	order = keyColumn + " " + direction + ", " + idColumn + " " + direction
+	if r.Cursor == nil {
+		return "", nil, order
+	}
+	condition = "(" + keyColumn + " " + op + " ? OR (" + keyColumn + " = ? AND " + idColumn + " " + op + " ?))"
+	return condition, []interface{}{r.Cursor.Key, r.Cursor.Key, r.Cursor.ID}, order
+}
+
+// Finish trims the extra row fetched to detect more items, restores the list
+// order for backward pages and works out the neighbouring cursors.
+// key returns the sort key and ID of an item.
+func Finish[T any](items []T, r Request, key func(T) (string, string)) ([]T, Info) {
+	more := len(items) > r.Limit
+	if more {
+		items = items[:r.Limit]
+	}
+	backward := r.Cursor != nil && r.Cursor.Backward
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	var info Info
+	if len(items) == 0 {
+		return items, info
+	}
+	hasNext, hasPrev := more, r.Cursor != nil || r.Offset > 0
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		k, id := key(items[len(items)-1])
+		info.Next = &Cursor{Key: k, ID: id}
+	}
+	if hasPrev {
+		k, id := key(items[0])
+		info.Prev = &Cursor{Key: k, ID: id, Backward: true}
+	}
+	return items, info
+}
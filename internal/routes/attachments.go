@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/blob"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// BlobStore keeps uploaded attachments. Uploads are refused until it is set.
+var BlobStore blob.Store
+
+const (
+	defaultAttachmentLimit = 50
+	maxAttachmentLimit     = 200
+)
+
+func Products_attachments_post(c *gin.Context) {
+	uploadAttachments(c, "product", c.Param("productId"))
+}
+
+func Receptions_attachments_post(c *gin.Context) {
+	uploadAttachments(c, "reception", c.Param("receptionId"))
+}
+
+func Products_attachments_get(c *gin.Context) {
+	listAttachments(c, "product", c.Param("productId"))
+}
+
+func Receptions_attachments_get(c *gin.Context) {
+	listAttachments(c, "reception", c.Param("receptionId"))
+}
+
+// Attachments_get serves the uploaded file itself.
+func Attachments_get(c *gin.Context) {
+	serveAttachment(c, false)
+}
+
+// Attachments_thumbnail serves a JPEG preview no larger than 256 pixels.
+func Attachments_thumbnail(c *gin.Context) {
+	serveAttachment(c, true)
+}
+
+// uploadAttachments accepts a multipart form with one or more "file" parts and
+// optional "kind" (photo or damage) and "comment" fields.
+func uploadAttachments(c *gin.Context, entityType, entityId string) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxAttachmentsPerUpload*service.MaxAttachmentBytes+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(c, apperr.New(apperr.KindTooLarge, "upload_too_large", "upload is too large"))
+			return
+		}
+		badRequest(c, "multipart form data is required")
+		return
+	}
+
+	attachments, err := Services.Attachments.Upload(c.Request.Context(), BlobStore, service.NewAttachments{
+		EntityType: entityType,
+		EntityId:   entityId,
+		Kind:       formValue(form, "kind"),
+		Comment:    formValue(form, "comment"),
+		Actor:      middleware.CurrentUser(c),
+		Files:      form.File["file"],
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, attachments)
+}
+
+// listAttachments lists one page of the attachments of an entity, oldest first.
+func listAttachments(c *gin.Context, entityType, entityId string) {
+	page, err := pageRequest(c, defaultAttachmentLimit, maxAttachmentLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	attachments, info, err := Services.Attachments.List(entityType, entityId, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, attachments)
+}
+
+func serveAttachment(c *gin.Context, thumb bool) {
+	content, err := Services.Attachments.Content(c.Request.Context(), BlobStore, c.Param("attachmentId"), thumb)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer content.Body.Close()
+
+	// Attachments never change once uploaded
+	headers := map[string]string{"Cache-Control": "private, max-age=31536000, immutable"}
+	if content.FileName != "" {
+		headers["Content-Disposition"] = mime.FormatMediaType("inline", map[string]string{"filename": content.FileName})
+	}
+	c.DataFromReader(http.StatusOK, content.Size, content.ContentType, content.Body, headers)
+}
+
+func formValue(form *multipart.Form, name string) string {
+	if values := form.Value[name]; len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/barcode"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+func Products_by_barcode(c *gin.Context) {
+	code := c.Param("code")
+	if _, err := barcode.Validate(code); err != nil {
+		respondError(c, apperr.Invalid("invalid_barcode", err.Error()))
+		return
+	}
+
+	lookup, err := db.GetProductByBarcode(code)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if lookup == nil {
+		respondError(c, db.ErrProductNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, lookup)
+}
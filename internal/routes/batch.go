@@ -0,0 +1,194 @@
+package routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchSize limits a JSON batch and the chunk size of a streamed one.
+const maxBatchSize = 500
+
+// maxStreamLine limits a single NDJSON line.
+const maxStreamLine = 64 * 1024
+
+type batchItem struct {
+	Type        string             `json:"type"`
+	CellId      string             `json:"cellId"`
+	Barcode     string             `json:"barcode"`
+	OrderId     string             `json:"orderId"`
+	WeightGrams int                `json:"weightGrams"`
+	Dimensions  *models.Dimensions `json:"dimensions"`
+}
+
+// Receptions_products_batch adds many products to an open reception at once.
+// A JSON body is validated as a whole and inserted in a single transaction;
+// an application/x-ndjson body is processed as a stream, see streamProducts.
+//
+// The route is registered as products:action because gin treats every colon
+// in a path as a parameter, so the action is checked here.
+func Receptions_products_batch(c *gin.Context) {
+	if c.Param("action") != ":batch" {
+		respondError(c, errRouteNotFound)
+		return
+	}
+
+	reception, err := Services.Receptions.GetOpen(c.Param("receptionId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	intake, err := Services.Products.Intake(reception)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if strings.HasPrefix(c.ContentType(), "application/x-ndjson") {
+		streamProducts(c, intake)
+		return
+	}
+
+	var req struct {
+		Items []batchItem `json:"items"`
+		// Partial inserts the valid items even if some are rejected.
+		Partial bool `json:"partial"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.Items) == 0 {
+		badRequest(c, "items are required")
+		return
+	}
+	if len(req.Items) > maxBatchSize {
+		badRequest(c, fmt.Sprintf("a batch may contain at most %d items", maxBatchSize))
+		return
+	}
+
+	summary := models.BatchSummary{Items: make([]models.BatchItemResult, len(req.Items))}
+	var products []models.Product
+	for i, item := range req.Items {
+		product, warning, err := intake.Prepare(i, service.NewProduct(item))
+		if err != nil {
+			summary.Items[i] = models.BatchItemResult{Index: i, Status: models.BatchItemRejected}
+			summary.Items[i].Error, summary.Items[i].Code = describe(err)
+			summary.Rejected++
+			continue
+		}
+		summary.Items[i] = models.BatchItemResult{Index: i, Status: models.BatchItemCreated, ProductId: product.ID, Warning: warning}
+		products = append(products, *product)
+	}
+
+	if (summary.Rejected > 0 && !req.Partial) || len(products) == 0 {
+		for i := range summary.Items {
+			if summary.Items[i].Status == models.BatchItemCreated {
+				summary.Items[i].Status = models.BatchItemSkipped
+				summary.Items[i].ProductId = ""
+			}
+		}
+		c.JSON(http.StatusUnprocessableEntity, summary)
+		return
+	}
+
+	if err := intake.Insert(products); err != nil {
+		respondError(c, err)
+		return
+	}
+	summary.Created = len(products)
+	c.JSON(http.StatusCreated, summary)
+}
+
+// streamProducts reads one item per line and writes one result per line in the
+// same order. Accepted items are inserted in chunks of maxBatchSize, each in its
+// own transaction, so a very large delivery never sits in memory at once.
+// Rejected lines do not stop the stream. The last line is the BatchSummary.
+func streamProducts(c *gin.Context, intake *service.Intake) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+
+	var summary models.BatchSummary
+	var products []models.Product
+	var results []models.BatchItemResult
+
+	flush := func() error {
+		var err error
+		if len(products) > 0 {
+			err = intake.Insert(products)
+		}
+		for _, result := range results {
+			if result.Status == models.BatchItemCreated {
+				if err != nil {
+					result.Status = models.BatchItemSkipped
+					result.ProductId = ""
+					result.Error, result.Code = describe(err)
+				} else {
+					summary.Created++
+				}
+			}
+			encoder.Encode(result)
+		}
+		c.Writer.Flush()
+		products, results = products[:0], results[:0]
+		return err
+	}
+
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxStreamLine)
+	index := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var item batchItem
+		result := models.BatchItemResult{Index: index}
+		index++
+		var product *models.Product
+		var warning string
+		err := json.Unmarshal([]byte(line), &item)
+		if err != nil {
+			err = apperr.Invalid("invalid_json", fmt.Sprintf("invalid JSON: %v", err))
+		} else {
+			product, warning, err = intake.Prepare(result.Index, service.NewProduct(item))
+		}
+
+		if err != nil {
+			result.Status = models.BatchItemRejected
+			result.Error, result.Code = describe(err)
+			summary.Rejected++
+		} else {
+			result.Status = models.BatchItemCreated
+			result.ProductId = product.ID
+			result.Warning = warning
+			products = append(products, *product)
+		}
+		results = append(results, result)
+
+		if len(products) == maxBatchSize {
+			if err := flush(); err != nil {
+				encoder.Encode(summary)
+				return
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		encoder.Encode(summary)
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		encoder.Encode(middleware.ProblemOf(c, apperr.Invalid("unreadable_stream", fmt.Sprintf("failed to read stream: %v", err))))
+		return
+	}
+	encoder.Encode(summary)
+}
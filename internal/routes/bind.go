@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON decodes the request body into req. When the body does not decode
+// it answers 400, naming the field whose value has the wrong type.
+func bindJSON(c *gin.Context, req interface{}) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	var fields []models.FieldError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		fields = []models.FieldError{{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type)}}
+	}
+	respondError(c, apperr.Invalid("invalid_request", "Invalid request", fields...))
+	return false
+}
+
+// jsonKind names the JSON value a Go type decodes from.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Ptr:
+		return jsonKind(t.Elem())
+	}
+	return "an object"
+}
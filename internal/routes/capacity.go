@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func PVZ_capacity(c *gin.Context) {
+	var req models.Capacity
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	occupancy, err := Services.PVZ.SetCapacity(c.Param("pvzId"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, occupancy)
+}
+
+func PVZ_occupancy(c *gin.Context) {
+	occupancy, err := Services.PVZ.Occupancy(c.Param("pvzId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, occupancy)
+}
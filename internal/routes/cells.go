@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCellLimit = 100
+	maxCellLimit     = 500
+)
+
+func PVZ_cells_post(c *gin.Context) {
+	var req struct {
+		Zone     string `json:"zone"`
+		Rack     string `json:"rack"`
+		Shelf    string `json:"shelf"`
+		Capacity int    `json:"capacity"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	cell, err := Services.Cells.Create(c.Param("pvzId"), service.NewCell(req))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, cell)
+}
+
+func PVZ_cells_get(c *gin.Context) {
+	page, err := pageRequest(c, defaultCellLimit, maxCellLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	cells, info, err := Services.Cells.List(c.Param("pvzId"), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, cells)
+}
+
+func Products_cell_get(c *gin.Context) {
+	location, err := Services.Cells.Location(c.Param("productId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, location)
+}
+
+func Products_cell_put(c *gin.Context) {
+	var req struct {
+		CellId string `json:"cellId"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	location, err := Services.Cells.Move(c.Param("productId"), req.CellId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, location)
+}
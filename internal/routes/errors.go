@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+var errRouteNotFound = apperr.NotFound("route_not_found", "Not found")
+
+// respondError answers with err as a problem document, with the status of its
+// kind. Errors without a kind are logged and reported as internal.
+func respondError(c *gin.Context, err error) {
+	middleware.Problem(c, err)
+}
+
+// badRequest rejects input that a handler validates itself.
+func badRequest(c *gin.Context, message string) {
+	respondError(c, apperr.Invalid("invalid_request", message))
+}
+
+// describe returns the message and code of err that a client may see.
+func describe(err error) (message, code string) {
+	e := apperr.From(err)
+	return e.Message, e.Code
+}
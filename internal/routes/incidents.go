@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultIncidentLimit = 50
+	maxIncidentLimit     = 200
+)
+
+type incidentRequest struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	ProductId   string `json:"productId"`
+}
+
+func (req incidentRequest) incident(c *gin.Context) service.NewIncident {
+	return service.NewIncident{
+		Type:        req.Type,
+		Description: req.Description,
+		ProductId:   req.ProductId,
+		Actor:       middleware.CurrentUser(c),
+	}
+}
+
+// Receptions_incidents_post logs an incident against a reception, optionally
+// naming one of its products.
+func Receptions_incidents_post(c *gin.Context) {
+	var req incidentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	incident, err := Services.Incidents.Report(c.Param("receptionId"), req.incident(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, incident)
+}
+
+// Products_incidents_post logs an incident against a product and the
+// reception it arrived with.
+func Products_incidents_post(c *gin.Context) {
+	var req incidentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	incident, err := Services.Incidents.ReportProduct(c.Param("productId"), req.incident(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, incident)
+}
+
+// Receptions_incidents_get lists the incidents of a reception newest first,
+// paginated like GET /incidents.
+func Receptions_incidents_get(c *gin.Context) {
+	page, err := pageRequest(c, defaultIncidentLimit, maxIncidentLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	incidents, info, err := Services.Incidents.ListForReception(c.Param("receptionId"), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, incidents)
+}
+
+// Incidents_get lists incidents across PVZs, filtered by status and pvzId and
+// paginated with cursors.
+func Incidents_get(c *gin.Context) {
+	page, err := pageRequest(c, defaultIncidentLimit, maxIncidentLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	incidents, info, err := Services.Incidents.List(c.Query("status"), c.Query("pvzId"), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, incidents)
+}
+
+func Incidents_get_by_id(c *gin.Context) {
+	incident, err := Services.Incidents.Get(c.Param("incidentId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, incident)
+}
+
+func Incidents_resolve(c *gin.Context) {
+	var req struct {
+		Resolution string `json:"resolution"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	incident, err := Services.Incidents.Resolve(c.Param("incidentId"), middleware.CurrentUser(c), req.Resolution)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, incident)
+}
@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 500
+)
+
+func Products_return(c *gin.Context) {
+	changeProductStatus(c, models.ProductStatusReturnedToSender,
+		models.ProductStatusReceived, models.ProductStatusStored)
+}
+
+func Products_lost(c *gin.Context) {
+	changeProductStatus(c, models.ProductStatusLost,
+		models.ProductStatusReceived, models.ProductStatusStored)
+}
+
+func Products_history(c *gin.Context) {
+	page, err := pageRequest(c, defaultHistoryLimit, maxHistoryLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	history, info, err := db.GetProductStatusHistory(c.Param("productId"), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, history)
+}
+
+// changeProductStatus moves the product from the URL to status, recording the
+// optional reason from the request body in its history.
+func changeProductStatus(c *gin.Context, status string, allowedFrom ...string) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength != 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	productId := c.Param("productId")
+	if _, err := db.GetProductByID(productId); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	product, err := db.UpdateProductStatus(productId, status, req.Reason, allowedFrom...)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
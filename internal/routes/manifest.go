@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/manifest"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func Receptions_manifest_put(c *gin.Context) {
+	var req struct {
+		Source string                `json:"source"`
+		Items  []models.ManifestItem `json:"items"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	m, err := Services.Manifests.Attach(c.Param("receptionId"), req.Source, middleware.CurrentUser(c), req.Items)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func Receptions_manifest_get(c *gin.Context) {
+	m, err := Services.Manifests.Get(c.Param("receptionId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func Receptions_reconciliation(c *gin.Context) {
+	report, err := Services.Manifests.Reconciliation(c.Param("receptionId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// maxManifestFileSize limits an imported manifest file.
+const maxManifestFileSize = 10 << 20
+
+// Manifests_import reads a supplier manifest file from the request body and
+// creates a pending reception with the expected items for every PVZ in it.
+// With dryRun=true nothing is created and the row-level errors are returned.
+func Manifests_import(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		switch c.ContentType() {
+		case "text/csv":
+			format = manifest.FormatCSV
+		case "application/json":
+			format = manifest.FormatJSON
+		}
+	}
+	dryRun := c.Query("dryRun") == "true"
+
+	result, err := Services.Manifests.Import(service.ManifestFile{
+		Format: format,
+		Body:   http.MaxBytesReader(c.Writer, c.Request.Body, maxManifestFileSize),
+		Mapping: manifest.Mapping{
+			Barcode:  c.Query("barcodeColumn"),
+			Type:     c.Query("typeColumn"),
+			Quantity: c.Query("quantityColumn"),
+			PvzId:    c.Query("pvzColumn"),
+		},
+		PvzId:  c.Query("pvzId"),
+		Source: c.DefaultQuery("source", "import"),
+		Actor:  middleware.CurrentUser(c),
+		DryRun: dryRun,
+	})
+	if errors.Is(err, service.ErrImportRejected) {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	} else if err != nil {
+		respondError(c, err)
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,371 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/openapi"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// API documents every route SetupRoutes registers. Request schemas describe
+// what the handlers accept, so the validator rejects malformed requests
+// before they are bound; rules that need the database stay in the handlers.
+var API = newAPI()
+
+// OpenAPI_get serves the API document.
+func OpenAPI_get(c *gin.Context) {
+	c.JSON(http.StatusOK, API)
+}
+
+var (
+	employeeOnly  = []string{"PVZemployee"}
+	moderatorOnly = []string{"Moderator"}
+	staff         = []string{"PVZemployee", "Moderator"}
+)
+
+func operation(id, summary string, roles []string) *openapi.Operation {
+	return &openapi.Operation{OperationID: id, Summary: summary, Roles: roles}
+}
+
+func newAPI() *openapi.Document {
+	api := openapi.New("PVZ service", "1.0.0")
+
+	errorSchema := api.SchemaOf(models.Error{})
+	message := openapi.Object(map[string]*openapi.Schema{"message": openapi.String()}, "message")
+	token := openapi.Object(map[string]*openapi.Schema{"token": openapi.String()}, "token")
+	pvz := api.SchemaOf(models.PVZ{})
+	reception := api.SchemaOf(models.Reception{})
+	product := api.SchemaOf(models.Product{})
+	products := openapi.ArrayOf(product)
+	order := api.SchemaOf(models.Order{})
+	transfer := api.SchemaOf(models.Transfer{})
+	incident := api.SchemaOf(models.Incident{})
+	attachments := openapi.ArrayOf(api.SchemaOf(models.Attachment{}))
+	occupancy := api.SchemaOf(models.Occupancy{})
+	location := api.SchemaOf(models.ProductLocation{})
+	manifest := api.SchemaOf(models.Manifest{})
+	productType := api.SchemaOf(models.ProductType{})
+
+	id := func() *openapi.Schema { return openapi.String().Length(1, -1) }
+	dimensions := func() *openapi.Schema {
+		side := func() *openapi.Schema { return openapi.Integer().Between(1, service.MaxMeasurement/100) }
+		return openapi.Object(map[string]*openapi.Schema{
+			"lengthMm": side(), "widthMm": side(), "heightMm": side(),
+		}).OrNull()
+	}
+	schedule := func() map[string]*openapi.Schema {
+		clock := func() *openapi.Schema { return openapi.String().Matching(`^\d{2}:\d{2}$`) }
+		return map[string]*openapi.Schema{
+			"workingHours": openapi.ArrayOf(openapi.Object(map[string]*openapi.Schema{
+				"weekday": openapi.String(), "open": clock(), "close": clock(),
+			}, "weekday", "open", "close")).OrNull(),
+			"holidays": openapi.ArrayOf(openapi.Object(map[string]*openapi.Schema{
+				"date": openapi.Date(), "closed": openapi.Boolean(), "open": clock(), "close": clock(),
+			}, "date")).OrNull(),
+		}
+	}
+	productFields := func() map[string]*openapi.Schema {
+		return map[string]*openapi.Schema{
+			"type":        id(),
+			"cellId":      openapi.String(),
+			"barcode":     openapi.String(),
+			"orderId":     openapi.String(),
+			"weightGrams": openapi.Integer().Between(0, service.MaxMeasurement),
+			"dimensions":  dimensions(),
+		}
+	}
+	incidentBody := func() *openapi.Schema {
+		return openapi.Object(map[string]*openapi.Schema{
+			"type":        openapi.String().OneOf(models.IncidentDamaged, models.IncidentWrongPvz, models.IncidentMissingSeal, models.IncidentOther),
+			"description": openapi.String().Length(0, service.MaxIncidentText),
+			"productId":   openapi.String(),
+		}, "type")
+	}
+	upload := openapi.Object(map[string]*openapi.Schema{
+		"files":   openapi.ArrayOf(&openapi.Schema{Type: "string", Format: "binary"}).Count(1, service.MaxAttachmentsPerUpload),
+		"kind":    openapi.String().OneOf(models.AttachmentPhoto, models.AttachmentDamage),
+		"comment": openapi.String().Length(0, service.MaxAttachmentComment),
+	}, "files")
+	paged := func(op *openapi.Operation, maxLimit int) *openapi.Operation {
+		return op.
+			Query("limit", openapi.Integer().Between(1, float64(maxLimit)), "Page size").
+			Query("cursor", openapi.String(), "Cursor from a Link header; cannot be combined with page").
+			Query("page", openapi.Integer().AtLeast(1), "1-based page number").
+			Query("count", openapi.Boolean(), "Report the total in X-Total-Count")
+	}
+	add := func(method, route string, op *openapi.Operation) {
+		api.Add(method, route, op.Fails("Error", errorSchema))
+	}
+
+	add("GET", "/openapi.json", operation("getOpenAPI", "This document", nil).
+		Returns(200, "OpenAPI document", &openapi.Schema{Type: "object"}))
+
+	// Users
+	add("POST", "/dummyLogin", operation("dummyLogin", "Get a token without an account", nil).
+		Body(openapi.Object(map[string]*openapi.Schema{"role": openapi.String()})).
+		Returns(200, "Token, also set as a cookie", token))
+	add("POST", "/register", operation("register", "Create a user", nil).
+		Body(openapi.Object(map[string]*openapi.Schema{
+			"email": id(), "password": id(), "role": openapi.String(),
+		}, "email", "password", "role")).
+		Returns(201, "Created user", api.SchemaOf(models.User{})))
+	add("POST", "/login", operation("login", "Log in with email and password", nil).
+		Body(openapi.Object(map[string]*openapi.Schema{"email": openapi.String(), "password": openapi.String()}, "email", "password")).
+		Returns(200, "Token, also set as a cookie", token))
+
+	// PVZs
+	pvzBody := schedule()
+	pvzBody["city"] = openapi.String().OneOf(service.Cities...)
+	pvzBody["address"] = openapi.String()
+	pvzBody["latitude"] = openapi.Number().Between(-90, 90).OrNull()
+	pvzBody["longitude"] = openapi.Number().Between(-180, 180).OrNull()
+	pvzBody["phone"] = openapi.String()
+	add("POST", "/pvz", operation("createPVZ", "Register a PVZ", employeeOnly).
+		Body(openapi.Object(pvzBody, "city")).
+		Returns(201, "Created PVZ", pvz))
+	add("GET", "/pvz", paged(operation("listPVZs", "List PVZs", staff).
+		Query("startDate", openapi.String(), "Registered on or after, RFC 3339 or YYYY-MM-DD").
+		Query("endDate", openapi.String(), "Registered on or before, RFC 3339 or YYYY-MM-DD").
+		Query("city", openapi.ArrayOf(openapi.String().Length(1, -1)), "City, may be repeated").
+		Query("status", openapi.String().OneOf("in_progress", "close", "none"), "Status of the latest reception").
+		Query("hasOpenReception", openapi.Boolean(), "").
+		Query("productType", openapi.ArrayOf(openapi.String()), "Received products of the type, may be repeated").
+		Query("receptionFrom", openapi.String(), "Had a reception on or after").
+		Query("receptionTo", openapi.String(), "Had a reception on or before").
+		Query("sort", openapi.String().OneOf("registrationDate", "city", "activity"), "").
+		Query("order", openapi.String().OneOf("asc", "desc"), ""), maxPVZLimit).
+		Returns(200, "PVZs", openapi.ArrayOf(pvz)))
+	add("GET", "/pvz/nearby", operation("nearbyPVZs", "Find PVZs around a point", nil).
+		RequiredQuery("lat", openapi.Number().Between(-90, 90), "Latitude").
+		RequiredQuery("lon", openapi.Number().Between(-180, 180), "Longitude").
+		Query("radius", openapi.Number().Between(0, maxNearbyRadius), "Search radius in meters").
+		Query("limit", openapi.Integer().Between(1, maxNearbyLimit), "").
+		Query("openNow", openapi.Boolean(), "Only PVZs open at the moment").
+		Returns(200, "PVZs by distance", openapi.ArrayOf(api.SchemaOf(models.NearbyPVZ{}))))
+	add("GET", "/pvz/:pvzId", operation("getPVZ", "Get a PVZ", staff).
+		Returns(200, "PVZ", pvz))
+	add("PUT", "/pvz/:pvzId/working_hours", operation("setWorkingHours", "Replace working hours and holidays", moderatorOnly).
+		Body(openapi.Object(schedule())).
+		Returns(200, "Updated PVZ", pvz))
+	add("PUT", "/pvz/:pvzId/capacity", operation("setCapacity", "Set capacity limits", moderatorOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{
+			"total":     openapi.Integer().AtLeast(0),
+			"byType":    openapi.MapOf(openapi.Integer().AtLeast(0)).OrNull(),
+			"policy":    openapi.String().OneOf("", service.CapacityPolicyReject, service.CapacityPolicyWarn),
+			"volumeCm3": openapi.Integer().AtLeast(0),
+		})).
+		Returns(200, "Occupancy with the new limits", occupancy))
+	add("GET", "/pvz/:pvzId/occupancy", operation("getOccupancy", "Get stored products against capacity", staff).
+		Returns(200, "Occupancy", occupancy))
+	add("POST", "/pvz/:pvzId/cells", operation("createCell", "Add a storage cell", moderatorOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{
+			"zone": id(), "rack": id(), "shelf": id(), "capacity": openapi.Integer().AtLeast(0),
+		}, "zone", "rack", "shelf")).
+		Returns(201, "Created cell", api.SchemaOf(models.StorageCell{})))
+	add("GET", "/pvz/:pvzId/cells", paged(operation("listCells", "List storage cells", staff), maxCellLimit).
+		Returns(200, "Cells by address", openapi.ArrayOf(api.SchemaOf(models.StorageCell{}))))
+	add("POST", "/pvz/:pvzId/close_last_reception", operation("closeLastReception", "Close the open reception", employeeOnly).
+		Returns(200, "Closed, with the reconciliation if the reception had a manifest", openapi.Object(map[string]*openapi.Schema{
+			"message":        openapi.String(),
+			"reconciliation": api.SchemaOf(models.Reconciliation{}),
+		}, "message")))
+	add("POST", "/pvz/:pvzId/delete_last_product", operation("deleteLastProduct", "Delete the last product of the open reception", employeeOnly).
+		Returns(200, "Deleted", message))
+	add("PUT", "/pvz/:pvzId/reception_timeout", operation("setReceptionTimeout", "Set how long a reception may stay open", moderatorOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{
+			"minutes": openapi.Integer().Between(0, maxReceptionTimeoutMinutes),
+			"action":  openapi.String().OneOf("", models.StaleActionClose, models.StaleActionFlag),
+		})).
+		Returns(200, "Timeout", api.SchemaOf(models.ReceptionTimeout{})))
+	add("GET", "/pvz/:pvzId/expiring", paged(operation("listExpiring", "List products whose storage period ends soon", staff).
+		Query("days", openapi.Integer().Between(0, service.MaxStoragePeriodDays), "Look ahead this many days"), maxStorageListLimit).
+		Returns(200, "Products by deadline", openapi.ArrayOf(api.SchemaOf(models.ExpiringProduct{}))))
+	add("GET", "/pvz/:pvzId/return_batches", paged(operation("listReturnBatches", "List return batches", staff), maxStorageListLimit).
+		Returns(200, "Batches, newest first", openapi.ArrayOf(api.SchemaOf(models.ReturnBatch{}))))
+
+	// Receptions
+	add("POST", "/receptions", operation("createReception", "Open a reception", staff).
+		Body(openapi.Object(map[string]*openapi.Schema{"pvzId": id(), "override": openapi.Boolean()}, "pvzId")).
+		Returns(201, "Opened reception", reception))
+	add("GET", "/receptions/:receptionId", operation("getReception", "Get a reception with a page of its products", staff).
+		Query("page", openapi.Integer().AtLeast(1), "").
+		Query("limit", openapi.Integer().Between(1, maxReceptionProductsLimit), "").
+		Returns(200, "Reception", api.SchemaOf(models.ReceptionDetails{})))
+	add("GET", "/receptions/:receptionId/products", paged(operation("listReceptionProducts", "List the products of a reception", staff), maxReceptionProductsLimit).
+		Returns(200, "Products, newest first", products))
+	batchItem := openapi.Object(productFields(), "type")
+	api.AddAt("POST", "/receptions/:receptionId/products:action", "/receptions/{receptionId}/products:batch",
+		operation("addProducts", "Add many products to the open reception", employeeOnly).
+			Body(openapi.Object(map[string]*openapi.Schema{
+				"items":   openapi.ArrayOf(batchItem).Count(1, maxBatchSize),
+				"partial": openapi.Boolean(),
+			}, "items")).
+			Accepts("application/x-ndjson", batchItem).
+			Returns(201, "All items created", api.SchemaOf(models.BatchSummary{})).
+			Returns(422, "Some items rejected", api.SchemaOf(models.BatchSummary{})).
+			ReturnsContent(200, "Result per streamed item", "application/x-ndjson", api.SchemaOf(models.BatchItemResult{})).
+			Fails("Error", errorSchema))
+	add("DELETE", "/receptions/:receptionId/products/:productId", operation("deleteReceptionProduct", "Delete a product of the open reception", employeeOnly).
+		Returns(200, "Deleted", message))
+	add("GET", "/receptions/stale", paged(operation("listStaleReceptions", "List receptions open past their timeout", moderatorOnly), maxStaleLimit).
+		Returns(200, "Stale receptions, oldest first", openapi.ArrayOf(api.SchemaOf(models.StaleReception{}))))
+	add("POST", "/manifests/import", operation("importManifests", "Create pending receptions from a supplier manifest", staff).
+		Query("format", openapi.String().OneOf("csv", "json"), "Defaults to the content type").
+		Query("barcodeColumn", openapi.String(), "").
+		Query("typeColumn", openapi.String(), "").
+		Query("quantityColumn", openapi.String(), "").
+		Query("pvzColumn", openapi.String(), "").
+		Query("pvzId", openapi.String(), "PVZ of rows without one").
+		Query("dryRun", openapi.Boolean(), "Only report the errors").
+		Query("source", openapi.String(), "").
+		Accepts("text/csv", openapi.String()).
+		Accepts("application/json", nil).
+		Returns(201, "Imported", api.SchemaOf(models.ManifestImport{})).
+		Returns(200, "Dry run", api.SchemaOf(models.ManifestImport{})).
+		Returns(422, "Rows with errors", api.SchemaOf(models.ManifestImport{})))
+	add("PUT", "/receptions/:receptionId/manifest", operation("setManifest", "Attach the expected items", staff).
+		Body(openapi.Object(map[string]*openapi.Schema{
+			"source": openapi.String(),
+			"items": openapi.ArrayOf(openapi.Object(map[string]*openapi.Schema{
+				"barcode": openapi.String(), "type": openapi.String(), "quantity": openapi.Integer(),
+			})).OrNull(),
+		})).
+		Returns(200, "Manifest", manifest))
+	add("GET", "/receptions/:receptionId/manifest", operation("getManifest", "Get the expected items", staff).
+		Returns(200, "Manifest", manifest))
+	add("GET", "/receptions/:receptionId/reconciliation", operation("getReconciliation", "Compare received products with the manifest", staff).
+		Returns(200, "Reconciliation", api.SchemaOf(models.Reconciliation{})))
+	add("POST", "/receptions/:receptionId/attachments", operation("uploadReceptionAttachments", "Upload photos of a reception", staff).
+		Accepts("multipart/form-data", upload).
+		Returns(201, "Uploaded", attachments))
+	add("GET", "/receptions/:receptionId/attachments", paged(operation("listReceptionAttachments", "List reception photos", staff), maxAttachmentLimit).
+		Returns(200, "Attachments, oldest first", attachments))
+	add("POST", "/receptions/:receptionId/incidents", operation("reportReceptionIncident", "Report a problem with a reception", staff).
+		Body(incidentBody()).
+		Returns(201, "Reported incident", incident))
+	add("GET", "/receptions/:receptionId/incidents", paged(operation("listReceptionIncidents", "List incidents of a reception", staff), maxIncidentLimit).
+		Returns(200, "Incidents, newest first", openapi.ArrayOf(incident)))
+	add("GET", "/receptions/:receptionId/audit", paged(operation("getReceptionAudit", "List changes to a reception", moderatorOnly), maxAuditLimit).
+		Returns(200, "Audit entries, oldest first", openapi.ArrayOf(api.SchemaOf(models.AuditEntry{}))))
+
+	// Products
+	productBody := productFields()
+	productBody["pvzId"] = openapi.String().Describe("PVZ whose open reception gets the product")
+	productBody["receptionId"] = openapi.String().Describe("Reception to add to instead of the open one")
+	add("POST", "/products", operation("createProduct", "Add a product to the open reception", employeeOnly).
+		Body(openapi.Object(productBody, "type")).
+		Returns(201, "Created product", product))
+	add("GET", "/products/by-barcode/:code", operation("findProductByBarcode", "Find a product by barcode", staff).
+		Returns(200, "Product and where it is", api.SchemaOf(models.ProductLookup{})))
+	add("GET", "/products/:productId/cell", operation("getProductCell", "Get where a product is stored", staff).
+		Returns(200, "Location", location))
+	add("PUT", "/products/:productId/cell", operation("moveProduct", "Put a product into a cell", employeeOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{"cellId": id()}, "cellId")).
+		Returns(200, "Location", location))
+	reason := openapi.Object(map[string]*openapi.Schema{"reason": openapi.String()})
+	add("POST", "/products/:productId/return", operation("returnProduct", "Return a product to the sender", employeeOnly).
+		OptionalBody(reason).
+		Returns(200, "Updated product", product))
+	add("POST", "/products/:productId/lost", operation("markProductLost", "Mark a product as lost", staff).
+		OptionalBody(reason).
+		Returns(200, "Updated product", product))
+	add("GET", "/products/:productId/history", paged(operation("getProductHistory", "List status changes of a product", staff), maxHistoryLimit).
+		Returns(200, "Status changes, oldest first", openapi.ArrayOf(api.SchemaOf(models.ProductStatusChange{}))))
+	add("POST", "/products/:productId/attachments", operation("uploadProductAttachments", "Upload photos of a product", staff).
+		Accepts("multipart/form-data", upload).
+		Returns(201, "Uploaded", attachments))
+	add("GET", "/products/:productId/attachments", paged(operation("listProductAttachments", "List product photos", staff), maxAttachmentLimit).
+		Returns(200, "Attachments, oldest first", attachments))
+	add("POST", "/products/:productId/incidents", operation("reportProductIncident", "Report a problem with a product", staff).
+		Body(incidentBody()).
+		Returns(201, "Reported incident", incident))
+
+	// Incidents and attachments
+	add("GET", "/incidents", paged(operation("listIncidents", "List incidents", moderatorOnly).
+		Query("status", openapi.String().OneOf(models.IncidentStatusOpen, models.IncidentStatusResolved), "").
+		Query("pvzId", openapi.String(), ""), maxIncidentLimit).
+		Returns(200, "Incidents, newest first", openapi.ArrayOf(incident)))
+	add("GET", "/incidents/:incidentId", operation("getIncident", "Get an incident", staff).
+		Returns(200, "Incident", incident))
+	add("POST", "/incidents/:incidentId/resolve", operation("resolveIncident", "Resolve an incident", moderatorOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{"resolution": openapi.String().Length(1, service.MaxIncidentText)}, "resolution")).
+		Returns(200, "Resolved incident", incident))
+	image := &openapi.Schema{Type: "string", Format: "binary"}
+	add("GET", "/attachments/:attachmentId", operation("getAttachment", "Download an attachment", staff).
+		ReturnsContent(200, "Image", "image/jpeg", image).
+		ReturnsContent(200, "Image", "image/png", image).
+		ReturnsContent(200, "Image", "image/gif", image))
+	add("GET", "/attachments/:attachmentId/thumbnail", operation("getAttachmentThumbnail", "Download the thumbnail of an attachment", staff).
+		ReturnsContent(200, "JPEG thumbnail", "image/jpeg", image))
+	add("GET", "/search", operation("search", "Search PVZs, receptions, products and orders", staff).
+		RequiredQuery("q", openapi.String().Length(1, service.MaxSearchLength), "Words to find, matched as prefixes").
+		Query("kind", openapi.String(), "Comma-separated kinds: pvz, reception, product, order").
+		Query("limit", openapi.Integer().Between(1, maxSearchLimit), "").
+		Returns(200, "Results, best first", openapi.ArrayOf(api.SchemaOf(models.SearchResult{}))))
+
+	// Orders and transfers
+	add("POST", "/orders", operation("createOrder", "Register an order", staff).
+		Body(openapi.Object(map[string]*openapi.Schema{
+			"orderNumber": id(), "pvzId": id(), "recipientPhone": openapi.String(),
+		}, "orderNumber", "pvzId", "recipientPhone")).
+		Returns(201, "Created order", order))
+	add("GET", "/orders/by-number/:number", operation("findOrderByNumber", "Find an order by number", staff).
+		Returns(200, "Order", order))
+	add("GET", "/orders/:orderId", operation("getOrder", "Get an order", staff).
+		Returns(200, "Order", order))
+	add("POST", "/orders/:orderId/pickup_code", operation("sendPickupCode", "Send a new pickup code to the recipient", employeeOnly).
+		Returns(200, "Sent", message))
+	add("POST", "/orders/:orderId/issue", operation("issueOrder", "Issue an order against its pickup code", employeeOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{"pickupCode": id()}, "pickupCode")).
+		Returns(200, "Issued order", order))
+	add("POST", "/transfers", operation("createTransfer", "Send stored products to another PVZ", employeeOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{
+			"sourcePvzId":      id(),
+			"destinationPvzId": id(),
+			"productIds":       openapi.ArrayOf(id()).Count(1, -1),
+		}, "sourcePvzId", "destinationPvzId", "productIds")).
+		Returns(201, "Created transfer", transfer))
+	add("GET", "/transfers/:transferId", operation("getTransfer", "Get a transfer", staff).
+		Returns(200, "Transfer", transfer))
+	add("POST", "/transfers/:transferId/in_transit", operation("dispatchTransfer", "Mark a transfer as on its way", employeeOnly).
+		Returns(200, "Transfer", transfer))
+	add("POST", "/transfers/:transferId/receive", operation("receiveTransfer", "Receive a transfer at the destination", employeeOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{"productIds": openapi.ArrayOf(openapi.String()).OrNull()})).
+		Returns(200, "Received transfer and what did not match", openapi.Object(map[string]*openapi.Schema{
+			"transfer":    transfer,
+			"discrepancy": api.SchemaOf(models.TransferDiscrepancy{}),
+		}, "transfer", "discrepancy")))
+
+	// Storage and catalogue
+	add("PUT", "/storage_periods/:type", operation("setStoragePeriod", "Set the storage period of a product type", moderatorOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{"days": openapi.Integer().Between(1, service.MaxStoragePeriodDays)}, "days")).
+		Returns(200, "Storage period", api.SchemaOf(models.StoragePeriod{})))
+	add("GET", "/storage_periods", operation("listStoragePeriods", "List storage periods", staff).
+		Returns(200, "Default and configured periods", openapi.Object(map[string]*openapi.Schema{
+			"defaultDays": openapi.Integer(),
+			"periods":     openapi.ArrayOf(api.SchemaOf(models.StoragePeriod{})).OrNull(),
+		}, "defaultDays", "periods")))
+	add("POST", "/return_batches/:batchId/dispatch", operation("dispatchReturnBatch", "Send a return batch back", employeeOnly).
+		Returns(200, "Dispatched batch", api.SchemaOf(models.ReturnBatch{})))
+	add("GET", "/product_types", paged(operation("listProductTypes", "List the product type catalogue", staff).
+		Query("all", openapi.Boolean(), "Include inactive types, for moderators"), maxProductTypeLimit).
+		Returns(200, "Product types by code", openapi.ArrayOf(productType)))
+	add("GET", "/product_types/:code", operation("getProductType", "Get a product type", staff).
+		Returns(200, "Product type", productType))
+	add("PUT", "/product_types/:code", operation("setProductType", "Create or update a product type", moderatorOnly).
+		Body(openapi.Object(map[string]*openapi.Schema{
+			"names":             openapi.MapOf(openapi.String().Length(1, -1)),
+			"sizeClass":         openapi.String().OneOf(models.SizeClassSmall, models.SizeClassMedium, models.SizeClassLarge, models.SizeClassOversized),
+			"fragile":           openapi.Boolean(),
+			"hazardous":         openapi.Boolean(),
+			"active":            openapi.Boolean().OrNull(),
+			"storagePeriodDays": openapi.Integer().Between(0, service.MaxStoragePeriodDays).Describe("0 removes the storage period; omit it to keep the current one"),
+			"maxWeightGrams":    openapi.Integer().Between(0, service.MaxMeasurement),
+			"maxDimensions":     dimensions(),
+		}, "names", "sizeClass")).
+		Returns(200, "Product type", productType))
+
+	return api
+}
@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/notify"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Notifier delivers pickup codes to customers. It defaults to a local stub.
+var Notifier notify.Notifier = notify.NewStub(nil)
+
+func Orders_post(c *gin.Context) {
+	var req struct {
+		OrderNumber    string `json:"orderNumber"`
+		PvzId          string `json:"pvzId"`
+		RecipientPhone string `json:"recipientPhone"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	order, err := Services.Orders.Create(service.NewOrder(req))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, order)
+}
+
+func Orders_get(c *gin.Context) {
+	order, err := Services.Orders.Get(c.Param("orderId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+func Orders_by_number(c *gin.Context) {
+	order, err := Services.Orders.GetByNumber(c.Param("number"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+func Orders_pickup_code(c *gin.Context) {
+	if err := Services.Orders.SendPickupCode(c.Param("orderId"), Notifier); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pickup code sent"})
+}
+
+func Orders_issue(c *gin.Context) {
+	var req struct {
+		PickupCode string `json:"pickupCode"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	order, err := Services.Orders.Issue(c.Param("orderId"), req.PickupCode)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
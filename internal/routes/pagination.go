@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+// pageRequest reads the limit, cursor and count query parameters of a list
+// endpoint. A page number is still accepted when no cursor is given.
+func pageRequest(c *gin.Context, defaultLimit, maxLimit int) (pagination.Request, error) {
+	req := pagination.Request{Limit: defaultLimit}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return req, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		req.Limit = limit
+	}
+
+	cursor, page := c.Query("cursor"), c.Query("page")
+	if cursor != "" && page != "" {
+		return req, fmt.Errorf("cursor and page cannot be combined")
+	}
+	if cursor != "" {
+		decoded, err := pagination.Decode(cursor)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = decoded
+	}
+	if page != "" {
+		number, err := strconv.Atoi(page)
+		if err != nil || number < 1 {
+			return req, fmt.Errorf("page must be a positive number")
+		}
+		req.Offset = (number - 1) * req.Limit
+	}
+
+	if value := c.Query("count"); value != "" {
+		count, err := strconv.ParseBool(value)
+		if err != nil {
+			return req, fmt.Errorf("invalid count")
+		}
+		req.Count = count
+	}
+	return req, nil
+}
+
+// setPageHeaders links a page to its neighbours with a Link header (rel next
+// and prev) and reports the total in X-Total-Count when it was requested.
+// The links repeat the request with the cursor swapped.
+func setPageHeaders(c *gin.Context, info pagination.Info) {
+	var links []string
+	for _, link := range []struct {
+		rel    string
+		cursor *pagination.Cursor
+	}{{"next", info.Next}, {"prev", info.Prev}} {
+		if link.cursor == nil {
+			continue
+		}
+		query := c.Request.URL.Query()
+		query.Del("page")
+		query.Set("cursor", link.cursor.Encode())
+		links = append(links, fmt.Sprintf("<%s?%s>; rel=%q", c.Request.URL.Path, query.Encode(), link.rel))
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+	if info.Total != nil {
+		c.Header("X-Total-Count", strconv.Itoa(*info.Total))
+	}
+}
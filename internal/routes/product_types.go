@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultProductTypeLimit = 100
+	maxProductTypeLimit     = 500
+)
+
+// Product_types_get lists the catalogue by code, paginated with cursors.
+func Product_types_get(c *gin.Context) {
+	page, err := pageRequest(c, defaultProductTypeLimit, maxProductTypeLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	productTypes, info, err := Services.ProductTypes.List(c.Query("all") == "true", middleware.CurrentRole(c), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, productTypes)
+}
+
+func Product_types_get_by_code(c *gin.Context) {
+	productType, err := Services.ProductTypes.Get(c.Param("code"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, productType)
+}
+
+// Product_types_put creates or replaces a catalogue entry. Types are never
+// deleted, since products keep referring to them; set active to false instead.
+// The storage period is only changed when storagePeriodDays is given.
+func Product_types_put(c *gin.Context) {
+	var req struct {
+		Names             map[string]string  `json:"names"`
+		SizeClass         string             `json:"sizeClass"`
+		Fragile           bool               `json:"fragile"`
+		Hazardous         bool               `json:"hazardous"`
+		Active            *bool              `json:"active"`
+		StoragePeriodDays *int               `json:"storagePeriodDays"`
+		MaxWeightGrams    int                `json:"maxWeightGrams"`
+		MaxDimensions     *models.Dimensions `json:"maxDimensions"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	saved, err := Services.ProductTypes.Put(models.ProductType{
+		Code:           c.Param("code"),
+		Names:          req.Names,
+		SizeClass:      req.SizeClass,
+		Fragile:        req.Fragile,
+		Hazardous:      req.Hazardous,
+		Active:         req.Active == nil || *req.Active,
+		MaxWeightGrams: req.MaxWeightGrams,
+		MaxDimensions:  req.MaxDimensions,
+	}, req.StoragePeriodDays)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// pvzQueryParams lists the query parameters GET /pvz understands; true marks
+// the ones that may be repeated.
+var pvzQueryParams = map[string]bool{
+	"startDate":        false,
+	"endDate":          false,
+	"city":             true,
+	"status":           false,
+	"hasOpenReception": false,
+	"productType":      true,
+	"receptionFrom":    false,
+	"receptionTo":      false,
+	"sort":             false,
+	"order":            false,
+	"limit":            false,
+	"cursor":           false,
+	"page":             false,
+	"count":            false,
+}
+
+var pvzStatuses = map[string]bool{models.ReceptionStatusInProgress: true, models.ReceptionStatusClosed: true, "none": true}
+
+// parsePVZFilter validates the filtering and sorting parameters of GET /pvz.
+// Unknown parameters, repeated single-value parameters and malformed values
+// are rejected rather than ignored.
+func parsePVZFilter(c *gin.Context) (db.PVZFilter, error) {
+	var filter db.PVZFilter
+	query := c.Request.URL.Query()
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		repeatable, known := pvzQueryParams[name]
+		if !known {
+			return filter, fmt.Errorf("unknown query parameter %q", name)
+		}
+		if !repeatable && len(query[name]) > 1 {
+			return filter, fmt.Errorf("%s must be given once", name)
+		}
+	}
+
+	var err error
+	if filter.RegisteredFrom, filter.RegisteredTo, err = dateRange(query.Get("startDate"), query.Get("endDate"), "startDate", "endDate"); err != nil {
+		return filter, err
+	}
+	if filter.ReceptionFrom, filter.ReceptionTo, err = dateRange(query.Get("receptionFrom"), query.Get("receptionTo"), "receptionFrom", "receptionTo"); err != nil {
+		return filter, err
+	}
+
+	for _, city := range query["city"] {
+		city = strings.TrimSpace(city)
+		if city == "" {
+			return filter, fmt.Errorf("city must not be empty")
+		}
+		filter.Cities = append(filter.Cities, city)
+	}
+
+	if status := query.Get("status"); status != "" {
+		if !pvzStatuses[status] {
+			return filter, fmt.Errorf("status must be in_progress, close or none")
+		}
+		filter.Status = status
+	}
+
+	if value := query.Get("hasOpenReception"); value != "" {
+		open, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("hasOpenReception must be true or false")
+		}
+		filter.HasOpenReception = &open
+	}
+
+	for _, code := range query["productType"] {
+		if _, err := db.GetProductType(code); err != nil {
+			return filter, fmt.Errorf("unknown product type %q", code)
+		}
+		filter.ProductTypes = append(filter.ProductTypes, code)
+	}
+
+	filter.Sort = db.PVZSortRegistrationDate
+	if value := query.Get("sort"); value != "" {
+		switch value {
+		case db.PVZSortRegistrationDate, db.PVZSortCity, db.PVZSortActivity:
+			filter.Sort = value
+		default:
+			return filter, fmt.Errorf("sort must be registrationDate, city or activity")
+		}
+	}
+	// Cities read best alphabetically, dates newest first
+	filter.Ascending = filter.Sort == db.PVZSortCity
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		filter.Ascending = true
+	case "desc":
+		filter.Ascending = false
+	default:
+		return filter, fmt.Errorf("order must be asc or desc")
+	}
+	return filter, nil
+}
+
+// dateRange parses an inclusive range of RFC3339 timestamps or plain dates.
+// A plain end date covers the whole day. The bounds are returned as UTC
+// RFC3339 timestamps.
+func dateRange(from, to, fromName, toName string) (string, string, error) {
+	var start, end time.Time
+	var err error
+	if from != "" {
+		if start, err = parseDateBound(from, false); err != nil {
+			return "", "", fmt.Errorf("%s must be an RFC3339 timestamp or a YYYY-MM-DD date", fromName)
+		}
+	}
+	if to != "" {
+		if end, err = parseDateBound(to, true); err != nil {
+			return "", "", fmt.Errorf("%s must be an RFC3339 timestamp or a YYYY-MM-DD date", toName)
+		}
+	}
+	if from != "" && to != "" && start.After(end) {
+		return "", "", fmt.Errorf("%s must not be after %s", fromName, toName)
+	}
+
+	var startBound, endBound string
+	if from != "" {
+		startBound = start.UTC().Format(time.RFC3339)
+	}
+	if to != "" {
+		endBound = end.UTC().Format(time.RFC3339)
+	}
+	return startBound, endBound, nil
+}
+
+func parseDateBound(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		return day.Add(24*time.Hour - time.Second), nil
+	}
+	return day, nil
+}
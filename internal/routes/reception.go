@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultReceptionProductsLimit = 50
+	maxReceptionProductsLimit     = 500
+	defaultAuditLimit             = 100
+	maxAuditLimit                 = 500
+)
+
+// Receptions_products_get lists the products of a reception newest first,
+// paginated with cursors like GET /pvz.
+func Receptions_products_get(c *gin.Context) {
+	page, err := pageRequest(c, defaultReceptionProductsLimit, maxReceptionProductsLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	reception, err := db.GetReceptionByID(c.Param("receptionId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	products, info, err := db.GetReceptionProducts(reception.ID, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, products)
+}
+
+// Receptions_get returns a reception with its PVZ, per-type counts and one page
+// of its products.
+func Receptions_get(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		badRequest(c, "page must be a positive number")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultReceptionProductsLimit)))
+	if err != nil || limit < 1 || limit > maxReceptionProductsLimit {
+		badRequest(c, fmt.Sprintf("limit must be between 1 and %d", maxReceptionProductsLimit))
+		return
+	}
+
+	reception, err := db.GetReceptionByID(c.Param("receptionId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	pvz, err := db.GetPVZByID(reception.PvzId)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	counts, err := db.GetReceptionProductCounts(reception.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	weightGrams, volumeCm3, err := db.GetReceptionTotals(reception.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	incidents, err := db.GetIncidents(db.IncidentFilter{ReceptionId: reception.ID})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	products, _, err := db.GetReceptionProducts(reception.ID, pagination.Request{Limit: limit, Offset: (page - 1) * limit})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	end := time.Now()
+	if reception.ClosedAt != nil {
+		end = *reception.ClosedAt
+	}
+	details := models.ReceptionDetails{
+		Reception:        *reception,
+		Pvz:              pvz,
+		DurationSeconds:  int64(end.Sub(reception.DateTime) / time.Second),
+		CountsByType:     counts,
+		TotalWeightGrams: weightGrams,
+		TotalVolumeCm3:   volumeCm3,
+		Incidents:        incidents,
+		Products:         products,
+		Page:             page,
+		Limit:            limit,
+	}
+	for _, count := range counts {
+		details.ProductCount += count
+	}
+	for _, incident := range incidents {
+		if incident.Status == models.IncidentStatusOpen {
+			details.OpenIncidents++
+		}
+	}
+	c.JSON(http.StatusOK, details)
+}
+
+func Receptions_delete_product(c *gin.Context) {
+	err := db.DeleteProductFromReception(c.Param("receptionId"), c.Param("productId"), middleware.CurrentUser(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
+}
+
+// Receptions_audit lists the changes to a reception oldest first, paginated
+// with cursors.
+func Receptions_audit(c *gin.Context) {
+	page, err := pageRequest(c, defaultAuditLimit, maxAuditLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	entries, info, err := db.GetAuditLogPage("reception", c.Param("receptionId"), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, entries)
+}
@@ -3,35 +3,28 @@ package routes
 import (
 	"fmt"
 	"net/http"
-	"regexp"
 	"strconv"
-	"time"
 
-	"github.com/StepOne-ai/pvz_avito/internal/db"
 	"github.com/StepOne-ai/pvz_avito/internal/middleware"
 	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
+// Services holds the business rules the handlers delegate to.
+var Services = service.New()
+
 func DummyLogin(c *gin.Context) {
 	var req struct {
 		Role string `json:"role"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	user := models.User{
-		ID:       "123",
-		Email:    "dummy@mail.ru",
-		Role:     "dummy",
-		Password: "dummy_password",
-	}
-
-	tokenString, err := middleware.GenerateToken(user)
+	tokenString, user, err := Services.Auth.DummyLogin()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: "Failed to generate token"})
+		respondError(c, err)
 		return
 	}
 	c.SetCookie("token", tokenString, 3600, "/", "localhost", false, true)
@@ -46,28 +39,16 @@ func Register(c *gin.Context) {
 		Password string `json:"password"`
 		Role     string `json:"role"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request"})
-		return
-	}
-
-	if !isValidEmail(req.Email) {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid email"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	id := fmt.Sprintf("user-%d", time.Now().Unix())
-	err := db.CreateUser(id, req.Email, req.Password, req.Role)
+	user, err := Services.Auth.Register(req.Email, req.Password, req.Role)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, models.User{ID: id, Email: req.Email, Role: req.Role, Password: req.Password})
-}
-
-func isValidEmail(email string) bool {
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-	return emailRegex.MatchString(email)
+	c.JSON(http.StatusCreated, user)
 }
 
 func Login(c *gin.Context) {
@@ -75,24 +56,13 @@ func Login(c *gin.Context) {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	if db.CheckCredentials(req.Email, req.Password) != nil {
-		c.JSON(http.StatusUnauthorized, models.Error{Message: "Invalid credentials"})
-		return
-	}
-	user, err := db.GetUserByEmail(req.Email)
+	tokenString, user, err := Services.Auth.Login(req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "User does not exist"})
-		return
-	}
-
-	tokenString, err := middleware.GenerateToken(user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: "Failed to generate token"})
+		respondError(c, err)
 		return
 	}
 	c.SetCookie("token", tokenString, 3600, "/", "localhost", false, true)
@@ -103,60 +73,151 @@ func Login(c *gin.Context) {
 
 func PVZ_post(c *gin.Context) {
 	var req struct {
-		City string `json:"city"`
+		City         string                   `json:"city"`
+		Address      string                   `json:"address"`
+		Latitude     *float64                 `json:"latitude"`
+		Longitude    *float64                 `json:"longitude"`
+		Phone        string                   `json:"phone"`
+		WorkingHours []models.WorkingHours    `json:"workingHours"`
+		Holidays     []models.HolidayOverride `json:"holidays"`
+	}
+	if !bindJSON(c, &req) {
+		return
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request"})
+
+	pvz, err := Services.PVZ.Create(service.NewPVZ(req))
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-	// City check
-	if !(req.City == "Москва" || req.City == "Казань" || req.City == "Санкт-Петербург") {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid city"})
+	c.JSON(http.StatusCreated, pvz)
+}
+
+func PVZ_get_by_id(c *gin.Context) {
+	pvz, err := Services.PVZ.Get(c.Param("pvzId"))
+	if err != nil {
+		respondError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, pvz)
+}
 
-	var pvz models.PVZ
-	pvz.ID = fmt.Sprintf("pvz-%d", time.Now().Unix())
-	pvz.RegistrationDate = time.Now()
-	pvz.City = req.City
+func PVZ_working_hours(c *gin.Context) {
+	var req struct {
+		WorkingHours []models.WorkingHours    `json:"workingHours"`
+		Holidays     []models.HolidayOverride `json:"holidays"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
 
-	err := db.CreatePVZ(pvz.ID, pvz.City, pvz.RegistrationDate.Format(time.RFC3339))
+	pvz, err := Services.PVZ.SetSchedule(c.Param("pvzId"), req.WorkingHours, req.Holidays)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, req)
+	c.JSON(http.StatusOK, pvz)
 }
 
+const (
+	defaultPVZLimit = 10
+	maxPVZLimit     = 100
+)
+
+// PVZ_get lists PVZs, newest first unless sort and order say otherwise. See
+// parsePVZFilter for the filters. Pages are linked through the Link header;
+// pass count=true to get the total in X-Total-Count.
 func PVZ_get(c *gin.Context) {
-	startDate := c.Query("startDate")
-	endDate := c.Query("endDate")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	filter, err := parsePVZFilter(c)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	page, err := pageRequest(c, defaultPVZLimit, maxPVZLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
 
-	pvzs, err := db.GetPVZsFiltered(startDate, endDate, page, limit)
+	pvzs, info, err := Services.PVZ.List(filter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Error{Message: err.Error()})
+		respondError(c, err)
 		return
 	}
+	setPageHeaders(c, info)
 	c.JSON(http.StatusOK, pvzs)
 }
 
+const (
+	defaultNearbyRadius = 5000.0
+	maxNearbyRadius     = 50000.0
+	defaultNearbyLimit  = 20
+	maxNearbyLimit      = 100
+)
+
+func PVZ_nearby(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, errLon := strconv.ParseFloat(c.Query("lon"), 64)
+	if errLat != nil || errLon != nil {
+		badRequest(c, "Invalid lat or lon")
+		return
+	}
+
+	radius := defaultNearbyRadius
+	if value := c.Query("radius"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 || parsed > maxNearbyRadius {
+			badRequest(c, fmt.Sprintf("radius must be between 0 and %.0f meters", maxNearbyRadius))
+			return
+		}
+		radius = parsed
+	}
+
+	limit := defaultNearbyLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxNearbyLimit {
+			badRequest(c, fmt.Sprintf("limit must be between 1 and %d", maxNearbyLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	openNow := false
+	if value := c.Query("openNow"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			badRequest(c, "Invalid openNow")
+			return
+		}
+		openNow = parsed
+	}
+
+	nearby, err := Services.PVZ.Nearby(lat, lon, radius, limit, openNow)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, nearby)
+}
+
 func PVZ_close_last_reception(c *gin.Context) {
-	pvzId := c.Param("pvzId")
-	err := db.CloseLastReception(pvzId)
+	report, err := Services.PVZ.CloseLastReception(c.Param("pvzId"), middleware.CurrentUser(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Reception closed"})
+
+	response := gin.H{"message": "Reception closed"}
+	if report != nil {
+		response["reconciliation"] = report
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func PVZ_delete_last_product(c *gin.Context) {
-	pvzId := c.Param("pvzId")
-	err := db.DeleteLastProduct(pvzId)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+	if err := Services.PVZ.DeleteLastProduct(c.Param("pvzId")); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
@@ -164,21 +225,21 @@ func PVZ_delete_last_product(c *gin.Context) {
 
 func Receptions(c *gin.Context) {
 	var req struct {
-		PvzId string `json:"pvzId"`
+		PvzId    string `json:"pvzId"`
+		Override bool   `json:"override"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request"})
+	if !bindJSON(c, &req) {
 		return
 	}
-	reception := models.Reception{
-		ID:       fmt.Sprintf("reception-%d", time.Now().Unix()),
-		DateTime: time.Now(),
+
+	reception, err := Services.Receptions.Open(service.NewReception{
 		PvzId:    req.PvzId,
-		Status:   "in_progress",
-	}
-	err := db.CreateReception(reception.ID, reception.DateTime.Format(time.RFC3339), reception.PvzId, reception.Status)
+		Override: req.Override,
+		Actor:    middleware.CurrentUser(c),
+		Role:     middleware.CurrentRole(c),
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, reception)
@@ -186,25 +247,34 @@ func Receptions(c *gin.Context) {
 
 func Products(c *gin.Context) {
 	var req struct {
-		Type        string `json:"type"`
-		PvzId       string `json:"pvzId"`
-		ReceptionId string `json:"receptionId"`
+		Type        string             `json:"type"`
+		PvzId       string             `json:"pvzId"`
+		ReceptionId string             `json:"receptionId"`
+		CellId      string             `json:"cellId"`
+		Barcode     string             `json:"barcode"`
+		OrderId     string             `json:"orderId"`
+		WeightGrams int                `json:"weightGrams"`
+		Dimensions  *models.Dimensions `json:"dimensions"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: "Invalid request"})
+	if !bindJSON(c, &req) {
 		return
 	}
-	product := models.Product{
-		ID:          fmt.Sprintf("product-%d", time.Now().Unix()),
-		DateTime:    time.Now(),
-		Type:        req.Type,
-		ReceptionId: req.ReceptionId,
-	}
 
-	err := db.CreateProduct(product.ID, product.DateTime.Format(time.RFC3339), product.Type, product.ReceptionId)
+	// Without an explicit reception the product goes to the PVZ's active one
+	product, warning, err := Services.Products.Add(req.PvzId, req.ReceptionId, service.NewProduct{
+		Type:        req.Type,
+		CellId:      req.CellId,
+		Barcode:     req.Barcode,
+		OrderId:     req.OrderId,
+		WeightGrams: req.WeightGrams,
+		Dimensions:  req.Dimensions,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.Error{Message: err.Error()})
+		respondError(c, err)
 		return
 	}
+	if warning != "" {
+		c.Header("X-Capacity-Warning", warning)
+	}
 	c.JSON(http.StatusCreated, product)
 }
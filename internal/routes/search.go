@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Search answers questions like "where is parcel X" or "which PVZ on
+// Tverskaya". kind narrows the search to a comma-separated list of kinds.
+func Search(c *gin.Context) {
+	limit := defaultSearchLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			badRequest(c, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	var kinds []string
+	if value := c.Query("kind"); value != "" {
+		kinds = strings.Split(value, ",")
+	}
+
+	results, err := Services.Search.Search(c.Query("q"), kinds, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -5,40 +5,378 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// SetupRoutes registers the API. Every route is described in API, which
+// validates requests after authentication and before they reach the
+// handlers, so callers without access get 401 or 403 rather than details of
+// the schema. Failures are answered with problem documents carrying the
+// request ID.
 func SetupRoutes(r *gin.Engine) {
-	r.POST("/dummyLogin", DummyLogin)
+	r.Use(middleware.RequestID())
+	validate := API.RequestValidator()
+	r.NoRoute(func(c *gin.Context) { respondError(c, errRouteNotFound) })
 
-	r.POST("/register", Register)
+	r.GET("/openapi.json", OpenAPI_get)
 
-	r.POST("/login", Login)
+	r.POST("/dummyLogin", validate, DummyLogin)
+
+	r.POST("/register", validate, Register)
+
+	r.POST("/login", validate, Login)
 
 	r.POST("/pvz",
 		middleware.JWTMiddleware(),
 		middleware.RoleMiddleware("PVZemployee"),
+		validate,
 		PVZ_post)
 
 	r.GET("/pvz",
 		middleware.JWTMiddleware(),
 		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
 		PVZ_get)
 
+	// Public: backs the customer-facing map
+	r.GET("/pvz/nearby", validate, PVZ_nearby)
+
+	r.GET("/pvz/:pvzId",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		PVZ_get_by_id)
+
+	r.PUT("/pvz/:pvzId/working_hours",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		PVZ_working_hours)
+
+	r.PUT("/pvz/:pvzId/capacity",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		PVZ_capacity)
+
+	r.GET("/pvz/:pvzId/occupancy",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		PVZ_occupancy)
+
+	r.POST("/pvz/:pvzId/cells",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		PVZ_cells_post)
+
+	r.GET("/pvz/:pvzId/cells",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		PVZ_cells_get)
+
 	r.POST("/pvz/:pvzId/close_last_reception",
 		middleware.JWTMiddleware(),
 		middleware.RoleMiddleware("PVZemployee"),
+		validate,
 		PVZ_close_last_reception)
 
 	r.POST("/pvz/:pvzId/delete_last_product",
 		middleware.JWTMiddleware(),
 		middleware.RoleMiddleware("PVZemployee"),
+		validate,
 		PVZ_delete_last_product)
 
 	r.POST("/receptions",
 		middleware.JWTMiddleware(),
-		middleware.RoleMiddleware("PVZemployee"),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
 		Receptions)
 
+	r.GET("/receptions/:receptionId",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_get)
+
+	r.GET("/receptions/:receptionId/products",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_products_get)
+
+	r.POST("/receptions/:receptionId/products:action",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Receptions_products_batch)
+
+	r.DELETE("/receptions/:receptionId/products/:productId",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Receptions_delete_product)
+
+	r.GET("/receptions/stale",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		Receptions_stale)
+
+	r.PUT("/pvz/:pvzId/reception_timeout",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		PVZ_reception_timeout)
+
+	r.POST("/manifests/import",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Manifests_import)
+
+	r.PUT("/receptions/:receptionId/manifest",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_manifest_put)
+
+	r.GET("/receptions/:receptionId/manifest",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_manifest_get)
+
+	r.GET("/receptions/:receptionId/reconciliation",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_reconciliation)
+
+	r.POST("/receptions/:receptionId/attachments",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_attachments_post)
+
+	r.GET("/receptions/:receptionId/attachments",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_attachments_get)
+
+	r.POST("/receptions/:receptionId/incidents",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_incidents_post)
+
+	r.GET("/receptions/:receptionId/incidents",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Receptions_incidents_get)
+
+	r.GET("/receptions/:receptionId/audit",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		Receptions_audit)
+
 	r.POST("/products",
 		middleware.JWTMiddleware(),
 		middleware.RoleMiddleware("PVZemployee"),
+		validate,
 		Products)
+
+	r.GET("/products/by-barcode/:code",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Products_by_barcode)
+
+	r.GET("/products/:productId/cell",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Products_cell_get)
+
+	r.PUT("/products/:productId/cell",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Products_cell_put)
+
+	r.POST("/products/:productId/return",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Products_return)
+
+	r.POST("/products/:productId/lost",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Products_lost)
+
+	r.GET("/products/:productId/history",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Products_history)
+
+	r.POST("/products/:productId/attachments",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Products_attachments_post)
+
+	r.GET("/products/:productId/attachments",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Products_attachments_get)
+
+	r.POST("/products/:productId/incidents",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Products_incidents_post)
+
+	r.GET("/incidents",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		Incidents_get)
+
+	r.GET("/incidents/:incidentId",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Incidents_get_by_id)
+
+	r.POST("/incidents/:incidentId/resolve",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		Incidents_resolve)
+
+	r.GET("/attachments/:attachmentId",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Attachments_get)
+
+	r.GET("/attachments/:attachmentId/thumbnail",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Attachments_thumbnail)
+
+	r.GET("/search",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Search)
+
+	r.POST("/orders",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Orders_post)
+
+	r.GET("/orders/by-number/:number",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Orders_by_number)
+
+	r.GET("/orders/:orderId",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Orders_get)
+
+	r.POST("/orders/:orderId/pickup_code",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Orders_pickup_code)
+
+	r.POST("/orders/:orderId/issue",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Orders_issue)
+
+	r.POST("/transfers",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Transfers_post)
+
+	r.GET("/transfers/:transferId",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Transfers_get)
+
+	r.POST("/transfers/:transferId/in_transit",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Transfers_in_transit)
+
+	r.POST("/transfers/:transferId/receive",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Transfers_receive)
+
+	r.PUT("/storage_periods/:type",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		Storage_periods_put)
+
+	r.GET("/storage_periods",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Storage_periods_get)
+
+	r.GET("/pvz/:pvzId/expiring",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		PVZ_expiring)
+
+	r.GET("/pvz/:pvzId/return_batches",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		PVZ_return_batches)
+
+	r.POST("/return_batches/:batchId/dispatch",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee"),
+		validate,
+		Return_batches_dispatch)
+
+	r.GET("/product_types",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Product_types_get)
+
+	r.GET("/product_types/:code",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("PVZemployee", "Moderator"),
+		validate,
+		Product_types_get_by_code)
+
+	r.PUT("/product_types/:code",
+		middleware.JWTMiddleware(),
+		middleware.RoleMiddleware("Moderator"),
+		validate,
+		Product_types_put)
 }
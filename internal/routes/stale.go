@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxReceptionTimeoutMinutes limits a per-PVZ reception timeout to a week.
+	maxReceptionTimeoutMinutes = 7 * 24 * 60
+	defaultStaleLimit          = 50
+	maxStaleLimit              = 500
+)
+
+// StaleReceptionTimeout and StaleReceptionAction are the defaults the stale
+// reception job runs with; the listing below reports against the same values.
+var (
+	StaleReceptionTimeout = models.DefaultReceptionTimeout
+	StaleReceptionAction  = models.StaleActionClose
+)
+
+func PVZ_reception_timeout(c *gin.Context) {
+	var req models.ReceptionTimeout
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Minutes < 0 || req.Minutes > maxReceptionTimeoutMinutes {
+		badRequest(c, "minutes must be between 0 and 10080")
+		return
+	}
+	if req.Action != "" && req.Action != models.StaleActionClose && req.Action != models.StaleActionFlag {
+		badRequest(c, "action must be close or flag")
+		return
+	}
+
+	if err := db.SetReceptionTimeout(c.Param("pvzId"), req); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// Receptions_stale is a dry run of the stale reception job: it lists the
+// receptions the job would close or flag right now without touching them,
+// oldest first and paginated with cursors.
+func Receptions_stale(c *gin.Context) {
+	page, err := pageRequest(c, defaultStaleLimit, maxStaleLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	stale, info, err := db.GetStaleReceptionsPage(time.Now(), StaleReceptionTimeout, StaleReceptionAction, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, stale)
+}
@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStorageListLimit = 50
+	maxStorageListLimit     = 200
+)
+
+func Storage_periods_put(c *gin.Context) {
+	var req struct {
+		Days int `json:"days"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	period, err := Services.Storage.SetPeriod(c.Param("type"), req.Days)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, period)
+}
+
+func Storage_periods_get(c *gin.Context) {
+	periods, err := Services.Storage.Periods()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"defaultDays": models.DefaultStoragePeriodDays, "periods": periods})
+}
+
+// PVZ_expiring lists products whose storage deadline is within the next `days` days,
+// including those that are already overdue.
+func PVZ_expiring(c *gin.Context) {
+	days := 1
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			badRequest(c, "Invalid days")
+			return
+		}
+		days = parsed
+	}
+	page, err := pageRequest(c, defaultStorageListLimit, maxStorageListLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	products, info, err := Services.Storage.Expiring(c.Param("pvzId"), days, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, products)
+}
+
+func PVZ_return_batches(c *gin.Context) {
+	page, err := pageRequest(c, defaultStorageListLimit, maxStorageListLimit)
+	if err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	batches, info, err := Services.Storage.ReturnBatches(c.Param("pvzId"), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setPageHeaders(c, info)
+	c.JSON(http.StatusOK, batches)
+}
+
+func Return_batches_dispatch(c *gin.Context) {
+	batch, err := Services.Storage.DispatchReturnBatch(c.Param("batchId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func Transfers_post(c *gin.Context) {
+	var req struct {
+		SourcePvzId      string   `json:"sourcePvzId"`
+		DestinationPvzId string   `json:"destinationPvzId"`
+		ProductIds       []string `json:"productIds"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	transfer, err := Services.Transfers.Create(service.NewTransfer{
+		SourcePvzId:      req.SourcePvzId,
+		DestinationPvzId: req.DestinationPvzId,
+		ProductIds:       req.ProductIds,
+		Actor:            middleware.CurrentUser(c),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, transfer)
+}
+
+func Transfers_get(c *gin.Context) {
+	transfer, err := Services.Transfers.Get(c.Param("transferId"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, transfer)
+}
+
+func Transfers_in_transit(c *gin.Context) {
+	transfer, err := Services.Transfers.MarkInTransit(c.Param("transferId"), middleware.CurrentUser(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, transfer)
+}
+
+func Transfers_receive(c *gin.Context) {
+	var req struct {
+		ProductIds []string `json:"productIds"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	transfer, discrepancy, err := Services.Transfers.Receive(c.Param("transferId"), req.ProductIds, middleware.CurrentUser(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"transfer": transfer, "discrepancy": discrepancy})
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a unit of background work run periodically by the Scheduler.
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type entry struct {
+	job      Job
+	interval time.Duration
+}
+
+// Scheduler runs registered jobs at fixed intervals until its context is cancelled.
+type Scheduler struct {
+	logger  *logrus.Logger
+	entries []entry
+	wg      sync.WaitGroup
+}
+
+func New(logger *logrus.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Register adds a job to run every interval. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job, interval time.Duration) {
+	s.entries = append(s.entries, entry{job: job, interval: interval})
+}
+
+// Start launches every registered job in its own goroutine. Each job runs once
+// immediately and then on every tick.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+}
+
+// Wait blocks until all job goroutines have stopped.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, e.job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	start := time.Now()
+	err := job.Run(ctx)
+	if s.logger == nil {
+		return
+	}
+
+	fields := logrus.Fields{"job": job.Name(), "duration": time.Since(start).String()}
+	if err != nil {
+		s.logger.WithFields(fields).WithError(err).Error("Background job failed")
+		return
+	}
+	s.logger.WithFields(fields).Debug("Background job finished")
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/blob"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+	"github.com/StepOne-ai/pvz_avito/internal/thumbnail"
+)
+
+// ErrNoBlobStore reports that attachments cannot be stored or served because
+// no blob store is configured.
+var ErrNoBlobStore = apperr.New(apperr.KindUnavailable, "storage_unavailable", "attachment storage is not configured")
+
+const (
+	MaxAttachmentBytes      = 10 << 20
+	MaxAttachmentsPerUpload = 10
+	MaxAttachmentComment    = 1000
+	thumbnailSide           = 256
+)
+
+// attachmentExtensions lists the accepted image types. The type is sniffed
+// from the file content; the Content-Type sent by the client is ignored.
+var attachmentExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// AttachmentService keeps photos of products and receptions in a blob store.
+// The store is passed to every call because it is configured after start-up.
+type AttachmentService struct {
+	now func() time.Time
+}
+
+// NewAttachments is an upload of one or more files to a product or reception.
+type NewAttachments struct {
+	EntityType string
+	EntityId   string
+	Kind       string
+	Comment    string
+	Actor      string
+	Files      []*multipart.FileHeader
+}
+
+// attachmentUpload is a validated file waiting to be written to the blob store.
+type attachmentUpload struct {
+	attachment models.Attachment
+	data       []byte
+	thumbnail  []byte
+}
+
+// AttachmentContent is an attachment file read from the blob store. Size is
+// -1 when it is not known. The caller closes Body.
+type AttachmentContent struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	FileName    string
+}
+
+// Upload stores the files and their thumbnails. A missing kind means photo.
+// Every file is checked before anything is stored, so an upload is accepted
+// or rejected as a whole.
+func (s *AttachmentService) Upload(ctx context.Context, store blob.Store, in NewAttachments) ([]models.Attachment, error) {
+	if err := s.checkEntity(in.EntityType, in.EntityId); err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, ErrNoBlobStore
+	}
+	if len(in.Files) == 0 {
+		return nil, invalidRequest("at least one file is required")
+	}
+	if len(in.Files) > MaxAttachmentsPerUpload {
+		return nil, invalidRequest("at most %d files can be uploaded at once", MaxAttachmentsPerUpload)
+	}
+	if in.Kind == "" {
+		in.Kind = models.AttachmentPhoto
+	}
+	if in.Kind != models.AttachmentPhoto && in.Kind != models.AttachmentDamage {
+		return nil, invalidRequest("kind must be photo or damage")
+	}
+	if len([]rune(in.Comment)) > MaxAttachmentComment {
+		return nil, invalidRequest("comment must be at most %d characters", MaxAttachmentComment)
+	}
+
+	now := s.now()
+	uploads := make([]attachmentUpload, 0, len(in.Files))
+	for _, file := range in.Files {
+		upload, err := prepareAttachment(file)
+		if err != nil {
+			return nil, err.Withf("%s: %s", file.Filename, err.Message)
+		}
+		id := NewID("attachment")
+		upload.attachment.ID = id
+		upload.attachment.EntityType = in.EntityType
+		upload.attachment.EntityId = in.EntityId
+		upload.attachment.Kind = in.Kind
+		upload.attachment.Comment = in.Comment
+		upload.attachment.BlobKey = "attachments/" + id + attachmentExtensions[upload.attachment.ContentType]
+		upload.attachment.ThumbnailKey = "attachments/" + id + "_thumb.jpg"
+		upload.attachment.UploadedBy = in.Actor
+		upload.attachment.CreatedAt = now
+		uploads = append(uploads, upload)
+	}
+
+	var stored []string
+	cleanup := func() {
+		for _, key := range stored {
+			store.Delete(ctx, key)
+		}
+	}
+
+	attachments := make([]models.Attachment, 0, len(uploads))
+	for _, upload := range uploads {
+		a := upload.attachment
+		if err := store.Put(ctx, a.BlobKey, bytes.NewReader(upload.data), a.SizeBytes, a.ContentType); err != nil {
+			cleanup()
+			return nil, err
+		}
+		stored = append(stored, a.BlobKey)
+		if err := store.Put(ctx, a.ThumbnailKey, bytes.NewReader(upload.thumbnail), int64(len(upload.thumbnail)), "image/jpeg"); err != nil {
+			cleanup()
+			return nil, err
+		}
+		stored = append(stored, a.ThumbnailKey)
+		attachments = append(attachments, a)
+	}
+
+	if err := db.InsertAttachments(attachments); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// List returns one page of the attachments of an entity, oldest first.
+func (s *AttachmentService) List(entityType, entityId string, page pagination.Request) ([]models.Attachment, pagination.Info, error) {
+	if err := s.checkEntity(entityType, entityId); err != nil {
+		return nil, pagination.Info{}, err
+	}
+	return db.GetAttachments(entityType, entityId, page)
+}
+
+// Content opens an attachment file, or its JPEG thumbnail when thumb is set.
+func (s *AttachmentService) Content(ctx context.Context, store blob.Store, attachmentId string, thumb bool) (*AttachmentContent, error) {
+	if store == nil {
+		return nil, ErrNoBlobStore
+	}
+	attachment, err := db.GetAttachmentByID(attachmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	content := AttachmentContent{ContentType: attachment.ContentType, Size: attachment.SizeBytes, FileName: attachment.FileName}
+	key := attachment.BlobKey
+	if thumb {
+		key = attachment.ThumbnailKey
+		content = AttachmentContent{ContentType: "image/jpeg", Size: -1}
+	}
+	content.Body, err = store.Get(ctx, key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, apperr.NotFound("attachment_file_missing", "attachment file is missing")
+	} else if err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+func (s *AttachmentService) checkEntity(entityType, entityId string) error {
+	var err error
+	switch entityType {
+	case "product":
+		_, err = db.GetProductByID(entityId)
+	case "reception":
+		_, err = db.GetReceptionByID(entityId)
+	default:
+		err = invalidRequest("unknown attachment owner %s", entityType)
+	}
+	return err
+}
+
+// prepareAttachment reads an uploaded file, checks its size and type and
+// renders its thumbnail.
+func prepareAttachment(file *multipart.FileHeader) (attachmentUpload, *apperr.Error) {
+	fileTooLarge := apperr.New(apperr.KindTooLarge, "file_too_large", fmt.Sprintf("file must be at most %d MB", MaxAttachmentBytes>>20))
+	if file.Size > MaxAttachmentBytes {
+		return attachmentUpload{}, fileTooLarge
+	}
+	f, err := file.Open()
+	if err != nil {
+		return attachmentUpload{}, apperr.Invalid("unreadable_file", "failed to read file")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxAttachmentBytes+1))
+	if err != nil {
+		return attachmentUpload{}, apperr.Invalid("unreadable_file", "failed to read file")
+	}
+	if len(data) > MaxAttachmentBytes {
+		return attachmentUpload{}, fileTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := attachmentExtensions[contentType]; !ok {
+		return attachmentUpload{}, apperr.New(apperr.KindUnsupported, "unsupported_file_type", "only JPEG, PNG and GIF images are accepted")
+	}
+	thumb, width, height, err := thumbnail.Make(data, thumbnailSide)
+	if errors.Is(err, thumbnail.ErrTooManyPixels) {
+		return attachmentUpload{}, apperr.New(apperr.KindTooLarge, "image_too_large", err.Error())
+	} else if err != nil {
+		return attachmentUpload{}, apperr.New(apperr.KindUnsupported, "invalid_image", "file is not a valid image")
+	}
+
+	return attachmentUpload{
+		attachment: models.Attachment{
+			FileName:    attachmentFileName(file.Filename),
+			ContentType: contentType,
+			SizeBytes:   int64(len(data)),
+			Width:       width,
+			Height:      height,
+		},
+		data:      data,
+		thumbnail: thumb,
+	}, nil
+}
+
+// attachmentFileName keeps only the base name the client sent, for display.
+func attachmentFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/token"
+)
+
+// AuthService registers users and issues their tokens.
+type AuthService struct{}
+
+func (s *AuthService) Register(email, password, role string) (*models.User, error) {
+	if !IsValidEmail(email) {
+		return nil, apperr.Invalid("invalid_email", "Invalid email")
+	}
+
+	user := models.User{ID: NewID("user"), Email: email, Role: role, Password: password}
+	if err := db.CreateUser(user.ID, user.Email, user.Password, user.Role); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Login checks the credentials and returns a token for the user.
+func (s *AuthService) Login(email, password string) (string, *models.User, error) {
+	if err := db.CheckCredentials(email, password); err != nil {
+		return "", nil, err
+	}
+	user, err := db.GetUserByEmail(email)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tokenString, err := token.Generate(user)
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenString, &user, nil
+}
+
+// DummyLogin returns a token for a fixed test user without checking anything.
+func (s *AuthService) DummyLogin() (string, *models.User, error) {
+	user := models.User{
+		ID:       "123",
+		Email:    "dummy@mail.ru",
+		Role:     "dummy",
+		Password: "dummy_password",
+	}
+
+	tokenString, err := token.Generate(user)
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenString, &user, nil
+}
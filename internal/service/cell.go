@@ -0,0 +1,80 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+// CellService manages the storage cells of PVZs and where products are kept.
+type CellService struct{}
+
+// NewCell describes a storage cell by its address. A zero Capacity means
+// the cell is unlimited.
+type NewCell struct {
+	Zone     string
+	Rack     string
+	Shelf    string
+	Capacity int
+}
+
+func (s *CellService) Create(pvzId string, in NewCell) (*models.StorageCell, error) {
+	in.Zone = strings.TrimSpace(in.Zone)
+	in.Rack = strings.TrimSpace(in.Rack)
+	in.Shelf = strings.TrimSpace(in.Shelf)
+	if in.Zone == "" || in.Rack == "" || in.Shelf == "" {
+		return nil, invalidRequest("zone, rack and shelf are required")
+	}
+	if in.Capacity < 0 {
+		return nil, invalidRequest("capacity must not be negative")
+	}
+	if _, err := db.GetPVZByID(pvzId); err != nil {
+		return nil, err
+	}
+
+	cell := models.StorageCell{
+		ID:       NewID("cell"),
+		PvzId:    pvzId,
+		Zone:     in.Zone,
+		Rack:     in.Rack,
+		Shelf:    in.Shelf,
+		Capacity: in.Capacity,
+	}
+	if err := db.CreateStorageCell(cell); err != nil {
+		return nil, err
+	}
+	return &cell, nil
+}
+
+func (s *CellService) List(pvzId string, page pagination.Request) ([]models.StorageCell, pagination.Info, error) {
+	return db.GetStorageCellsPage(pvzId, page)
+}
+
+// Location tells where a product is kept.
+func (s *CellService) Location(productId string) (*models.ProductLocation, error) {
+	return db.GetProductLocation(productId)
+}
+
+// Move puts a product into another cell of its PVZ, which must have room.
+func (s *CellService) Move(productId, cellId string) (*models.ProductLocation, error) {
+	if cellId == "" {
+		return nil, invalidRequest("cellId is required")
+	}
+	location, err := db.GetProductLocation(productId)
+	if err != nil {
+		return nil, err
+	}
+	if location.Cell != nil && location.Cell.ID == cellId {
+		return location, nil
+	}
+
+	if _, err := ResolveCell(location.PvzId, cellId); err != nil {
+		return nil, err
+	}
+	if err := db.SetProductCell(productId, cellId); err != nil {
+		return nil, err
+	}
+	return db.GetProductLocation(productId)
+}
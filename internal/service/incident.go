@@ -0,0 +1,119 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+// MaxIncidentText bounds incident descriptions and resolutions in characters.
+const MaxIncidentText = 2000
+
+var incidentTypes = map[string]bool{
+	models.IncidentDamaged:     true,
+	models.IncidentWrongPvz:    true,
+	models.IncidentMissingSeal: true,
+	models.IncidentOther:       true,
+}
+
+// IncidentService records problems found with deliveries and their resolution.
+type IncidentService struct {
+	now func() time.Time
+}
+
+// NewIncident describes a problem reported by Actor, optionally about one product.
+type NewIncident struct {
+	Type        string
+	Description string
+	ProductId   string
+	Actor       string
+}
+
+// Report logs an incident against a reception. A product it names must have
+// arrived with that reception.
+func (s *IncidentService) Report(receptionId string, in NewIncident) (*models.Incident, error) {
+	reception, err := db.GetReceptionByID(receptionId)
+	if err != nil {
+		return nil, err
+	}
+	if in.ProductId != "" {
+		product, err := db.GetProductByID(in.ProductId)
+		if err != nil || product.ReceptionId != reception.ID {
+			return nil, invalidRequest("product does not belong to the reception")
+		}
+	}
+	return s.create(reception.ID, in)
+}
+
+// ReportProduct logs an incident against a product and the reception it
+// arrived with.
+func (s *IncidentService) ReportProduct(productId string, in NewIncident) (*models.Incident, error) {
+	product, err := db.GetProductByID(productId)
+	if err != nil {
+		return nil, err
+	}
+	in.ProductId = product.ID
+	return s.create(product.ReceptionId, in)
+}
+
+func (s *IncidentService) create(receptionId string, in NewIncident) (*models.Incident, error) {
+	if !incidentTypes[in.Type] {
+		return nil, invalidRequest("type must be damaged, wrong_pvz, missing_seal or other")
+	}
+	description := strings.TrimSpace(in.Description)
+	if len([]rune(description)) > MaxIncidentText {
+		return nil, invalidRequest("description must be at most %d characters", MaxIncidentText)
+	}
+	if in.Type == models.IncidentOther && description == "" {
+		return nil, invalidRequest("description is required for incidents of type other")
+	}
+
+	incident := models.Incident{
+		ID:          NewID("incident"),
+		ReceptionId: receptionId,
+		ProductId:   in.ProductId,
+		Type:        in.Type,
+		Description: description,
+		Status:      models.IncidentStatusOpen,
+		ReportedBy:  in.Actor,
+		ReportedAt:  s.now(),
+	}
+	if err := db.CreateIncident(incident); err != nil {
+		return nil, err
+	}
+	return db.GetIncidentByID(incident.ID)
+}
+
+// ListForReception lists the incidents of a reception, newest first.
+func (s *IncidentService) ListForReception(receptionId string, page pagination.Request) ([]models.Incident, pagination.Info, error) {
+	reception, err := db.GetReceptionByID(receptionId)
+	if err != nil {
+		return nil, pagination.Info{}, err
+	}
+	return db.GetIncidentsPage(db.IncidentFilter{ReceptionId: reception.ID}, page)
+}
+
+// List lists incidents across PVZs, newest first. An empty status or pvzId
+// does not filter.
+func (s *IncidentService) List(status, pvzId string, page pagination.Request) ([]models.Incident, pagination.Info, error) {
+	if status != "" && status != models.IncidentStatusOpen && status != models.IncidentStatusResolved {
+		return nil, pagination.Info{}, invalidRequest("status must be open or resolved")
+	}
+	return db.GetIncidentsPage(db.IncidentFilter{Status: status, PvzId: pvzId}, page)
+}
+
+func (s *IncidentService) Get(incidentId string) (*models.Incident, error) {
+	return db.GetIncidentByID(incidentId)
+}
+
+// Resolve closes an open incident with a note on how it was settled.
+func (s *IncidentService) Resolve(incidentId, actor, resolution string) (*models.Incident, error) {
+	resolution = strings.TrimSpace(resolution)
+	if resolution == "" || len([]rune(resolution)) > MaxIncidentText {
+		return nil, invalidRequest("resolution is required and must be at most %d characters", MaxIncidentText)
+	}
+	return db.ResolveIncident(incidentId, actor, resolution, s.now())
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/barcode"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/manifest"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// maxManifestItems limits the number of lines in a manifest.
+const maxManifestItems = 10000
+
+// ErrImportRejected reports a manifest file with row errors or without any
+// rows to import. The import result lists the errors.
+var ErrImportRejected = apperr.Invalid("import_rejected", "manifest import has errors")
+
+// ManifestService attaches supplier manifests to receptions.
+type ManifestService struct {
+	now func() time.Time
+}
+
+// Attach sets the expected items of a reception, replacing an earlier
+// manifest. A missing quantity means one item.
+func (s *ManifestService) Attach(receptionId, source, actor string, items []models.ManifestItem) (*models.Manifest, error) {
+	if source == "" {
+		source = "api"
+	}
+	items, err := normalizeManifestItems(items)
+	if err != nil {
+		return nil, invalidRequest("%v", err)
+	}
+
+	m := models.Manifest{
+		ReceptionId: receptionId,
+		Source:      source,
+		AttachedAt:  s.now(),
+		AttachedBy:  actor,
+		Items:       items,
+	}
+	if err := db.SetManifest(m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s *ManifestService) Get(receptionId string) (*models.Manifest, error) {
+	return db.GetManifest(receptionId)
+}
+
+func (s *ManifestService) Reconciliation(receptionId string) (*models.Reconciliation, error) {
+	return db.GetReconciliation(receptionId)
+}
+
+// ManifestFile is a supplier manifest file to import on behalf of Actor.
+// PvzId applies to rows that do not name a PVZ.
+type ManifestFile struct {
+	Format  string
+	Body    io.Reader
+	Mapping manifest.Mapping
+	PvzId   string
+	Source  string
+	Actor   string
+	DryRun  bool
+}
+
+// Import creates a pending reception with the expected items for every PVZ in
+// the file. With DryRun nothing is created. When any row is rejected nothing
+// is created either, and ErrImportRejected is returned with the result.
+func (s *ManifestService) Import(in ManifestFile) (*models.ManifestImport, error) {
+	rows, rowErrors, err := manifest.Parse(in.Format, in.Body, in.Mapping)
+	if err != nil {
+		return nil, invalidRequest("%v", err)
+	}
+	total := len(rows) + len(rowErrors)
+	rowErrors = append(rowErrors, manifest.Validate(rows, in.PvzId, isAcceptedProductType)...)
+
+	// Group the rows per PVZ in the order the PVZs first appear in the file
+	result := models.ManifestImport{DryRun: in.DryRun, Rows: total, Errors: []models.ImportRowError{}}
+	now := s.now()
+	var receptions []models.Reception
+	var manifests []models.Manifest
+	index := make(map[string]int)
+	for _, row := range rows {
+		if row.PvzId == "" {
+			continue
+		}
+		i, ok := index[row.PvzId]
+		if !ok {
+			if _, err := db.GetPVZByID(row.PvzId); err != nil {
+				rowErrors = append(rowErrors, models.ImportRowError{Line: row.Line, Field: "pvzId", Message: err.Error()})
+				continue
+			}
+			i = len(manifests)
+			index[row.PvzId] = i
+			reception := models.Reception{
+				ID:       NewID("reception"),
+				DateTime: now,
+				PvzId:    row.PvzId,
+				Status:   models.ReceptionStatusPending,
+				Kind:     models.ReceptionKindDelivery,
+			}
+			receptions = append(receptions, reception)
+			manifests = append(manifests, models.Manifest{
+				ReceptionId: reception.ID,
+				Source:      in.Source,
+				AttachedAt:  now,
+				AttachedBy:  in.Actor,
+			})
+			result.Receptions = append(result.Receptions, models.ImportedReception{PvzId: row.PvzId})
+		}
+		manifests[i].Items = append(manifests[i].Items, row.Item)
+		result.Receptions[i].Items++
+		result.Receptions[i].Expected += row.Item.Quantity
+	}
+	for i := range manifests {
+		if len(manifests[i].Items) > maxManifestItems {
+			rowErrors = append(rowErrors, models.ImportRowError{
+				Message: fmt.Sprintf("PVZ %s: a manifest may contain at most %d items", receptions[i].PvzId, maxManifestItems),
+			})
+		}
+	}
+	if len(rowErrors) > 0 {
+		sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Line < rowErrors[j].Line })
+		result.Errors = rowErrors
+	}
+	if result.Receptions == nil {
+		result.Receptions = []models.ImportedReception{}
+	}
+
+	if in.DryRun {
+		return &result, nil
+	}
+	if len(result.Errors) > 0 || len(receptions) == 0 {
+		return &result, ErrImportRejected
+	}
+
+	if err := db.ImportManifests(receptions, manifests); err != nil {
+		return nil, err
+	}
+	for i := range receptions {
+		result.Receptions[i].ReceptionId = receptions[i].ID
+	}
+	return &result, nil
+}
+
+// normalizeManifestItems validates manifest lines and fills in the default
+// quantity. A barcode identifies exactly one product, so it may appear once.
+func normalizeManifestItems(items []models.ManifestItem) ([]models.ManifestItem, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("manifest has no items")
+	}
+	if len(items) > maxManifestItems {
+		return nil, fmt.Errorf("a manifest may contain at most %d items", maxManifestItems)
+	}
+
+	seen := make(map[string]int, len(items))
+	for i := range items {
+		item := &items[i]
+		if item.Type == "" {
+			return nil, fmt.Errorf("item %d: type is required", i)
+		}
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+		if item.Quantity < 0 {
+			return nil, fmt.Errorf("item %d: quantity must be positive", i)
+		}
+		if item.Barcode == "" {
+			continue
+		}
+
+		if item.Quantity != 1 {
+			return nil, fmt.Errorf("item %d: an item with a barcode has quantity 1", i)
+		}
+		if _, err := barcode.Validate(item.Barcode); err != nil {
+			return nil, fmt.Errorf("item %d: %v", i, err)
+		}
+		if first, ok := seen[item.Barcode]; ok {
+			return nil, fmt.Errorf("item %d: barcode %s repeats item %d", i, item.Barcode, first)
+		}
+		seen[item.Barcode] = i
+	}
+	return items, nil
+}
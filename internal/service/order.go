@@ -0,0 +1,120 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/notify"
+)
+
+const (
+	pickupCodeDigits      = 6
+	maxPickupCodeAttempts = 5
+)
+
+var ErrNotificationFailed = apperr.New(apperr.KindUnavailable, "notification_failed", "Failed to deliver pickup code")
+
+// OrderService registers customer orders and hands them out by pickup code.
+type OrderService struct {
+	now func() time.Time
+}
+
+// NewOrder describes an order announced for a PVZ.
+type NewOrder struct {
+	OrderNumber    string
+	PvzId          string
+	RecipientPhone string
+}
+
+func (s *OrderService) Create(in NewOrder) (*models.Order, error) {
+	in.OrderNumber = strings.TrimSpace(in.OrderNumber)
+	if in.OrderNumber == "" {
+		return nil, invalidRequest("orderNumber is required")
+	}
+	if !IsValidPhone(in.RecipientPhone) {
+		return nil, invalidRequest("Invalid phone")
+	}
+	if _, err := db.GetPVZByID(in.PvzId); err != nil {
+		return nil, apperr.InvalidReference(err, "pvzId")
+	}
+
+	order := models.Order{
+		ID:             NewID("order"),
+		OrderNumber:    in.OrderNumber,
+		PvzId:          in.PvzId,
+		RecipientPhone: in.RecipientPhone,
+		Status:         models.OrderStatusAwaiting,
+		CreatedAt:      s.now(),
+		Products:       []models.Product{},
+	}
+	if err := db.CreateOrder(order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (s *OrderService) Get(orderId string) (*models.Order, error) {
+	return db.GetOrderByID(orderId)
+}
+
+func (s *OrderService) GetByNumber(number string) (*models.Order, error) {
+	return db.GetOrderByNumber(number)
+}
+
+// SendPickupCode issues a new pickup code for the order and sends it to the
+// recipient. Only a hash of the code is kept, so an earlier code stops working.
+func (s *OrderService) SendPickupCode(orderId string, notifier notify.Notifier) error {
+	order, err := db.GetOrderByID(orderId)
+	if err != nil {
+		return err
+	}
+
+	code, err := generatePickupCode()
+	if err != nil {
+		return err
+	}
+	if err := db.SetOrderPickupCode(order.ID, hashPickupCode(order.ID, code)); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Заказ %s ждёт вас в пункте выдачи. Код получения: %s", order.OrderNumber, code)
+	if err := notifier.Notify(order.RecipientPhone, message); err != nil {
+		return ErrNotificationFailed
+	}
+	return nil
+}
+
+// Issue hands the order out when the pickup code matches. After too many
+// wrong codes the code is blocked until a new one is sent.
+func (s *OrderService) Issue(orderId, pickupCode string) (*models.Order, error) {
+	if pickupCode == "" {
+		return nil, invalidRequest("pickupCode is required")
+	}
+	return db.IssueOrder(orderId, hashPickupCode(orderId, pickupCode), maxPickupCodeAttempts)
+}
+
+func generatePickupCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < pickupCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", pickupCodeDigits, n), nil
+}
+
+// hashPickupCode keeps pickup codes out of the database; the order ID salts the hash.
+func hashPickupCode(orderId, code string) string {
+	sum := sha256.Sum256([]byte(orderId + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/barcode"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// Capacity policies, see models.CapacityPolicyReject.
+const (
+	CapacityPolicyReject = models.CapacityPolicyReject
+	CapacityPolicyWarn   = models.CapacityPolicyWarn
+)
+
+var (
+	// ErrDuplicateScan reports a barcode scanned twice into the same reception.
+	ErrDuplicateScan = apperr.Conflict("duplicate_scan", "barcode was already scanned")
+)
+
+// ProductService receives products into receptions.
+type ProductService struct {
+	now func() time.Time
+}
+
+// NewProduct describes a product arriving at a PVZ. Everything but Type is optional.
+type NewProduct struct {
+	Type        string
+	CellId      string
+	Barcode     string
+	OrderId     string
+	WeightGrams int
+	Dimensions  *models.Dimensions
+}
+
+// Add receives one product into a reception, or into the active reception of
+// the PVZ when receptionId is empty. The warning is set when the product
+// exceeds the capacity of a PVZ whose policy is to warn.
+func (s *ProductService) Add(pvzId, receptionId string, in NewProduct) (*models.Product, string, error) {
+	var reception *models.Reception
+	var err error
+	if receptionId != "" {
+		reception, err = db.GetReceptionByID(receptionId)
+	} else {
+		reception, err = db.GetActiveReception(pvzId)
+	}
+	if err != nil {
+		return nil, "", apperr.InvalidReference(err, "receptionId")
+	}
+	if reception.Status != models.ReceptionStatusInProgress {
+		return nil, "", db.ErrReceptionClosed
+	}
+
+	intake, err := s.Intake(reception)
+	if err != nil {
+		return nil, "", err
+	}
+	product, warning, err := intake.Prepare(0, in)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := db.InsertProduct(*product); err != nil {
+		return nil, "", err
+	}
+	return product, warning, nil
+}
+
+// Intake validates products arriving in one reception. It keeps track of the
+// products accepted so far, so barcodes, capacity and cells are checked
+// against them as well as the database.
+type Intake struct {
+	now          func() time.Time
+	reception    *models.Reception
+	occupancy    *models.Occupancy
+	cells        []models.StorageCell
+	cellsLoaded  bool
+	pendingCells map[string]int
+	barcodes     map[string]int
+}
+
+func (s *ProductService) Intake(reception *models.Reception) (*Intake, error) {
+	occupancy, err := db.GetPVZOccupancy(reception.PvzId)
+	if err != nil {
+		return nil, err
+	}
+	return &Intake{
+		now:          s.now,
+		reception:    reception,
+		occupancy:    occupancy,
+		pendingCells: make(map[string]int),
+		barcodes:     make(map[string]int),
+	}, nil
+}
+
+// Prepare validates the product at index of the delivery and, when it is
+// accepted, reserves its barcode, capacity and cell for the rest of the
+// delivery. The product is not stored; see Insert.
+func (in *Intake) Prepare(index int, item NewProduct) (*models.Product, string, error) {
+	productType, err := CheckProductType(item.Type)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := CheckMeasurements(productType, item.WeightGrams, item.Dimensions); err != nil {
+		return nil, "", err
+	}
+
+	if item.OrderId != "" {
+		if err := checkOrder(item.OrderId, in.reception.PvzId); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if item.Barcode != "" {
+		if first, ok := in.barcodes[item.Barcode]; ok {
+			return nil, "", ErrDuplicateScan.Withf("duplicate scan: barcode %s repeats item %d", item.Barcode, first)
+		}
+		if err := checkBarcode(item.Barcode, in.reception.ID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var volumeCm3 int64
+	if item.Dimensions != nil {
+		volumeCm3 = item.Dimensions.VolumeCm3()
+	}
+	warning, err := capacityOverflow(in.occupancy, item.Type, volumeCm3)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cell, err := in.cell(item.CellId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	product := &models.Product{
+		ID:          NewID("product"),
+		DateTime:    in.now(),
+		Type:        item.Type,
+		ReceptionId: in.reception.ID,
+		Barcode:     item.Barcode,
+		Status:      models.ProductStatusReceived,
+		OrderId:     item.OrderId,
+		WeightGrams: item.WeightGrams,
+		Dimensions:  item.Dimensions,
+	}
+	if cell != nil {
+		product.CellId = cell.ID
+		in.pendingCells[cell.ID]++
+	}
+	if item.Barcode != "" {
+		in.barcodes[item.Barcode] = index
+	}
+	in.occupancy.Add(*product)
+	return product, warning, nil
+}
+
+// Insert stores prepared products in a single transaction: either all of
+// them are created or none is.
+func (in *Intake) Insert(products []models.Product) error {
+	return db.InsertProducts(products)
+}
+
+// cell works like ResolveCell but counts the products already placed by the intake.
+func (in *Intake) cell(cellId string) (*models.StorageCell, error) {
+	if cellId != "" {
+		cell, err := ResolveCell(in.reception.PvzId, cellId)
+		if err != nil {
+			return nil, err
+		}
+		if !cellHasRoom(cell, in.pendingCells[cell.ID]) {
+			return nil, errCellFull(cell)
+		}
+		return cell, nil
+	}
+
+	if !in.cellsLoaded {
+		cells, err := db.GetStorageCellsByPVZ(in.reception.PvzId)
+		if err != nil {
+			return nil, err
+		}
+		in.cells = cells
+		in.cellsLoaded = true
+	}
+	for i := range in.cells {
+		if cellHasRoom(&in.cells[i], in.pendingCells[in.cells[i].ID]) {
+			return &in.cells[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func cellHasRoom(cell *models.StorageCell, pending int) bool {
+	return cell.Capacity == 0 || cell.Occupied+pending < cell.Capacity
+}
+
+// ResolveCell picks the storage cell for a product arriving at a PVZ. A requested
+// cell must belong to the PVZ and have room; otherwise the first free cell is used.
+// A nil cell means the PVZ has no cell layout and the product stays unassigned.
+func ResolveCell(pvzId, cellId string) (*models.StorageCell, error) {
+	if cellId == "" {
+		return db.FindFreeStorageCell(pvzId)
+	}
+
+	cell, err := db.GetStorageCellByID(cellId)
+	if err != nil {
+		return nil, apperr.InvalidReference(err, "cellId")
+	}
+	if cell.PvzId != pvzId {
+		return nil, apperr.Invalid("cell_other_pvz", "storage cell belongs to another PVZ")
+	}
+	if !cellHasRoom(cell, 0) {
+		return nil, errCellFull(cell)
+	}
+	return cell, nil
+}
+
+func errCellFull(cell *models.StorageCell) error {
+	return apperr.Conflict("cell_full", fmt.Sprintf("storage cell %s-%s-%s is full", cell.Zone, cell.Rack, cell.Shelf))
+}
+
+// CheckProductType makes sure products of the type are accepted and returns the catalogue entry.
+func CheckProductType(code string) (*models.ProductType, error) {
+	productType, err := db.GetProductType(code)
+	if errors.Is(err, db.ErrProductTypeNotFound) {
+		return nil, apperr.Invalid("unknown_product_type", fmt.Sprintf("unknown product type %q", code))
+	} else if err != nil {
+		return nil, err
+	}
+	if !productType.Active {
+		return nil, apperr.Invalid("product_type_inactive", fmt.Sprintf("product type %q is no longer accepted", code))
+	}
+	return productType, nil
+}
+
+// CheckMeasurements validates the optional weight and dimensions of a product
+// against sanity bounds and the limits of its type.
+func CheckMeasurements(productType *models.ProductType, weightGrams int, dimensions *models.Dimensions) error {
+	if weightGrams < 0 || weightGrams > MaxMeasurement {
+		return apperr.Invalid("invalid_measurements", fmt.Sprintf("weightGrams must be between 0 and %d", MaxMeasurement))
+	}
+	if dimensions != nil {
+		if err := ValidateDimensions(*dimensions, "dimensions"); err != nil {
+			return apperr.Invalid("invalid_measurements", err.Error())
+		}
+	}
+
+	if limit := productType.MaxWeightGrams; limit > 0 && weightGrams > limit {
+		return apperr.Invalid("exceeds_type_limits", fmt.Sprintf("%s weighs at most %d g, got %d g", productType.Code, limit, weightGrams))
+	}
+	if limit := productType.MaxDimensions; limit != nil && dimensions != nil && !dimensions.FitsWithin(*limit) {
+		return apperr.Invalid("exceeds_type_limits", fmt.Sprintf("%s must fit within %dx%dx%d mm", productType.Code, limit.LengthMm, limit.WidthMm, limit.HeightMm))
+	}
+	return nil
+}
+
+// checkOrder makes sure a product can be added to the order at the given PVZ.
+func checkOrder(orderId, pvzId string) error {
+	order, err := db.GetOrderByID(orderId)
+	if err != nil {
+		return apperr.InvalidReference(err, "orderId")
+	}
+	if order.PvzId != pvzId {
+		return apperr.Invalid("order_other_pvz", fmt.Sprintf("order %s is delivered to another PVZ", order.OrderNumber))
+	}
+	if order.Status != models.OrderStatusAwaiting {
+		return db.ErrOrderAlreadyIssued
+	}
+	return nil
+}
+
+// checkBarcode validates a scanned barcode and makes sure no stored or
+// travelling product carries it.
+// Scanning the same item twice within a reception is reported separately so staff
+// can tell a double scan from a mislabelled parcel.
+func checkBarcode(code, receptionId string) error {
+	if _, err := barcode.Validate(code); err != nil {
+		return apperr.Invalid("invalid_barcode", err.Error())
+	}
+
+	existing, err := db.GetProductByBarcode(code)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+	if existing.Product.ReceptionId == receptionId {
+		return ErrDuplicateScan.Withf("duplicate scan: barcode %s is already in this reception as %s", code, existing.Product.ID)
+	}
+	if existing.Product.Status == models.ProductStatusInTransit {
+		return db.ErrBarcodeStored.Withf("barcode %s is in transit from PVZ %s", code, existing.PvzId)
+	}
+	return db.ErrBarcodeStored.Withf("barcode %s is already stored at PVZ %s", code, existing.PvzId)
+}
+
+// capacityOverflow applies the PVZ capacity policy to an occupancy. Under the
+// warn policy an overflow is returned as a warning instead of an error.
+// db.InsertProducts checks again when the products are stored.
+func capacityOverflow(occupancy *models.Occupancy, productType string, volumeCm3 int64) (string, error) {
+	overflow := occupancy.Overflow(productType, volumeCm3)
+	if overflow != "" && occupancy.Capacity.Policy != CapacityPolicyWarn {
+		return "", db.ErrCapacityExceeded.Withf("%s", overflow)
+	}
+	return overflow, nil
+}
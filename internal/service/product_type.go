@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+// MaxProductTypeCodeLength bounds the code of a catalogue entry in bytes.
+const MaxProductTypeCodeLength = 64
+
+// ProductTypeService maintains the catalogue of product types.
+type ProductTypeService struct{}
+
+// List returns the catalogue by code. Inactive types are only listed when a
+// moderator asks for all of them.
+func (s *ProductTypeService) List(all bool, role string, page pagination.Request) ([]models.ProductType, pagination.Info, error) {
+	return db.GetProductTypes(all && role == "Moderator", page)
+}
+
+func (s *ProductTypeService) Get(code string) (*models.ProductType, error) {
+	return db.GetProductType(code)
+}
+
+// Put creates or replaces a catalogue entry. Types are never deleted, since
+// products keep referring to them; they are deactivated instead. The storage
+// period is only changed when storagePeriodDays is given.
+func (s *ProductTypeService) Put(productType models.ProductType, storagePeriodDays *int) (*models.ProductType, error) {
+	if storagePeriodDays != nil {
+		productType.StoragePeriodDays = *storagePeriodDays
+	}
+	if err := validateProductType(productType); err != nil {
+		return nil, apperr.Invalid("invalid_request", err.Error())
+	}
+
+	if err := db.SetProductType(productType, storagePeriodDays); err != nil {
+		return nil, err
+	}
+	return db.GetProductType(productType.Code)
+}
+
+func validateProductType(productType models.ProductType) error {
+	if productType.Code == "" || len(productType.Code) > MaxProductTypeCodeLength {
+		return fmt.Errorf("code must be between 1 and %d bytes", MaxProductTypeCodeLength)
+	}
+	if len(productType.Names) == 0 {
+		return fmt.Errorf("at least one display name is required")
+	}
+	for locale, name := range productType.Names {
+		if locale == "" || name == "" {
+			return fmt.Errorf("display names must have a locale and a name")
+		}
+	}
+	switch productType.SizeClass {
+	case models.SizeClassSmall, models.SizeClassMedium, models.SizeClassLarge, models.SizeClassOversized:
+	default:
+		return fmt.Errorf("sizeClass must be small, medium, large or oversized")
+	}
+	if productType.StoragePeriodDays < 0 || productType.StoragePeriodDays > MaxStoragePeriodDays {
+		return fmt.Errorf("storagePeriodDays must be between 0 and %d", MaxStoragePeriodDays)
+	}
+	if productType.MaxWeightGrams < 0 || productType.MaxWeightGrams > MaxMeasurement {
+		return fmt.Errorf("maxWeightGrams must be between 0 and %d", MaxMeasurement)
+	}
+	if productType.MaxDimensions != nil {
+		return ValidateDimensions(*productType.MaxDimensions, "maxDimensions")
+	}
+	return nil
+}
+
+// isAcceptedProductType reports whether products of the type are accepted.
+func isAcceptedProductType(code string) bool {
+	_, err := CheckProductType(code)
+	return err == nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+// PVZService manages pickup points and their schedules.
+type PVZService struct {
+	now func() time.Time
+}
+
+// NewPVZ describes a PVZ to open. Latitude and longitude are optional but
+// must be set together.
+type NewPVZ struct {
+	City         string
+	Address      string
+	Latitude     *float64
+	Longitude    *float64
+	Phone        string
+	WorkingHours []models.WorkingHours
+	Holidays     []models.HolidayOverride
+}
+
+func (s *PVZService) Create(in NewPVZ) (*models.PVZ, error) {
+	if !IsValidCity(in.City) {
+		return nil, apperr.Invalid("invalid_city", "Invalid city", models.FieldError{Field: "city", Message: "Invalid city"})
+	}
+	if (in.Latitude == nil) != (in.Longitude == nil) {
+		return nil, apperr.Invalid("invalid_coordinates", "Latitude and longitude must be set together")
+	}
+	if in.Latitude != nil && !IsValidCoordinate(*in.Latitude, *in.Longitude) {
+		return nil, apperr.Invalid("invalid_coordinates", "Invalid coordinates")
+	}
+	if in.Phone != "" && !IsValidPhone(in.Phone) {
+		return nil, apperr.Invalid("invalid_phone", "Invalid phone", models.FieldError{Field: "phone", Message: "Invalid phone"})
+	}
+	if err := models.ValidateSchedule(in.WorkingHours, in.Holidays); err != nil {
+		return nil, apperr.Invalid("invalid_schedule", err.Error())
+	}
+
+	pvz := models.PVZ{
+		ID:               NewID("pvz"),
+		RegistrationDate: s.now(),
+		City:             in.City,
+		Address:          in.Address,
+		Phone:            in.Phone,
+		WorkingHours:     normalizeWorkingHours(in.WorkingHours),
+		Holidays:         in.Holidays,
+	}
+	if in.Latitude != nil {
+		pvz.Latitude = *in.Latitude
+		pvz.Longitude = *in.Longitude
+	}
+	if err := db.CreatePVZWithDetails(&pvz); err != nil {
+		return nil, err
+	}
+	return &pvz, nil
+}
+
+func (s *PVZService) Get(pvzId string) (*models.PVZ, error) {
+	return db.GetPVZByID(pvzId)
+}
+
+func (s *PVZService) List(filter db.PVZFilter, page pagination.Request) ([]models.PVZ, pagination.Info, error) {
+	return db.GetPVZsFiltered(filter, page)
+}
+
+// Nearby returns up to limit PVZs within radius meters, nearest first. With
+// openNow only PVZs open at the moment are included.
+func (s *PVZService) Nearby(lat, lon, radius float64, limit int, openNow bool) ([]models.NearbyPVZ, error) {
+	if !IsValidCoordinate(lat, lon) {
+		return nil, apperr.Invalid("invalid_coordinates", "Invalid lat or lon")
+	}
+	nearby, err := db.GetPVZsNearby(lat, lon, radius)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.NearbyPVZ{}
+	now := s.now()
+	for _, pvz := range nearby {
+		if openNow && !pvz.IsOpenAt(now) {
+			continue
+		}
+		result = append(result, pvz)
+		if len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// SetSchedule replaces the working hours and holidays of a PVZ.
+func (s *PVZService) SetSchedule(pvzId string, hours []models.WorkingHours, holidays []models.HolidayOverride) (*models.PVZ, error) {
+	if err := models.ValidateSchedule(hours, holidays); err != nil {
+		return nil, apperr.Invalid("invalid_schedule", err.Error())
+	}
+	if err := db.SetPVZSchedule(pvzId, normalizeWorkingHours(hours), holidays); err != nil {
+		return nil, err
+	}
+	return db.GetPVZByID(pvzId)
+}
+
+// SetCapacity replaces the capacity limits of a PVZ and returns its occupancy
+// against them. An empty policy rejects products that do not fit.
+func (s *PVZService) SetCapacity(pvzId string, capacity models.Capacity) (*models.Occupancy, error) {
+	if capacity.Policy == "" {
+		capacity.Policy = CapacityPolicyReject
+	}
+	if capacity.Policy != CapacityPolicyReject && capacity.Policy != CapacityPolicyWarn {
+		return nil, invalidRequest("policy must be reject or warn")
+	}
+	if capacity.Total < 0 || capacity.VolumeCm3 < 0 {
+		return nil, invalidRequest("total capacity must not be negative")
+	}
+	for productType, limit := range capacity.ByType {
+		if productType == "" || limit < 0 {
+			return nil, invalidRequest("Invalid capacity for product type")
+		}
+	}
+
+	if err := db.SetPVZCapacity(pvzId, capacity); err != nil {
+		return nil, err
+	}
+	return db.GetPVZOccupancy(pvzId)
+}
+
+// Occupancy counts the products stored at a PVZ against its capacity.
+func (s *PVZService) Occupancy(pvzId string) (*models.Occupancy, error) {
+	return db.GetPVZOccupancy(pvzId)
+}
+
+// CloseLastReception closes the active reception of a PVZ. When a manifest
+// announced the delivery, the reconciliation against it is returned.
+func (s *PVZService) CloseLastReception(pvzId, actor string) (*models.Reconciliation, error) {
+	receptionId, err := db.CloseLastReceptionBy(pvzId, actor)
+	if err != nil {
+		return nil, err
+	}
+	report, err := db.GetReconciliation(receptionId)
+	if errors.Is(err, db.ErrReconciliationNotFound) {
+		return nil, nil
+	}
+	return report, err
+}
+
+// DeleteLastProduct removes the most recently received product of the active reception.
+func (s *PVZService) DeleteLastProduct(pvzId string) error {
+	return db.DeleteLastProduct(pvzId)
+}
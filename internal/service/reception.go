@@ -0,0 +1,69 @@
+package service
+
+import (
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+var (
+	ErrPVZClosed         = apperr.Invalid("pvz_closed", "PVZ is closed at this time")
+	ErrOverrideForbidden = apperr.Forbidden("override_forbidden", "Only a moderator can override working hours")
+)
+
+// ReceptionService opens receptions of deliveries.
+type ReceptionService struct {
+	now func() time.Time
+}
+
+// NewReception asks to open a reception at a PVZ on behalf of Actor, whose
+// role decides whether working hours may be overridden.
+type NewReception struct {
+	PvzId    string
+	Override bool
+	Actor    string
+	Role     string
+}
+
+// Open starts the pending reception a manifest announced for the PVZ, or a
+// new delivery reception when there is none. Outside working hours only a
+// moderator may open a reception, and only explicitly. While a reception is
+// in progress no other can be opened at the PVZ.
+func (s *ReceptionService) Open(in NewReception) (*models.Reception, error) {
+	pvz, err := db.GetPVZByID(in.PvzId)
+	if err != nil {
+		return nil, apperr.InvalidReference(err, "pvzId")
+	}
+
+	now := s.now()
+	if !pvz.IsOpenAt(now) {
+		if !in.Override {
+			return nil, ErrPVZClosed
+		}
+		if in.Role != "Moderator" {
+			return nil, ErrOverrideForbidden
+		}
+	}
+
+	return db.OpenReception(models.Reception{
+		ID:       NewID("reception"),
+		DateTime: now,
+		PvzId:    in.PvzId,
+		Status:   models.ReceptionStatusInProgress,
+		Kind:     models.ReceptionKindDelivery,
+	}, in.Actor)
+}
+
+// GetOpen returns a reception that is still accepting products.
+func (s *ReceptionService) GetOpen(receptionId string) (*models.Reception, error) {
+	reception, err := db.GetReceptionByID(receptionId)
+	if err != nil {
+		return nil, err
+	}
+	if reception.Status != models.ReceptionStatusInProgress {
+		return nil, db.ErrReceptionClosed
+	}
+	return reception, nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// MaxSearchLength limits the length of a search query.
+const MaxSearchLength = 200
+
+// SearchService finds PVZs, receptions, products and orders by free text.
+type SearchService struct{}
+
+// Search matches text as word prefixes against PVZ cities and addresses,
+// reception IDs and staff, product barcodes and types, and order numbers.
+// kinds narrows the search; none means every kind.
+func (s *SearchService) Search(text string, kinds []string, limit int) ([]models.SearchResult, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || len([]rune(text)) > MaxSearchLength {
+		return nil, invalidRequest("q is required and must be at most %d characters", MaxSearchLength)
+	}
+	if db.SearchQuery(text) == "" {
+		return nil, invalidRequest("q must contain letters or digits")
+	}
+	for _, kind := range kinds {
+		if !isSearchKind(kind) {
+			return nil, invalidRequest("kind must list pvz, reception, product or order")
+		}
+	}
+	return db.Search(text, kinds, limit)
+}
+
+func isSearchKind(kind string) bool {
+	for _, known := range db.SearchKinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
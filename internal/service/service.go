@@ -0,0 +1,60 @@
+// Package service holds the business rules of the PVZ domain: validation,
+// invariants and the order in which the database is changed. Front ends such
+// as the HTTP routes only decode requests and encode results, so a gRPC server
+// or a CLI can reuse the same rules. Errors are *apperr.Error values.
+package service
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Services bundles the services a front end needs.
+type Services struct {
+	PVZ          *PVZService
+	Receptions   *ReceptionService
+	Products     *ProductService
+	Auth         *AuthService
+	Orders       *OrderService
+	Transfers    *TransferService
+	Cells        *CellService
+	Storage      *StorageService
+	ProductTypes *ProductTypeService
+	Incidents    *IncidentService
+	Manifests    *ManifestService
+	Attachments  *AttachmentService
+	Search       *SearchService
+}
+
+// New returns the services backed by the global database, using the system clock.
+func New() *Services {
+	return NewWithClock(time.Now)
+}
+
+// NewWithClock is New with a clock of the caller's choice, for tests and replays.
+func NewWithClock(now func() time.Time) *Services {
+	return &Services{
+		PVZ:          &PVZService{now: now},
+		Receptions:   &ReceptionService{now: now},
+		Products:     &ProductService{now: now},
+		Auth:         &AuthService{},
+		Orders:       &OrderService{now: now},
+		Transfers:    &TransferService{now: now},
+		Cells:        &CellService{},
+		Storage:      &StorageService{now: now},
+		ProductTypes: &ProductTypeService{},
+		Incidents:    &IncidentService{now: now},
+		Manifests:    &ManifestService{now: now},
+		Attachments:  &AttachmentService{now: now},
+		Search:       &SearchService{},
+	}
+}
+
+var idSequence uint64
+
+// NewID builds an identifier from a prefix and the current time. The sequence
+// suffix keeps IDs unique when several are generated within the same instant.
+func NewID(prefix string) string {
+	return fmt.Sprintf("%s-%d-%d", prefix, time.Now().UnixNano(), atomic.AddUint64(&idSequence, 1))
+}
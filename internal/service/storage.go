@@ -0,0 +1,70 @@
+package service
+
+import (
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+)
+
+// MaxStoragePeriodDays bounds how long products of a type may be kept.
+const MaxStoragePeriodDays = 365
+
+// StorageService tracks how long products stay at a PVZ and sends back the
+// ones kept too long.
+type StorageService struct {
+	now func() time.Time
+}
+
+// SetPeriod configures how many days products of a catalogue type are kept.
+func (s *StorageService) SetPeriod(productType string, days int) (*models.StoragePeriod, error) {
+	if productType == "" {
+		return nil, invalidRequest("Invalid request")
+	}
+	if days < 1 || days > MaxStoragePeriodDays {
+		return nil, invalidRequest("days must be between 1 and %d", MaxStoragePeriodDays)
+	}
+	if _, err := db.GetProductType(productType); err != nil {
+		return nil, err
+	}
+
+	period := models.StoragePeriod{Type: productType, Days: days}
+	if err := db.SetStoragePeriod(period); err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+func (s *StorageService) Periods() ([]models.StoragePeriod, error) {
+	return db.GetStoragePeriods()
+}
+
+// Expiring lists the products of a PVZ whose storage deadline is within the
+// next days days, including those already overdue, soonest first.
+func (s *StorageService) Expiring(pvzId string, days int, page pagination.Request) ([]models.ExpiringProduct, pagination.Info, error) {
+	if days < 0 || days > MaxStoragePeriodDays {
+		return nil, pagination.Info{}, invalidRequest("Invalid days")
+	}
+	if _, err := db.GetPVZByID(pvzId); err != nil {
+		return nil, pagination.Info{}, err
+	}
+	now := s.now()
+	return db.GetExpiringProductsPage(pvzId, now.AddDate(0, 0, days), now, page)
+}
+
+// ReturnBatches lists the return batches of a PVZ, newest first.
+func (s *StorageService) ReturnBatches(pvzId string, page pagination.Request) ([]models.ReturnBatch, pagination.Info, error) {
+	if _, err := db.GetPVZByID(pvzId); err != nil {
+		return nil, pagination.Info{}, err
+	}
+	return db.GetReturnBatchesPage(pvzId, page)
+}
+
+// DispatchReturnBatch hands a pending return batch to the courier.
+func (s *StorageService) DispatchReturnBatch(batchId string) (*models.ReturnBatch, error) {
+	if err := db.DispatchReturnBatch(batchId); err != nil {
+		return nil, err
+	}
+	return db.GetReturnBatchByID(batchId)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// TransferService moves stored products between PVZs.
+type TransferService struct {
+	now func() time.Time
+}
+
+// NewTransfer asks to send products from one PVZ to another on behalf of Actor.
+type NewTransfer struct {
+	SourcePvzId      string
+	DestinationPvzId string
+	ProductIds       []string
+	Actor            string
+}
+
+// Create dispatches the products from the source PVZ. Repeated product IDs
+// are sent once.
+func (s *TransferService) Create(in NewTransfer) (*models.Transfer, error) {
+	if len(in.ProductIds) == 0 {
+		return nil, invalidRequest("productIds are required")
+	}
+	if in.SourcePvzId == in.DestinationPvzId {
+		return nil, invalidRequest("source and destination PVZ must differ")
+	}
+	for _, pvzId := range []string{in.SourcePvzId, in.DestinationPvzId} {
+		if _, err := db.GetPVZByID(pvzId); err != nil {
+			return nil, apperr.InvalidReference(err, "pvzId")
+		}
+	}
+
+	transfer := models.Transfer{
+		ID:               NewID("transfer"),
+		SourcePvzId:      in.SourcePvzId,
+		DestinationPvzId: in.DestinationPvzId,
+		Status:           models.TransferStatusDispatched,
+		CreatedAt:        s.now(),
+	}
+	seen := make(map[string]bool, len(in.ProductIds))
+	for _, productId := range in.ProductIds {
+		if seen[productId] {
+			continue
+		}
+		seen[productId] = true
+		transfer.Items = append(transfer.Items, models.TransferItem{ProductId: productId, Status: models.TransferItemPending})
+	}
+
+	if err := db.CreateTransfer(transfer, in.Actor); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (s *TransferService) Get(transferId string) (*models.Transfer, error) {
+	return db.GetTransferByID(transferId)
+}
+
+// MarkInTransit records that the courier has picked the transfer up.
+func (s *TransferService) MarkInTransit(transferId, actor string) (*models.Transfer, error) {
+	if err := db.MarkTransferInTransit(transferId, actor); err != nil {
+		return nil, err
+	}
+	return db.GetTransferByID(transferId)
+}
+
+// Receive accepts the arrived products into a new reception at the
+// destination and reports the products that went missing or were unexpected.
+func (s *TransferService) Receive(transferId string, arrived []string, actor string) (*models.Transfer, *models.TransferDiscrepancy, error) {
+	discrepancy, err := db.ReceiveTransfer(transferId, NewID("reception"), arrived, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+	transfer, err := db.GetTransferByID(transferId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return transfer, discrepancy, nil
+}
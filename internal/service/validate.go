@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+)
+
+// Cities lists the cities PVZs can be opened in.
+var Cities = []string{"Москва", "Казань", "Санкт-Петербург"}
+
+// MaxMeasurement bounds weights in grams and sides in millimetres to catch
+// unit mix-ups; nothing heavier than 1 t or longer than 10 m goes through a PVZ.
+const MaxMeasurement = 1000000
+
+var (
+	emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+	phoneRegex = regexp.MustCompile(`^\+?[0-9]{10,15}$`)
+)
+
+func IsValidCity(city string) bool {
+	for _, c := range Cities {
+		if city == c {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidEmail(email string) bool {
+	return emailRegex.MatchString(email)
+}
+
+func IsValidPhone(phone string) bool {
+	return phoneRegex.MatchString(phone)
+}
+
+func IsValidCoordinate(latitude, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
+
+// ValidateDimensions checks every side against MaxMeasurement; field names
+// the dimensions in the message.
+func ValidateDimensions(dimensions models.Dimensions, field string) error {
+	for _, side := range []int{dimensions.LengthMm, dimensions.WidthMm, dimensions.HeightMm} {
+		if side < 1 || side > MaxMeasurement/100 {
+			return fmt.Errorf("%s must be between 1 and %d mm on every side", field, MaxMeasurement/100)
+		}
+	}
+	return nil
+}
+
+// normalizeWorkingHours lowercases weekday names so lookups don't depend on client casing.
+func normalizeWorkingHours(hours []models.WorkingHours) []models.WorkingHours {
+	normalized := make([]models.WorkingHours, 0, len(hours))
+	for _, h := range hours {
+		h.Weekday = strings.ToLower(h.Weekday)
+		normalized = append(normalized, h)
+	}
+	return normalized
+}
+
+// invalidRequest reports input that fails a check without a code of its own.
+func invalidRequest(format string, args ...interface{}) error {
+	return apperr.Invalid("invalid_request", fmt.Sprintf(format, args...))
+}
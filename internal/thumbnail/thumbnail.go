@@ -0,0 +1,108 @@
+// Package thumbnail produces small JPEG previews of uploaded photos.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+
+	// Register the decoders for the formats staff can upload.
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	quality = 80
+	// maxPixels keeps a small file that declares a huge canvas from
+	// exhausting memory when decoded.
+	maxPixels = 50_000_000
+)
+
+var ErrTooManyPixels = errors.New("image dimensions are too large")
+
+// Make decodes an image and scales it down so that its longer side is at most
+// maxSide pixels. Smaller images keep their size. It also returns the size of
+// the original image.
+func Make(data []byte, maxSide int) (thumb []byte, width, height int, err error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to decode image: %v", err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, 0, 0, ErrTooManyPixels
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to decode image: %v", err)
+	}
+	bounds := src.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := width, height
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			dstWidth, dstHeight = maxSide, max(1, height*maxSide/width)
+		} else {
+			dstWidth, dstHeight = max(1, width*maxSide/height), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	if dstWidth == width && dstHeight == height {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	} else {
+		scale(dst, src)
+	}
+	flatten(dst)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to encode thumbnail: %v", err)
+	}
+	return buf.Bytes(), width, height, nil
+}
+
+// scale averages the source pixels that fall into each destination pixel.
+// A box filter keeps previews of damage readable without pulling in an
+// imaging library.
+func scale(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	for y := 0; y < db.Dy(); y++ {
+		y0 := sb.Min.Y + y*sb.Dy()/db.Dy()
+		y1 := max(y0+1, sb.Min.Y+(y+1)*sb.Dy()/db.Dy())
+		for x := 0; x < db.Dx(); x++ {
+			x0 := sb.Min.X + x*sb.Dx()/db.Dx()
+			x1 := max(x0+1, sb.Min.X+(x+1)*sb.Dx()/db.Dx())
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+}
+
+// flatten puts transparent pixels on a white background, since JPEG has no
+// alpha channel and would otherwise show them black.
+func flatten(img *image.RGBA) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		background := 0xff - img.Pix[i+3]
+		img.Pix[i+0] += background
+		img.Pix[i+1] += background
+		img.Pix[i+2] += background
+		img.Pix[i+3] = 0xff
+	}
+}
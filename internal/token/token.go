@@ -0,0 +1,52 @@
+// Package token issues and verifies the JWTs that authenticate users. It has
+// no HTTP dependencies, so every front end can share it.
+package token
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var secret = []byte("some_super_secret_key")
+
+// Secret returns the key tokens are signed with.
+func Secret() []byte {
+	return secret
+}
+
+// Generate signs a token for the user that expires in 24 hours.
+func Generate(user models.User) (string, error) {
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"email": user.Email,
+		"role":  user.Role,
+		"exp":   time.Now().Add(time.Hour * 24).Unix(),
+		"iat":   time.Now().Unix(),
+	})
+
+	tokenString, err := claims.SignedString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// Verify parses a token and checks its signature and expiry.
+func Verify(tokenString string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		return secret, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return token, nil
+}
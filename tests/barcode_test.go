@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/barcode"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarcodeValidate(t *testing.T) {
+	format, err := barcode.Validate("4006381333931")
+	assert.NoError(t, err)
+	assert.Equal(t, barcode.FormatEAN13, format)
+
+	_, err = barcode.Validate("4006381333932")
+	assert.Error(t, err)
+
+	format, err = barcode.Validate("PVZ-0001-ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, barcode.FormatCode128, format)
+
+	_, err = barcode.Validate("посылка")
+	assert.Error(t, err)
+	_, err = barcode.Validate("")
+	assert.Error(t, err)
+}
+
+func TestProductBarcodes(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-2", "Казань", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+	require.NoError(t, db.CreateReception("reception-2", "2023-01-02T00:00:00Z", "pvz-2", "in_progress"))
+
+	addProduct := func(pvzId, code string) *models.Error {
+		w := makeAuthorizedRequest(t, r, http.MethodPost, "/products", "PVZemployee", map[string]string{
+			"type": "электроника", "pvzId": pvzId, "barcode": code,
+		})
+		if w.Code == http.StatusCreated {
+			return nil
+		}
+		var e models.Error
+		json.Unmarshal(w.Body.Bytes(), &e)
+		e.Message = http.StatusText(w.Code) + ": " + e.Message
+		return &e
+	}
+
+	assert.Nil(t, addProduct("pvz-1", "4006381333931"))
+	if e := addProduct("pvz-1", "4006381333931"); assert.NotNil(t, e) {
+		assert.Contains(t, e.Message, "Conflict: duplicate scan")
+	}
+	if e := addProduct("pvz-2", "4006381333931"); assert.NotNil(t, e) {
+		assert.Contains(t, e.Message, "already stored at PVZ pvz-1")
+	}
+	if e := addProduct("pvz-1", "4006381333932"); assert.NotNil(t, e) {
+		assert.Contains(t, e.Message, "Bad Request")
+	}
+
+	w := makeAuthorizedRequest(t, r, http.MethodGet, "/products/by-barcode/4006381333931", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var lookup models.ProductLookup
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lookup))
+	assert.Equal(t, "pvz-1", lookup.PvzId)
+	assert.Equal(t, "reception-1", lookup.Product.ReceptionId)
+	assert.Equal(t, "4006381333931", lookup.Product.Barcode)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/products/by-barcode/5901234123457", "PVZemployee", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
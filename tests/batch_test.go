@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchProductIntake(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+	require.NoError(t, db.CreateStorageCell(models.StorageCell{ID: "cell-1", PvzId: "pvz-1", Zone: "A", Rack: "01", Shelf: "1", Capacity: 1}))
+
+	url := "/receptions/reception-1/products:batch"
+	items := []map[string]string{
+		{"type": "электроника", "barcode": "PKG-0001"},
+		{"type": "одежда", "barcode": "PKG-0002"},
+		{"type": "обувь", "barcode": "PKG-0001"},
+		{"type": "обувь", "cellId": "cell-1"},
+	}
+
+	// The duplicate scan and the cell filled by the first item reject the whole batch
+	w := makeAuthorizedRequest(t, r, http.MethodPost, url, "PVZemployee", map[string]interface{}{"items": items})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	var summary models.BatchSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 0, summary.Created)
+	assert.Equal(t, 2, summary.Rejected)
+	assert.Equal(t, models.BatchItemSkipped, summary.Items[0].Status)
+	assert.Equal(t, models.BatchItemRejected, summary.Items[2].Status)
+	assert.Contains(t, summary.Items[2].Error, "repeats item 0")
+	assert.Equal(t, models.BatchItemRejected, summary.Items[3].Status)
+	products, err := db.GetProductsByReception("reception-1")
+	require.NoError(t, err)
+	assert.Empty(t, products)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, url, "PVZemployee", map[string]interface{}{"items": items, "partial": true})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 2, summary.Created)
+	assert.Equal(t, "cell-1", mustGetProduct(t, summary.Items[0].ProductId).CellId)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions/reception-1/products:unknown", "PVZemployee", map[string]interface{}{"items": items})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The NDJSON variant answers line by line and ends with the summary
+	var stream strings.Builder
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(&stream, "{\"type\": \"одежда\", \"barcode\": \"BOX-%d\"}\n", i)
+	}
+	stream.WriteString("not json\n")
+	req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(stream.String()))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	token, err := middleware.GenerateToken(models.User{Email: "staff@example.com", Role: "PVZemployee"})
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	req.AddCookie(&http.Cookie{Name: "role", Value: "PVZemployee"})
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var lines []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.Len(t, lines, 5)
+	var result models.BatchItemResult
+	require.NoError(t, json.Unmarshal([]byte(lines[3]), &result))
+	assert.Equal(t, models.BatchItemRejected, result.Status)
+	require.NoError(t, json.Unmarshal([]byte(lines[4]), &summary))
+	assert.Equal(t, 3, summary.Created)
+	assert.Equal(t, 1, summary.Rejected)
+
+	products, err = db.GetProductsByReception("reception-1")
+	require.NoError(t, err)
+	assert.Len(t, products, 5)
+
+	require.NoError(t, db.CloseLastReception("pvz-1"))
+	w = makeAuthorizedRequest(t, r, http.MethodPost, url, "PVZemployee", map[string]interface{}{"items": items})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func mustGetProduct(t *testing.T, id string) *models.Product {
+	product, err := db.GetProductByID(id)
+	require.NoError(t, err)
+	return product
+}
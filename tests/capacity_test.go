@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/routes"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPVZCapacity(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPut, "/pvz/pvz-1/capacity", "Moderator", map[string]interface{}{
+		"total":  3,
+		"byType": map[string]int{"обувь": 1},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	addProduct := func(productType string) int {
+		w := makeAuthorizedRequest(t, r, http.MethodPost, "/products", "PVZemployee", map[string]string{
+			"type":  productType,
+			"pvzId": "pvz-1",
+		})
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, addProduct("обувь"))
+	assert.Equal(t, http.StatusConflict, addProduct("обувь"))
+	assert.Equal(t, http.StatusCreated, addProduct("электроника"))
+	assert.Equal(t, http.StatusCreated, addProduct("электроника"))
+	assert.Equal(t, http.StatusConflict, addProduct("электроника"))
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz/pvz-1/occupancy", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var occupancy models.Occupancy
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &occupancy))
+	assert.Equal(t, 3, occupancy.Total)
+	assert.Equal(t, 1, occupancy.ByType["обувь"])
+	assert.Equal(t, 2, occupancy.ByType["электроника"])
+	require.NotNil(t, occupancy.Available)
+	assert.Equal(t, 0, *occupancy.Available)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/pvz/pvz-1/capacity", "Moderator", map[string]interface{}{
+		"total":  3,
+		"policy": "warn",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/products", "PVZemployee", map[string]string{
+		"type":  "одежда",
+		"pvzId": "pvz-1",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Header().Get("X-Capacity-Warning"), "4 of 3")
+
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/pvz/pvz-1/capacity", "Moderator", map[string]interface{}{
+		"policy": "ignore",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// Products validated concurrently are checked again when they are stored.
+func TestCapacityCheckedOnInsert(t *testing.T) {
+	require.NoError(t, db.InitDB(":memory:"))
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+	require.NoError(t, db.SetPVZCapacity("pvz-1", models.Capacity{Total: 1, Policy: models.CapacityPolicyReject}))
+
+	reception, err := db.GetReceptionByID("reception-1")
+	require.NoError(t, err)
+	var products []*models.Product
+	for i := 0; i < 2; i++ {
+		intake, err := routes.Services.Products.Intake(reception)
+		require.NoError(t, err)
+		product, _, err := intake.Prepare(0, service.NewProduct{Type: "обувь"})
+		require.NoError(t, err)
+		products = append(products, product)
+	}
+
+	require.NoError(t, db.InsertProduct(*products[0]))
+	err = db.InsertProduct(*products[1])
+	assert.True(t, errors.Is(err, db.ErrCapacityExceeded), "%v", err)
+
+	occupancy, err := db.GetPVZOccupancy("pvz-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, occupancy.Total)
+}
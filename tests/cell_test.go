@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageCells(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-2", "Казань", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+
+	createCell := func(pvzId, zone, rack, shelf string, capacity int) models.StorageCell {
+		w := makeAuthorizedRequest(t, r, http.MethodPost, "/pvz/"+pvzId+"/cells", "Moderator", map[string]interface{}{
+			"zone": zone, "rack": rack, "shelf": shelf, "capacity": capacity,
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var cell models.StorageCell
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cell))
+		return cell
+	}
+	small := createCell("pvz-1", "A", "01", "1", 1)
+	large := createCell("pvz-1", "B", "01", "1", 0)
+	foreign := createCell("pvz-2", "A", "01", "1", 0)
+
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/pvz/pvz-1/cells", "Moderator", map[string]interface{}{
+		"zone": "A", "rack": "01", "shelf": "1",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	addProduct := func(body map[string]string) (int, models.Product) {
+		body["pvzId"] = "pvz-1"
+		body["type"] = "электроника"
+		w := makeAuthorizedRequest(t, r, http.MethodPost, "/products", "PVZemployee", body)
+		var product models.Product
+		json.Unmarshal(w.Body.Bytes(), &product)
+		return w.Code, product
+	}
+
+	// Automatic assignment fills cells in address order
+	code, first := addProduct(map[string]string{})
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, small.ID, first.CellId)
+	code, second := addProduct(map[string]string{})
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, large.ID, second.CellId)
+
+	// Manual assignment is checked against the PVZ and the cell capacity
+	code, _ = addProduct(map[string]string{"cellId": small.ID})
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = addProduct(map[string]string{"cellId": foreign.ID})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/products/"+first.ID+"/cell", "PVZemployee", map[string]string{"cellId": large.ID})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/products/"+first.ID+"/cell", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var location models.ProductLocation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &location))
+	assert.Equal(t, "pvz-1", location.PvzId)
+	require.NotNil(t, location.Cell)
+	assert.Equal(t, "B", location.Cell.Zone)
+	assert.Equal(t, 2, location.Cell.Occupied)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/products/unknown/cell", "PVZemployee", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) models.Error {
+	t.Helper()
+	assert.Equal(t, "application/problem+json", strings.Split(w.Header().Get("Content-Type"), ";")[0])
+	var problem models.Error
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem), w.Body.String())
+	assert.Equal(t, w.Code, problem.Status)
+	assert.Equal(t, problem.Detail, problem.Message)
+	assert.Equal(t, w.Header().Get("X-Request-ID"), problem.RequestID)
+	return problem
+}
+
+func TestProblemResponses(t *testing.T) {
+	r := newTestRouter(t)
+
+	cases := []struct {
+		method, url, role string
+		body              interface{}
+		status            int
+		code              string
+	}{
+		{http.MethodGet, "/pvz/unknown", "Moderator", nil, http.StatusNotFound, "pvz_not_found"},
+		{http.MethodGet, "/no/such/route", "Moderator", nil, http.StatusNotFound, "route_not_found"},
+		{http.MethodPost, "/pvz", "PVZemployee", map[string]string{"city": "Тверь"}, http.StatusBadRequest, "invalid_request"},
+		{http.MethodPost, "/receptions", "PVZemployee", map[string]string{"pvzId": "unknown"}, http.StatusBadRequest, "pvz_not_found"},
+		{http.MethodPut, "/storage_periods/обувь", "PVZemployee", map[string]int{"days": 10}, http.StatusForbidden, "role_forbidden"},
+		{http.MethodPost, "/pvz/unknown/close_last_reception", "PVZemployee", nil, http.StatusConflict, "no_open_reception"},
+	}
+	for _, tc := range cases {
+		name := fmt.Sprintf("%s %s", tc.method, tc.url)
+		w := makeAuthorizedRequest(t, r, tc.method, tc.url, tc.role, tc.body)
+		require.Equal(t, tc.status, w.Code, "%s: %s", name, w.Body.String())
+		problem := decodeProblem(t, w)
+		assert.Equal(t, tc.code, problem.Code, name)
+		assert.Equal(t, http.StatusText(tc.status), problem.Title, name)
+		assert.NotEmpty(t, problem.RequestID, name)
+	}
+
+	// Constraint violations are reported without the database text
+	register := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"email": "dup@example.com", "password": "secret", "role": "employee"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", "client-request.1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	require.Equal(t, http.StatusCreated, register().Code)
+	w := register()
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	problem := decodeProblem(t, w)
+	assert.Equal(t, "email_taken", problem.Code)
+	assert.Equal(t, "client-request.1", problem.RequestID)
+	assert.NotContains(t, w.Body.String(), "UNIQUE")
+}
+
+func TestAppErrors(t *testing.T) {
+	detailed := db.ErrPVZNotFound.Withf("PVZ %s not found", "pvz-1")
+	assert.True(t, errors.Is(detailed, db.ErrPVZNotFound))
+	assert.Equal(t, "pvz_not_found", detailed.Code)
+
+	wrapped := fmt.Errorf("failed to close reception: %w", db.ErrReceptionClosed)
+	e := apperr.From(wrapped)
+	assert.Equal(t, apperr.KindConflict, e.Kind)
+	assert.Equal(t, "reception_closed", e.Code)
+	assert.Equal(t, wrapped.Error(), e.Message)
+	assert.True(t, errors.Is(e, db.ErrReceptionClosed))
+
+	cause := errors.New("disk I/O error")
+	e = apperr.From(cause)
+	assert.Equal(t, apperr.KindInternal, e.Kind)
+	assert.Equal(t, "internal error", e.Message)
+	assert.True(t, errors.Is(e, cause))
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRouter returns the API router backed by a fresh in-memory database.
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.SetupRoutes(r)
+	require.NoError(t, db.InitDB(":memory:"))
+	return r
+}
+
+// makeAuthorizedRequest performs a request carrying the token and role cookies
+// that the JWT and role middlewares expect.
+func makeAuthorizedRequest(t *testing.T, r *gin.Engine, method, url, role string, body interface{}) *httptest.ResponseRecorder {
+	return makeRequestWithRoles(t, r, method, url, role, role, body)
+}
+
+// makeRequestWithRoles is makeAuthorizedRequest with a role cookie that may
+// disagree with the role in the token, as a client could send.
+func makeRequestWithRoles(t *testing.T, r *gin.Engine, method, url, tokenRole, cookieRole string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(jsonBody)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	token, err := middleware.GenerateToken(models.User{Email: "staff@example.com", Role: tokenRole})
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	req.AddCookie(&http.Cookie{Name: "role", Value: cookieRole})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
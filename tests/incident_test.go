@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidents(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/receptions", "PVZemployee", map[string]string{"pvzId": "pvz-1"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var reception models.Reception
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reception))
+	require.NoError(t, db.InsertProduct(models.Product{ID: "product-1", DateTime: time.Now(), Type: "обувь", ReceptionId: reception.ID}))
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/products/product-1/incidents", "PVZemployee",
+		map[string]string{"type": models.IncidentDamaged, "description": "Коробка смята"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var damaged models.Incident
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &damaged))
+	assert.Equal(t, reception.ID, damaged.ReceptionId)
+	assert.Equal(t, "pvz-1", damaged.PvzId)
+	assert.Equal(t, models.IncidentStatusOpen, damaged.Status)
+	assert.Equal(t, "staff@example.com", damaged.ReportedBy)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions/"+reception.ID+"/incidents", "PVZemployee",
+		map[string]string{"type": models.IncidentMissingSeal})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var seal models.Incident
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &seal))
+
+	for _, body := range []map[string]string{
+		{"type": "lost"},
+		{"type": models.IncidentOther},
+		{"type": models.IncidentDamaged, "productId": "unknown"},
+	} {
+		w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions/"+reception.ID+"/incidents", "PVZemployee", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/pvz/pvz-1/close_last_reception", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// The closed reception is highlighted while incidents stay open
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz/pvz-1", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var pvz models.PVZ
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pvz))
+	require.Len(t, pvz.FlaggedReceptions, 1)
+	assert.Equal(t, reception.ID, pvz.FlaggedReceptions[0].ReceptionId)
+	assert.Equal(t, 2, pvz.FlaggedReceptions[0].OpenIncidents)
+	assert.NotNil(t, pvz.FlaggedReceptions[0].ClosedAt)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/incidents/"+damaged.ID+"/resolve", "PVZemployee", map[string]string{"resolution": "ok"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/incidents/"+damaged.ID+"/resolve", "Moderator", map[string]string{"resolution": " "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/incidents/"+damaged.ID+"/resolve", "Moderator",
+		map[string]string{"resolution": "Компенсация получена от поставщика"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resolved models.Incident
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resolved))
+	assert.Equal(t, models.IncidentStatusResolved, resolved.Status)
+	assert.NotNil(t, resolved.ResolvedAt)
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/incidents/"+damaged.ID+"/resolve", "Moderator", map[string]string{"resolution": "again"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/incidents?status=open&pvzId=pvz-1", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var open []models.Incident
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &open))
+	require.Len(t, open, 1)
+	assert.Equal(t, seal.ID, open[0].ID)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/"+reception.ID, "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var details models.ReceptionDetails
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &details))
+	assert.Equal(t, 1, details.OpenIncidents)
+	assert.Len(t, details.Incidents, 2)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/incidents/"+seal.ID+"/resolve", "Moderator", map[string]string{"resolution": "Пломба заменена"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz/pvz-1", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	pvz = models.PVZ{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pvz))
+	assert.Empty(t, pvz.FlaggedReceptions)
+}
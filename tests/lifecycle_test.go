@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/notify"
+	"github.com/StepOne-ai/pvz_avito/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductLifecycle(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+
+	stub := notify.NewStub(nil)
+	previous := routes.Notifier
+	routes.Notifier = stub
+	defer func() { routes.Notifier = previous }()
+
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/orders", "PVZemployee", map[string]string{
+		"orderNumber": "A-1001", "pvzId": "pvz-1", "recipientPhone": "+79161234567",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var order models.Order
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+
+	addProduct := func(body map[string]string) models.Product {
+		body["type"] = "одежда"
+		body["pvzId"] = "pvz-1"
+		w := makeAuthorizedRequest(t, r, http.MethodPost, "/products", "PVZemployee", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var product models.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		return product
+	}
+	parcel := addProduct(map[string]string{"barcode": "4006381333931", "orderId": order.ID})
+	returned := addProduct(map[string]string{})
+	assert.Equal(t, models.ProductStatusReceived, parcel.Status)
+
+	// Products are handed over against the order's pickup code only
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/pvz/pvz-1/issue", "PVZemployee", map[string]string{"pickupCode": "4821"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/pvz/pvz-1/close_last_reception", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/pickup_code", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	code := regexp.MustCompile(`\d{6}$`).FindString(stub.Messages()[0].Text)
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/issue", "PVZemployee", map[string]string{"pickupCode": code})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+	require.Len(t, order.Products, 1)
+	assert.Equal(t, parcel.ID, order.Products[0].ID)
+	assert.Equal(t, models.ProductStatusIssued, order.Products[0].Status)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/products/"+parcel.ID+"/return", "PVZemployee", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/products/"+returned.ID+"/return", "PVZemployee", map[string]string{"reason": "refused"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/products/"+parcel.ID+"/history", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var history []models.ProductStatusChange
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history, 3)
+	assert.Equal(t, models.ProductStatusReceived, history[0].Status)
+	assert.Equal(t, models.ProductStatusStored, history[1].Status)
+	assert.Equal(t, models.ProductStatusIssued, history[2].Status)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/products/"+returned.ID+"/history", "PVZemployee", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history, 3)
+	assert.Equal(t, models.ProductStatusReturnedToSender, history[2].Status)
+	assert.Equal(t, "refused", history[2].Comment)
+
+	// Products that left the PVZ free up capacity and their barcodes
+	occupancy, err := db.GetPVZOccupancy("pvz-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, occupancy.Total)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/products/by-barcode/4006381333931", "PVZemployee", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/middleware"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postManifestFile(t *testing.T, r *gin.Engine, url, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	token, err := middleware.GenerateToken(models.User{Email: "staff@example.com", Role: "Moderator"})
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	req.AddCookie(&http.Cookie{Name: "role", Value: "Moderator"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestManifestImport(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-2", "Казань", "2023-01-01T00:00:00Z"))
+
+	csv := "ПВЗ,Штрихкод,Категория,Кол-во\n" +
+		"pvz-1,PKG-1,одежда,\n" +
+		"pvz-1,,обувь,3\n" +
+		"pvz-2,PKG-2,электроника,1\n" +
+		"pvz-2,PKG-1,мебель,x\n" +
+		"pvz-9,,одежда,1\n"
+	url := "/manifests/import?pvzColumn=ПВЗ&barcodeColumn=Штрихкод&typeColumn=Категория&quantityColumn=Кол-во"
+
+	// The dry run reports every broken row and creates nothing
+	w := postManifestFile(t, r, url+"&dryRun=true", "text/csv", csv)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result models.ManifestImport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 5, result.Rows)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 5, result.Errors[0].Line)
+	assert.Equal(t, "Кол-во", result.Errors[0].Field)
+	assert.Equal(t, 6, result.Errors[1].Line)
+	assert.Equal(t, "pvzId", result.Errors[1].Field)
+
+	w = postManifestFile(t, r, url, "text/csv", csv)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	receptions, err := db.GetReceptionsByPVZ("pvz-1")
+	require.NoError(t, err)
+	assert.Empty(t, receptions)
+
+	w = postManifestFile(t, r, "/manifests/import?format=json&pvzId=pvz-1&dryRun=true", "application/octet-stream",
+		`{"items": [{"barcode": "PKG-1", "type": "мебель"}, {"barcode": "PKG-1", "type": "одежда"}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "type", result.Errors[0].Field)
+	assert.Equal(t, "barcode", result.Errors[1].Field)
+
+	w = postManifestFile(t, r, "/manifests/import?pvzId=pvz-1&source=supplier-7", "application/json",
+		`[{"barcode": 4006381333931, "type": "электроника"}, {"type": "одежда", "quantity": 2}]`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result.Receptions, 1)
+	assert.Equal(t, 3, result.Receptions[0].Expected)
+	pendingId := result.Receptions[0].ReceptionId
+
+	m, err := db.GetManifest(pendingId)
+	require.NoError(t, err)
+	assert.Equal(t, "supplier-7", m.Source)
+	assert.Equal(t, "4006381333931", m.Items[0].Barcode)
+
+	// Opening a reception when the truck arrives picks up the pending one
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions", "PVZemployee", map[string]string{"pvzId": "pvz-1"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var reception models.Reception
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reception))
+	assert.Equal(t, pendingId, reception.ID)
+	assert.Equal(t, "in_progress", reception.Status)
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceptionReconciliation(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPut, "/receptions/reception-1/manifest", "PVZemployee", map[string]interface{}{
+		"items": []map[string]interface{}{{"barcode": "PKG-1", "type": "одежда", "quantity": 2}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/receptions/reception-1/manifest", "PVZemployee", map[string]interface{}{
+		"source": "supplier-42",
+		"items": []map[string]interface{}{
+			{"barcode": "PKG-1", "type": "одежда"},
+			{"barcode": "PKG-2", "type": "электроника"},
+			{"barcode": "PKG-3", "type": "обувь"},
+			{"type": "одежда", "quantity": 2},
+		},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/reception-1/reconciliation", "PVZemployee", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	for i, product := range []struct{ barcode, productType string }{
+		{"PKG-1", "одежда"},
+		{"PKG-2", "обувь"},
+		{"", "одежда"},
+		{"PKG-9", "электроника"},
+	} {
+		require.NoError(t, db.InsertProduct(models.Product{
+			ID: fmt.Sprintf("product-%d", i), Type: product.productType, ReceptionId: "reception-1", Barcode: product.barcode,
+		}))
+	}
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/pvz/pvz-1/close_last_reception", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var closed struct {
+		Reconciliation models.Reconciliation `json:"reconciliation"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &closed))
+	assert.Equal(t, "reception-1", closed.Reconciliation.ReceptionId)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/reception-1/reconciliation", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report models.Reconciliation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 5, report.Expected)
+	assert.Equal(t, 4, report.Received)
+	assert.Equal(t, 2, report.Matched)
+	assert.Equal(t, []models.ManifestItem{
+		{Barcode: "PKG-3", Type: "обувь", Quantity: 1},
+		{Type: "одежда", Quantity: 1},
+	}, report.Missing)
+	require.Len(t, report.Extra, 1)
+	assert.Equal(t, "PKG-9", report.Extra[0].Barcode)
+	require.Len(t, report.Mismatched, 1)
+	assert.Equal(t, "электроника", report.Mismatched[0].ExpectedType)
+	assert.Equal(t, "обувь", report.Mismatched[0].Type)
+
+	// The manifest cannot be replaced once the reception is closed
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/receptions/reception-1/manifest", "PVZemployee", map[string]interface{}{
+		"items": []map[string]interface{}{{"type": "одежда"}},
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductMeasurements(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPut, "/product_types/обувь", "Moderator", map[string]interface{}{
+		"names": map[string]string{"ru": "Обувь"}, "sizeClass": "medium",
+		"maxWeightGrams": 3000, "maxDimensions": map[string]int{"lengthMm": 400, "widthMm": 300, "heightMm": 200},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/pvz/pvz-1/capacity", "Moderator", map[string]interface{}{"volumeCm3": 30000})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	addProduct := func(weight int, length, width, height int) *httptest.ResponseRecorder {
+		body := map[string]interface{}{"type": "обувь", "pvzId": "pvz-1", "weightGrams": weight}
+		if length > 0 {
+			body["dimensions"] = map[string]int{"lengthMm": length, "widthMm": width, "heightMm": height}
+		}
+		return makeAuthorizedRequest(t, r, http.MethodPost, "/products", "PVZemployee", body)
+	}
+
+	// A box fits in any orientation, but not when it is too heavy or too long
+	w = addProduct(1200, 200, 350, 100)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var product models.Product
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+	assert.Equal(t, &models.Dimensions{LengthMm: 200, WidthMm: 350, HeightMm: 100}, product.Dimensions)
+	assert.Equal(t, http.StatusBadRequest, addProduct(3500, 0, 0, 0).Code)
+	assert.Equal(t, http.StatusBadRequest, addProduct(500, 450, 100, 100).Code)
+	assert.Equal(t, http.StatusBadRequest, addProduct(500, 100, 0, 100).Code)
+
+	require.Equal(t, http.StatusCreated, addProduct(800, 0, 0, 0).Code)
+	require.Equal(t, http.StatusCreated, addProduct(700, 300, 200, 100).Code)
+
+	// 7000 + 6000 cm3 stored, another 20000 cm3 does not fit into 30000
+	assert.Equal(t, http.StatusConflict, addProduct(700, 400, 250, 200).Code)
+
+	occupancy, err := db.GetPVZOccupancy("pvz-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2700), occupancy.WeightGrams)
+	assert.Equal(t, int64(13000), occupancy.VolumeCm3)
+	require.NotNil(t, occupancy.VolumeAvailableCm3)
+	assert.Equal(t, int64(17000), *occupancy.VolumeAvailableCm3)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/reception-1", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var details models.ReceptionDetails
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &details))
+	assert.Equal(t, int64(2700), details.TotalWeightGrams)
+	assert.Equal(t, int64(13000), details.TotalVolumeCm3)
+}
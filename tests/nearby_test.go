@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/geo"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistance(t *testing.T) {
+	// Moscow to Saint Petersburg is roughly 634 km
+	distance := geo.Distance(55.7558, 37.6173, 59.9343, 30.3351)
+	assert.InDelta(t, 634000, distance, 5000)
+	assert.Zero(t, geo.Distance(55.7558, 37.6173, 55.7558, 37.6173))
+}
+
+func TestGetPVZsNearby(t *testing.T) {
+	r := newTestRouter(t)
+
+	today := time.Now().In(models.PVZLocation).Format("2006-01-02")
+	pvzs := []models.PVZ{
+		// Red Square
+		{ID: "pvz-center", City: "Москва", Latitude: 55.7539, Longitude: 37.6208},
+		// Tverskaya, about 1.3 km away and closed today
+		{ID: "pvz-tverskaya", City: "Москва", Latitude: 55.7649, Longitude: 37.6056,
+			Holidays: []models.HolidayOverride{{Date: today, Closed: true}}},
+		// Saint Petersburg
+		{ID: "pvz-spb", City: "Санкт-Петербург", Latitude: 59.9343, Longitude: 30.3351},
+		// No location
+		{ID: "pvz-unknown", City: "Казань"},
+	}
+	for i := range pvzs {
+		pvzs[i].RegistrationDate = time.Now()
+		require.NoError(t, db.CreatePVZWithDetails(&pvzs[i]))
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/pvz/nearby?lat=55.7558&lon=37.6173&radius=3000", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var nearby []models.NearbyPVZ
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nearby))
+	require.Len(t, nearby, 2)
+	assert.Equal(t, "pvz-center", nearby[0].ID)
+	assert.Equal(t, "pvz-tverskaya", nearby[1].ID)
+	assert.Less(t, nearby[0].Distance, nearby[1].Distance)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/pvz/nearby?lat=55.7558&lon=37.6173&radius=3000&openNow=true", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nearby))
+	require.Len(t, nearby, 1)
+	assert.Equal(t, "pvz-center", nearby[0].ID)
+
+	// The index follows its PVZs when the implicit rowids change, as VACUUM
+	// may do, and when they move afterwards
+	_, err := db.DB.Exec(`UPDATE pvzs SET rowid = rowid + 100`)
+	require.NoError(t, err)
+	_, err = db.DB.Exec(`UPDATE pvzs SET latitude = 55.7560, longitude = 37.6180 WHERE id = 'pvz-spb'`)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/pvz/nearby?lat=55.7558&lon=37.6173&radius=3000", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nearby))
+	require.Len(t, nearby, 3)
+	assert.Equal(t, "pvz-spb", nearby[0].ID)
+	assert.Equal(t, "pvz-center", nearby[1].ID)
+	assert.Equal(t, "pvz-tverskaya", nearby[2].ID)
+
+	for _, query := range []string{"lat=abc&lon=37", "lat=55&lon=37&radius=-1", "lat=95&lon=37", "lat=55&lon=37&openNow=maybe"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/pvz/nearby?"+query, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/openapi"
+	"github.com/StepOne-ai/pvz_avito/internal/routes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.SetupRoutes(r)
+
+	var registered []openapi.Route
+	for _, route := range r.Routes() {
+		registered = append(registered, openapi.Route{Method: route.Method, Path: route.Path})
+	}
+	undocumented, unrouted := routes.API.CheckRoutes(registered)
+	assert.Empty(t, undocumented, "routes missing from the OpenAPI document")
+	assert.Empty(t, unrouted, "documented operations without a route")
+
+	w := makeAuthorizedRequest(t, r, http.MethodGet, "/openapi.json", "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var document struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	assert.Contains(t, document.Paths["/pvz/{pvzId}"], "get")
+	assert.Contains(t, document.Paths["/receptions/{receptionId}/products:batch"], "post")
+	assert.Contains(t, document.Components.Schemas, "PVZ")
+	assert.Contains(t, document.Components.Schemas, "ReceptionDetails")
+}
+
+func TestRequestValidation(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+
+	cases := []struct {
+		method, url, role string
+		body              interface{}
+		fields            []models.FieldError
+	}{
+		{http.MethodPost, "/pvz", "PVZemployee", map[string]interface{}{"city": "Тверь", "latitude": "north"}, []models.FieldError{
+			{Field: "city", Message: "must be one of Москва, Казань, Санкт-Петербург"},
+			{Field: "latitude", Message: "must be a number"},
+		}},
+		{http.MethodPost, "/receptions/reception-1/products:batch", "PVZemployee", map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"type": "обувь"},
+				map[string]interface{}{"barcode": "PKG-1", "weightGrams": 1.5, "dimensions": map[string]int{"lengthMm": 0, "widthMm": 10, "heightMm": 10}},
+			},
+		}, []models.FieldError{
+			{Field: "items[1].type", Message: "is required"},
+			{Field: "items[1].dimensions.lengthMm", Message: "must be between 1 and 10000"},
+			{Field: "items[1].weightGrams", Message: "must be an integer"},
+		}},
+		{http.MethodPost, "/receptions", "Moderator", nil, []models.FieldError{{Message: "request body is required"}}},
+		{http.MethodPost, "/receptions", "Moderator", "pvz-1", []models.FieldError{{Message: "must be an object"}}},
+		{http.MethodPut, "/storage_periods/обувь", "Moderator", map[string]int{"days": 400}, []models.FieldError{
+			{Field: "days", Message: "must be between 1 and 365"},
+		}},
+		{http.MethodGet, "/pvz/nearby?lon=37.6&radius=-1", "", nil, []models.FieldError{
+			{Field: "lat", Message: "is required"},
+			{Field: "radius", Message: "must be between 0 and 50000"},
+		}},
+		{http.MethodGet, "/pvz?limit=ten&hasOpenReception=yes", "Moderator", nil, []models.FieldError{
+			{Field: "hasOpenReception", Message: "must be true or false"},
+			{Field: "limit", Message: "must be an integer"},
+		}},
+	}
+	for _, tc := range cases {
+		name := fmt.Sprintf("%s %s", tc.method, tc.url)
+		w := makeAuthorizedRequest(t, r, tc.method, tc.url, tc.role, tc.body)
+		require.Equal(t, http.StatusBadRequest, w.Code, name)
+		var e models.Error
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &e), name)
+		assert.Equal(t, "Invalid request", e.Message, name)
+		assert.ElementsMatch(t, tc.fields, e.Fields, name)
+	}
+
+	// Valid requests still reach the handlers
+	w := makeAuthorizedRequest(t, r, http.MethodGet, "/pvz/nearby?lat=55.75&lon=37.61&openNow=false", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions/reception-1/products:batch", "PVZemployee", map[string]interface{}{
+		"items": []map[string]interface{}{{"type": "обувь", "weightGrams": 500, "dimensions": nil}},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Access is checked before the request is
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/storage_periods/обувь", "PVZemployee", map[string]int{"days": 400})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	req, err := http.NewRequest(http.MethodPost, "/pvz", strings.NewReader(`{"city": "Тверь"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	anonymous := httptest.NewRecorder()
+	r.ServeHTTP(anonymous, req)
+	assert.Equal(t, http.StatusUnauthorized, anonymous.Code, anonymous.Body.String())
+}
+
+// Responses of a typical day at a PVZ match the document.
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var problems []string
+	r.Use(routes.API.ResponseValidator(func(c *gin.Context, errs []models.FieldError) {
+		for _, e := range errs {
+			problems = append(problems, fmt.Sprintf("%s %s: %d %s %s", c.Request.Method, c.FullPath(), c.Writer.Status(), e.Field, e.Message))
+		}
+	}))
+	routes.SetupRoutes(r)
+	require.NoError(t, db.InitDB(":memory:"))
+
+	request := func(method, url, role string, body interface{}, status int) []byte {
+		w := makeAuthorizedRequest(t, r, method, url, role, body)
+		require.Equal(t, status, w.Code, "%s %s: %s", method, url, w.Body.String())
+		return w.Body.Bytes()
+	}
+
+	var pvz models.PVZ
+	require.NoError(t, json.Unmarshal(request(http.MethodPost, "/pvz", "PVZemployee", map[string]interface{}{
+		"city": "Москва", "address": "ул. Тверская, 1", "latitude": 55.76, "longitude": 37.61, "phone": "+74950000000",
+	}, http.StatusCreated), &pvz))
+	base := "/pvz/" + pvz.ID
+	request(http.MethodPut, base+"/capacity", "Moderator", map[string]interface{}{"total": 100, "byType": map[string]int{"обувь": 10}}, http.StatusOK)
+	request(http.MethodPost, base+"/cells", "Moderator", map[string]interface{}{"zone": "A", "rack": "1", "shelf": "1"}, http.StatusCreated)
+
+	var reception models.Reception
+	require.NoError(t, json.Unmarshal(request(http.MethodPost, "/receptions", "PVZemployee", map[string]string{"pvzId": pvz.ID}, http.StatusCreated), &reception))
+	var product models.Product
+	require.NoError(t, json.Unmarshal(request(http.MethodPost, "/products", "PVZemployee", map[string]interface{}{
+		"type": "обувь", "pvzId": pvz.ID, "barcode": "4601234567893", "weightGrams": 800,
+		"dimensions": map[string]int{"lengthMm": 300, "widthMm": 200, "heightMm": 120},
+	}, http.StatusCreated), &product))
+	request(http.MethodPost, "/receptions/"+reception.ID+"/products:batch", "PVZemployee", map[string]interface{}{
+		"items": []map[string]string{{"type": "одежда"}, {"type": "электроника"}},
+	}, http.StatusCreated)
+	request(http.MethodPost, "/receptions/"+reception.ID+"/incidents", "PVZemployee", map[string]string{"type": "damaged", "productId": product.ID}, http.StatusCreated)
+	request(http.MethodPost, base+"/close_last_reception", "PVZemployee", nil, http.StatusOK)
+
+	for _, url := range []string{
+		"/pvz", "/pvz?sort=activity&count=true", "/pvz/nearby?lat=55.75&lon=37.61", base, base + "/occupancy", base + "/cells",
+		base + "/expiring?days=30", base + "/return_batches",
+		"/receptions/" + reception.ID, "/receptions/" + reception.ID + "/products", "/receptions/" + reception.ID + "/incidents",
+		"/receptions/" + reception.ID + "/audit", "/receptions/stale", "/products/by-barcode/4601234567893",
+		"/products/" + product.ID + "/cell", "/products/" + product.ID + "/history", "/incidents", "/search?q=4601",
+		"/storage_periods", "/product_types", "/product_types/обувь",
+	} {
+		request(http.MethodGet, url, "Moderator", nil, http.StatusOK)
+	}
+	request(http.MethodGet, "/pvz/unknown", "Moderator", nil, http.StatusNotFound)
+	request(http.MethodPost, "/pvz", "PVZemployee", map[string]string{"city": "Тверь"}, http.StatusBadRequest)
+
+	assert.Empty(t, problems)
+}
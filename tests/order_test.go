@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/notify"
+	"github.com/StepOne-ai/pvz_avito/internal/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderPickup(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-2", "Казань", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+
+	stub := notify.NewStub(nil)
+	previous := routes.Notifier
+	routes.Notifier = stub
+	defer func() { routes.Notifier = previous }()
+
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/orders", "PVZemployee", map[string]string{
+		"orderNumber": "A-1001", "pvzId": "pvz-1", "recipientPhone": "+79161234567",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var order models.Order
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+
+	for i := 0; i < 2; i++ {
+		w = makeAuthorizedRequest(t, r, http.MethodPost, "/products", "PVZemployee", map[string]string{
+			"type": "одежда", "pvzId": "pvz-1", "orderId": order.ID,
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/pickup_code", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	messages := stub.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "+79161234567", messages[0].Recipient)
+	code := regexp.MustCompile(`\d{6}$`).FindString(messages[0].Text)
+	require.NotEmpty(t, code)
+
+	// Items still inside an open reception cannot be handed over
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/issue", "PVZemployee", map[string]string{"pickupCode": code})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/pvz/pvz-1/close_last_reception", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/issue", "PVZemployee", map[string]string{"pickupCode": "000000"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/issue", "PVZemployee", map[string]string{"pickupCode": code})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+	assert.Equal(t, models.OrderStatusIssued, order.Status)
+	require.Len(t, order.Products, 2)
+	for _, product := range order.Products {
+		assert.Equal(t, models.ProductStatusIssued, product.Status)
+	}
+
+	// The code is single use
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/issue", "PVZemployee", map[string]string{"pickupCode": code})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/orders/by-number/A-1001", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders", "PVZemployee", map[string]string{
+		"orderNumber": "A-1001", "pvzId": "pvz-1", "recipientPhone": "+79161234567",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestOrderPickupCodeAttempts(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/orders", "PVZemployee", map[string]string{
+		"orderNumber": "A-2002", "pvzId": "pvz-1", "recipientPhone": "89161234567",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var order models.Order
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/issue", "PVZemployee", map[string]string{"pickupCode": "123456"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/pickup_code", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for i := 0; i < 5; i++ {
+		w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/issue", "PVZemployee", map[string]string{"pickupCode": "abc"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	}
+	var e models.Error
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/orders/"+order.ID+"/issue", "PVZemployee", map[string]string{"pickupCode": "abc"})
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &e))
+	assert.Equal(t, db.ErrPickupCodeBlocked.Error(), e.Message)
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pageLink returns the URL of the given relation in a Link header.
+func pageLink(header http.Header, rel string) string {
+	match := regexp.MustCompile(`<([^>]+)>; rel="` + rel + `"`).FindStringSubmatch(header.Get("Link"))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func pvzIDs(t *testing.T, body []byte) []string {
+	var pvzs []models.PVZ
+	require.NoError(t, json.Unmarshal(body, &pvzs))
+	ids := []string{}
+	for _, pvz := range pvzs {
+		ids = append(ids, pvz.ID)
+	}
+	return ids
+}
+
+func TestPVZCursorPagination(t *testing.T) {
+	r := newTestRouter(t)
+
+	// pvz-3 and pvz-4 share a registration date, so the ID breaks the tie
+	dates := []string{"2023-01-01", "2023-02-01", "2023-03-01", "2023-03-01", "2023-04-01", "2023-05-01", "2023-06-01"}
+	for i, date := range dates {
+		require.NoError(t, db.CreatePVZ(fmt.Sprintf("pvz-%d", i+1), "Москва", date+"T00:00:00Z"))
+	}
+	expected := []string{"pvz-7", "pvz-6", "pvz-5", "pvz-4", "pvz-3", "pvz-2", "pvz-1"}
+
+	w := makeAuthorizedRequest(t, r, http.MethodGet, "/pvz?limit=3&count=true", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "7", w.Header().Get("X-Total-Count"))
+	assert.Empty(t, pageLink(w.Header(), "prev"))
+
+	var seen []string
+	pages := [][]string{}
+	for {
+		ids := pvzIDs(t, w.Body.Bytes())
+		pages = append(pages, ids)
+		seen = append(seen, ids...)
+		next := pageLink(w.Header(), "next")
+		if next == "" {
+			break
+		}
+		assert.Contains(t, next, "count=true")
+		w = makeAuthorizedRequest(t, r, http.MethodGet, next, "Moderator", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	assert.Equal(t, expected, seen)
+	require.Len(t, pages, 3)
+
+	// Walk back from the last page
+	for i := len(pages) - 2; i >= 0; i-- {
+		prev := pageLink(w.Header(), "prev")
+		require.NotEmpty(t, prev)
+		w = makeAuthorizedRequest(t, r, http.MethodGet, prev, "Moderator", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, pages[i], pvzIDs(t, w.Body.Bytes()))
+	}
+	assert.Empty(t, pageLink(w.Header(), "prev"))
+
+	// Page numbers still work and link onwards with cursors
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz?page=2&limit=3", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"pvz-4", "pvz-3", "pvz-2"}, pvzIDs(t, w.Body.Bytes()))
+	assert.NotEmpty(t, pageLink(w.Header(), "prev"))
+	assert.NotContains(t, pageLink(w.Header(), "next"), "page=")
+
+	// New PVZs do not shift the pages of a client that is already paging
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz?limit=3", "Moderator", nil)
+	next := pageLink(w.Header(), "next")
+	require.NoError(t, db.CreatePVZ("pvz-8", "Москва", time.Now().Format(time.RFC3339)))
+	w = makeAuthorizedRequest(t, r, http.MethodGet, next, "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, pages[1], pvzIDs(t, w.Body.Bytes()))
+
+	for _, query := range []string{"page=0", "limit=0", "limit=101", "page=x", "cursor=bogus", "cursor=" + next[len("/pvz?cursor="):] + "&page=2", "count=maybe"} {
+		w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz?"+query, "Moderator", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestReceptionProductsPagination(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/receptions", "PVZemployee", map[string]string{"pvzId": "pvz-1"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var reception models.Reception
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reception))
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.InsertProduct(models.Product{
+			ID: fmt.Sprintf("product-%d", i), DateTime: start.Add(time.Duration(i/2) * time.Minute), Type: "обувь", ReceptionId: reception.ID,
+		}))
+	}
+
+	url := "/receptions/" + reception.ID + "/products?limit=2&count=true"
+	var seen []string
+	for url != "" {
+		w = makeAuthorizedRequest(t, r, http.MethodGet, url, "PVZemployee", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "5", w.Header().Get("X-Total-Count"))
+		var products []models.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &products))
+		for _, product := range products {
+			seen = append(seen, product.ID)
+		}
+		url = pageLink(w.Header(), "next")
+	}
+	assert.Equal(t, []string{"product-4", "product-3", "product-2", "product-1", "product-0"}, seen)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/unknown/products", "PVZemployee", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// collectPages follows the next links from url and then walks back through
+// the prev links, returning the values of field on each page going forward.
+func collectPages(t *testing.T, r *gin.Engine, url, field string) [][]string {
+	values := func(body []byte) []string {
+		var items []map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &items))
+		result := []string{}
+		for _, item := range items {
+			result = append(result, fmt.Sprint(item[field]))
+		}
+		return result
+	}
+
+	var pages [][]string
+	w := makeAuthorizedRequest(t, r, http.MethodGet, url, "Moderator", nil)
+	for {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		pages = append(pages, values(w.Body.Bytes()))
+		next := pageLink(w.Header(), "next")
+		if next == "" {
+			break
+		}
+		w = makeAuthorizedRequest(t, r, http.MethodGet, next, "Moderator", nil)
+	}
+	for i := len(pages) - 2; i >= 0; i-- {
+		prev := pageLink(w.Header(), "prev")
+		require.NotEmpty(t, prev, url)
+		w = makeAuthorizedRequest(t, r, http.MethodGet, prev, "Moderator", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, pages[i], values(w.Body.Bytes()), url)
+	}
+	return pages
+}
+
+func TestStorageListsPagination(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+	// product-5 was taken in Moscow time, between product-1 and product-2;
+	// product-2 and product-4 share a time, so the ID breaks the tie
+	intake := map[string]string{
+		"product-1": "2023-01-02T00:01:00Z",
+		"product-2": "2023-01-02T00:02:00Z",
+		"product-3": "2023-01-02T00:03:00Z",
+		"product-4": "2023-01-02T00:02:00Z",
+		"product-5": "2023-01-02T03:01:30+03:00",
+		"product-6": "2023-01-02T00:04:00Z",
+	}
+	for id, dateTime := range intake {
+		require.NoError(t, db.CreateProduct(id, dateTime, "одежда", "reception-1"))
+	}
+	require.NoError(t, db.CloseLastReception("pvz-1"))
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/products/product-6/lost", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	pages := collectPages(t, r, "/pvz/pvz-1/expiring?days=0&limit=2", "id")
+	assert.Equal(t, [][]string{{"product-1", "product-5"}, {"product-2", "product-4"}, {"product-3"}}, pages)
+
+	pages = collectPages(t, r, "/products/product-6/history?limit=2", "status")
+	assert.Equal(t, [][]string{
+		{models.ProductStatusReceived, models.ProductStatusStored}, {models.ProductStatusLost},
+	}, pages)
+
+	// Zone A sorts before AB whatever the rack and shelf
+	for i, address := range [][3]string{{"AB", "1", "1"}, {"A", "2", "1"}, {"A", "1", "2"}, {"B", "1", "1"}, {"A", "1", "1"}} {
+		require.NoError(t, db.CreateStorageCell(models.StorageCell{
+			ID: fmt.Sprintf("cell-%d", i), PvzId: "pvz-1", Zone: address[0], Rack: address[1], Shelf: address[2],
+		}))
+	}
+	pages = collectPages(t, r, "/pvz/pvz-1/cells?limit=2", "id")
+	assert.Equal(t, [][]string{{"cell-4", "cell-2"}, {"cell-1", "cell-0"}, {"cell-3"}}, pages)
+
+	created := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{0, time.Hour, time.Hour, 2 * time.Hour} {
+		require.NoError(t, db.CreateReturnBatch(models.ReturnBatch{
+			ID: fmt.Sprintf("batch-%d", i), PvzId: "pvz-1", Status: models.ReturnBatchPending, CreatedAt: created.Add(offset),
+		}))
+	}
+	pages = collectPages(t, r, "/pvz/pvz-1/return_batches?limit=3", "id")
+	assert.Equal(t, [][]string{{"batch-3", "batch-2", "batch-1"}, {"batch-0"}}, pages)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz/pvz-1/cells?count=true&limit=1", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "5", w.Header().Get("X-Total-Count"))
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz/pvz-1/expiring?limit=0", "Moderator", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductTypeCatalogue(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+
+	listTypes := func(url string) []string {
+		w := makeAuthorizedRequest(t, r, http.MethodGet, url, "Moderator", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var productTypes []models.ProductType
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &productTypes))
+		var codes []string
+		for _, productType := range productTypes {
+			codes = append(codes, productType.Code)
+		}
+		return codes
+	}
+	assert.Equal(t, []string{"обувь", "одежда", "электроника"}, listTypes("/product_types"))
+	w := makeAuthorizedRequest(t, r, http.MethodGet, "/product_types?limit=2", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"электроника"}, listTypes(pageLink(w.Header(), "next")))
+
+	furniture := map[string]interface{}{
+		"names": map[string]string{"ru": "Мебель", "en": "Furniture"}, "sizeClass": "huge", "storagePeriodDays": 3,
+	}
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/product_types/мебель", "Moderator", furniture)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	furniture["sizeClass"] = models.SizeClassOversized
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/product_types/мебель", "PVZemployee", furniture)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/product_types/мебель", "Moderator", furniture)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/product_types/мебель", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var productType models.ProductType
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &productType))
+	assert.Equal(t, "Furniture", productType.Names["en"])
+	assert.True(t, productType.Active)
+	periods, err := db.GetStoragePeriods()
+	require.NoError(t, err)
+	assert.Equal(t, []models.StoragePeriod{{Type: "мебель", Days: 3}}, periods)
+
+	addProduct := func(productType string) int {
+		w := makeAuthorizedRequest(t, r, http.MethodPost, "/products", "PVZemployee", map[string]string{
+			"type": productType, "pvzId": "pvz-1",
+		})
+		return w.Code
+	}
+	assert.Equal(t, http.StatusCreated, addProduct("мебель"))
+	assert.Equal(t, http.StatusBadRequest, addProduct("игрушки"))
+
+	// A retired type stays in the catalogue but new products of it are refused
+	furniture["active"] = false
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/product_types/мебель", "Moderator", furniture)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, addProduct("мебель"))
+	assert.NotContains(t, listTypes("/product_types"), "мебель")
+	assert.Contains(t, listTypes("/product_types?all=true"), "мебель")
+	w = makeRequestWithRoles(t, r, http.MethodGet, "/product_types?all=true", "PVZemployee", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "мебель")
+
+	// The storage period only changes when it is given
+	delete(furniture, "storagePeriodDays")
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/product_types/мебель", "Moderator", furniture)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &productType))
+	assert.Equal(t, 3, productType.StoragePeriodDays)
+	furniture["storagePeriodDays"] = 0
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/product_types/мебель", "Moderator", furniture)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	periods, err = db.GetStoragePeriods()
+	require.NoError(t, err)
+	assert.Empty(t, periods)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions/reception-1/products:batch", "PVZemployee", map[string]interface{}{
+		"items": []map[string]string{{"type": "одежда"}, {"type": "игрушки"}},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPVZFilters(t *testing.T) {
+	r := newTestRouter(t)
+
+	require.NoError(t, db.CreatePVZ("pvz-a", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-b", "Казань", "2023-02-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-c", "Москва", "2023-03-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-d", "Санкт-Петербург", "2023-04-01T00:00:00Z"))
+
+	// pvz-a received shoes in May and is receiving again; pvz-b received
+	// electronics in June; pvz-c and pvz-d never received anything
+	require.NoError(t, db.CreateReception("rec-a1", "2024-05-10T10:00:00Z", "pvz-a", "close"))
+	require.NoError(t, db.InsertProduct(models.Product{ID: "p-1", DateTime: time.Date(2024, 5, 10, 10, 5, 0, 0, time.UTC), Type: "обувь", ReceptionId: "rec-a1"}))
+	require.NoError(t, db.CreateReception("rec-a2", "2024-07-01T09:00:00Z", "pvz-a", "in_progress"))
+	require.NoError(t, db.CreateReception("rec-b1", "2024-06-15T12:00:00Z", "pvz-b", "close"))
+	require.NoError(t, db.InsertProduct(models.Product{ID: "p-2", DateTime: time.Date(2024, 6, 15, 12, 30, 0, 0, time.UTC), Type: "электроника", ReceptionId: "rec-b1"}))
+
+	list := func(query string) []string {
+		w := makeAuthorizedRequest(t, r, http.MethodGet, "/pvz?"+query, "Moderator", nil)
+		require.Equal(t, http.StatusOK, w.Code, query+": "+w.Body.String())
+		return pvzIDs(t, w.Body.Bytes())
+	}
+
+	cases := map[string][]string{
+		"": {"pvz-d", "pvz-c", "pvz-b", "pvz-a"},
+		"city=" + url.QueryEscape("Москва"):                                        {"pvz-c", "pvz-a"},
+		"city=" + url.QueryEscape("Москва") + "&city=" + url.QueryEscape("Казань"): {"pvz-c", "pvz-b", "pvz-a"},
+		"status=in_progress":     {"pvz-a"},
+		"status=close":           {"pvz-b"},
+		"status=none":            {"pvz-d", "pvz-c"},
+		"hasOpenReception=false": {"pvz-d", "pvz-c", "pvz-b"},
+		"productType=" + url.QueryEscape("обувь"):                               {"pvz-a"},
+		"receptionFrom=2024-06-01&receptionTo=2024-06-30":                       {"pvz-b"},
+		"receptionTo=2024-05-10":                                                {"pvz-a"},
+		"productType=" + url.QueryEscape("обувь") + "&receptionFrom=2024-06-01": {},
+		"startDate=2023-02-01&endDate=2023-03-01":                               {"pvz-c", "pvz-b"},
+		"startDate=2023-02-01T00:00:00%2B03:00":                                 {"pvz-d", "pvz-c", "pvz-b"},
+		"sort=city":                                                             {"pvz-b", "pvz-a", "pvz-c", "pvz-d"},
+		"sort=registrationDate&order=asc":                                       {"pvz-a", "pvz-b", "pvz-c", "pvz-d"},
+		"sort=activity":                                                         {"pvz-a", "pvz-b", "pvz-d", "pvz-c"},
+	}
+	for query, expected := range cases {
+		assert.Equal(t, expected, list(query), query)
+	}
+
+	// Cursors follow the requested sort order, also across the two Moscow PVZs
+	w := makeAuthorizedRequest(t, r, http.MethodGet, "/pvz?sort=city&limit=2", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"pvz-b", "pvz-a"}, pvzIDs(t, w.Body.Bytes()))
+	w = makeAuthorizedRequest(t, r, http.MethodGet, pageLink(w.Header(), "next"), "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"pvz-c", "pvz-d"}, pvzIDs(t, w.Body.Bytes()))
+	w = makeAuthorizedRequest(t, r, http.MethodGet, pageLink(w.Header(), "prev"), "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"pvz-b", "pvz-a"}, pvzIDs(t, w.Body.Bytes()))
+
+	for _, query := range []string{
+		"startDate=2023-13-01",
+		"endDate=yesterday",
+		"receptionFrom=2024-06-01T25:00:00Z",
+		"startDate=2023-05-01&endDate=2023-01-01",
+		"startDate=2023-01-01&startDate=2023-02-01",
+		"status=open",
+		"hasOpenReception=maybe",
+		"productType=unknown",
+		"sort=distance",
+		"order=up",
+		"city=",
+		"town=" + url.QueryEscape("Москва"),
+	} {
+		w := makeAuthorizedRequest(t, r, http.MethodGet, "/pvz?"+query, "Moderator", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// Bounds compare instants, whatever offset a time was stored with: this
+	// PVZ opened late on April 30 in UTC
+	require.NoError(t, db.CreatePVZ("pvz-e", "Москва", "2023-05-01T01:00:00+03:00"))
+	assert.Equal(t, []string{"pvz-e"}, list("startDate=2023-04-30&endDate=2023-04-30"))
+	assert.Empty(t, list("startDate=2023-05-01"))
+}
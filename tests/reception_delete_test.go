@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteProductFromReception(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+	require.NoError(t, db.CreateProduct("product-1", "2023-01-02T00:01:00Z", "электроника", "reception-1"))
+	require.NoError(t, db.CreateProduct("product-2", "2023-01-02T00:02:00Z", "одежда", "reception-1"))
+	require.NoError(t, db.CreateProduct("product-3", "2023-01-02T00:03:00Z", "обувь", "reception-1"))
+
+	// Remove a product scanned before the last one
+	w := makeAuthorizedRequest(t, r, http.MethodDelete, "/receptions/reception-1/products/product-1", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	products, err := db.GetProductsByReception("reception-1")
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, "product-3", products[0].ID)
+	assert.Equal(t, "product-2", products[1].ID)
+
+	w = makeAuthorizedRequest(t, r, http.MethodDelete, "/receptions/reception-1/products/product-1", "PVZemployee", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/reception-1/audit", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var entries []models.AuditEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "product_deleted", entries[0].Action)
+	assert.Equal(t, "staff@example.com", entries[0].Actor)
+	var deleted models.Product
+	require.NoError(t, json.Unmarshal(entries[0].Details, &deleted))
+	assert.Equal(t, "product-1", deleted.ID)
+	assert.Equal(t, "электроника", deleted.Type)
+
+	require.NoError(t, db.CloseLastReception("pvz-1"))
+	w = makeAuthorizedRequest(t, r, http.MethodDelete, "/receptions/reception-1/products/product-2", "PVZemployee", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
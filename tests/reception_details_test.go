@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceptionDetails(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/receptions", "PVZemployee", map[string]string{"pvzId": "pvz-1"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var reception models.Reception
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reception))
+
+	start := time.Now().Add(-time.Hour)
+	for i, productType := range []string{"одежда", "одежда", "обувь", "электроника", "одежда"} {
+		require.NoError(t, db.InsertProduct(models.Product{
+			ID: fmt.Sprintf("product-%d", i), DateTime: start.Add(time.Duration(i) * time.Minute), Type: productType, ReceptionId: reception.ID,
+		}))
+	}
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/"+reception.ID+"?page=2&limit=2", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var details models.ReceptionDetails
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &details))
+	assert.Equal(t, "pvz-1", details.Pvz.ID)
+	assert.Equal(t, "staff@example.com", details.OpenedBy)
+	assert.Nil(t, details.ClosedAt)
+	assert.Equal(t, 5, details.ProductCount)
+	assert.Equal(t, map[string]int{"одежда": 3, "обувь": 1, "электроника": 1}, details.CountsByType)
+	require.Len(t, details.Products, 2)
+	assert.Equal(t, "product-2", details.Products[0].ID)
+	assert.Equal(t, "product-1", details.Products[1].ID)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/"+reception.ID+"?limit=0", "Moderator", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/unknown", "Moderator", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/pvz/pvz-1/close_last_reception", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/"+reception.ID, "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &details))
+	assert.Equal(t, "close", details.Status)
+	assert.Equal(t, "staff@example.com", details.ClosedBy)
+	require.NotNil(t, details.ClosedAt)
+	assert.Equal(t, int64(details.ClosedAt.Sub(details.DateTime)/time.Second), details.DurationSeconds)
+	assert.Len(t, details.Products, 5)
+}
@@ -1,11 +1,14 @@
 package tests
 
 import (
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateReceptionInDB(t *testing.T) {
@@ -39,3 +42,42 @@ func TestCreateReceptionInDB(t *testing.T) {
 	assert.Equal(t, pvzID, reception.PvzId)
 	assert.Equal(t, status, reception.Status)
 }
+
+func TestDuplicateReceptionsClosedOnStartup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pvz.db")
+	require.NoError(t, db.InitDB(path))
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-2", "Москва", "2023-01-01T00:00:00Z"))
+
+	// A database from before the unique index, with two receptions in
+	// progress at pvz-1; the later one was written with a Moscow offset
+	_, err := db.DB.Exec(`DROP INDEX receptions_in_progress`)
+	require.NoError(t, err)
+	require.NoError(t, db.CreateReception("reception-old", "2023-01-02T10:00:00Z", "pvz-1", models.ReceptionStatusInProgress))
+	require.NoError(t, db.CreateReception("reception-new", "2023-01-02T14:00:00+03:00", "pvz-1", models.ReceptionStatusInProgress))
+	require.NoError(t, db.CreateReception("reception-other", "2023-01-01T10:00:00Z", "pvz-2", models.ReceptionStatusInProgress))
+	require.NoError(t, db.CreateProduct("product-1", "2023-01-02T10:05:00Z", "обувь", "reception-old"))
+	require.NoError(t, db.DB.Close())
+
+	require.NoError(t, db.InitDB(path))
+	// Some tests use whatever database is open, so leave a usable one behind
+	t.Cleanup(func() {
+		db.DB.Close()
+		db.InitDB(":memory:")
+	})
+	for id, status := range map[string]string{
+		"reception-old":   models.ReceptionStatusClosed,
+		"reception-new":   models.ReceptionStatusInProgress,
+		"reception-other": models.ReceptionStatusInProgress,
+	} {
+		reception, err := db.GetReceptionByID(id)
+		require.NoError(t, err)
+		assert.Equal(t, status, reception.Status, id)
+	}
+	product, err := db.GetProductByID("product-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductStatusStored, product.Status)
+
+	// The index is back, so a second reception in progress is refused
+	assert.Error(t, db.CreateReception("reception-2", "2023-01-03T10:00:00Z", "pvz-2", models.ReceptionStatusInProgress))
+}
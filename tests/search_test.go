@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	r := newTestRouter(t)
+	t.Logf("search module: %s", db.SearchModule())
+
+	now := time.Now()
+	require.NoError(t, db.CreatePVZWithDetails(&models.PVZ{ID: "pvz-tver", RegistrationDate: now, City: "Москва", Address: "ул. Тверская, 7 <корпус 2>"}))
+	require.NoError(t, db.CreatePVZWithDetails(&models.PVZ{ID: "pvz-city", RegistrationDate: now, City: "Тверь", Address: "ул. Советская, 1"}))
+	require.NoError(t, db.CreatePVZWithDetails(&models.PVZ{ID: "pvz-spb", RegistrationDate: now, City: "Санкт-Петербург", Address: "Невский проспект, 28"}))
+	require.NoError(t, db.CreateReception("rec-1", now.Format(time.RFC3339), "pvz-spb", "in_progress"))
+	require.NoError(t, db.InsertProduct(models.Product{ID: "product-1", DateTime: now, Type: "обувь", ReceptionId: "rec-1", Barcode: "4601234567890"}))
+	require.NoError(t, db.CreateOrder(models.Order{ID: "order-1", OrderNumber: "A-100500", PvzId: "pvz-spb", RecipientPhone: "+79990000000", Status: "created", CreatedAt: now}))
+
+	search := func(query string) []models.SearchResult {
+		w := makeAuthorizedRequest(t, r, http.MethodGet, "/search?"+query, "PVZemployee", nil)
+		require.Equal(t, http.StatusOK, w.Code, query+": "+w.Body.String())
+		var results []models.SearchResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+		return results
+	}
+
+	// A city match ranks above an address match, and prefixes match whole words
+	results := search("q=" + url.QueryEscape("твер"))
+	require.Len(t, results, 2)
+	assert.Equal(t, "pvz-city", results[0].ID)
+	assert.Equal(t, "<mark>Тверь</mark>", results[0].Title)
+	assert.Equal(t, "pvz-tver", results[1].ID)
+	assert.Equal(t, "ул. <mark>Тверская</mark>, 7 &lt;корпус 2&gt;", results[1].Snippet)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	results = search("q=" + url.QueryEscape("Тверская 7"))
+	require.Len(t, results, 1)
+	assert.Equal(t, "pvz-tver", results[0].ID)
+
+	results = search("q=4601234")
+	require.Len(t, results, 1)
+	assert.Equal(t, models.SearchResult{
+		Kind: db.SearchKindProduct, ID: "product-1", PvzId: "pvz-spb", Title: "<mark>4601234567890</mark>",
+		Snippet: "обувь product-1", Score: results[0].Score,
+	}, results[0])
+
+	results = search("q=A-100500&kind=order,pvz")
+	require.Len(t, results, 1)
+	assert.Equal(t, db.SearchKindOrder, results[0].Kind)
+	assert.Equal(t, "pvz-spb", results[0].PvzId)
+	assert.Empty(t, search("q=A-100500&kind=product"))
+
+	// The index follows changes made after the initial fill
+	_, err := db.DB.Exec(`UPDATE products SET barcode = '2000000000015' WHERE id = 'product-1'`)
+	require.NoError(t, err)
+	assert.Empty(t, search("q=4601234"))
+	assert.Len(t, search("q=2000000000015"), 1)
+	_, err = db.DB.Exec(`DELETE FROM products WHERE id = 'product-1'`)
+	require.NoError(t, err)
+	assert.Empty(t, search("q=2000000000015"))
+
+	// Results stay with their rows when the implicit rowids change, as VACUUM
+	// may do
+	_, err = db.DB.Exec(`UPDATE pvzs SET rowid = rowid + 100`)
+	require.NoError(t, err)
+	results = search("q=" + url.QueryEscape("Невский"))
+	require.Len(t, results, 1)
+	assert.Equal(t, "pvz-spb", results[0].ID)
+
+	for _, query := range []string{"", "q=", "q=%20-%20", "q=x&limit=0", "q=x&limit=101", "q=x&kind=user"} {
+		w := makeAuthorizedRequest(t, r, http.MethodGet, "/search?"+query, "PVZemployee", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/apperr"
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/manifest"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/pagination"
+	"github.com/StepOne-ai/pvz_avito/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The rules hold without any HTTP front end in between.
+func TestServices(t *testing.T) {
+	require.NoError(t, db.InitDB(":memory:"))
+	// A Sunday evening in Moscow
+	now := time.Date(2024, 3, 10, 21, 0, 0, 0, models.PVZLocation)
+	services := service.NewWithClock(func() time.Time { return now })
+
+	_, err := services.PVZ.Create(service.NewPVZ{City: "Тверь"})
+	assert.Equal(t, "invalid_city", apperr.From(err).Code)
+	_, err = services.PVZ.Create(service.NewPVZ{City: "Москва", Phone: "12"})
+	assert.Equal(t, "invalid_phone", apperr.From(err).Code)
+
+	pvz, err := services.PVZ.Create(service.NewPVZ{
+		City:         "Казань",
+		WorkingHours: []models.WorkingHours{{Weekday: "Monday", Open: "09:00", Close: "21:00"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, now, pvz.RegistrationDate)
+	assert.Equal(t, "monday", pvz.WorkingHours[0].Weekday)
+
+	// Closed on Sundays, so only a moderator may open a reception and only explicitly
+	_, err = services.Receptions.Open(service.NewReception{PvzId: pvz.ID, Actor: "staff@example.com", Role: "Moderator"})
+	assert.True(t, errors.Is(err, service.ErrPVZClosed))
+	_, err = services.Receptions.Open(service.NewReception{PvzId: pvz.ID, Override: true, Role: "PVZemployee"})
+	assert.True(t, errors.Is(err, service.ErrOverrideForbidden))
+	_, err = services.Receptions.Open(service.NewReception{PvzId: "unknown"})
+	assert.Equal(t, apperr.KindValidation, apperr.KindOf(err))
+	assert.Equal(t, "pvz_not_found", apperr.From(err).Code)
+
+	reception, err := services.Receptions.Open(service.NewReception{PvzId: pvz.ID, Override: true, Actor: "staff@example.com", Role: "Moderator"})
+	require.NoError(t, err)
+	assert.Equal(t, models.ReceptionStatusInProgress, reception.Status)
+	assert.True(t, now.Equal(reception.DateTime))
+	_, err = services.Receptions.Open(service.NewReception{PvzId: pvz.ID, Override: true, Role: "Moderator"})
+	assert.True(t, errors.Is(err, db.ErrReceptionInProgress))
+	assert.Error(t, db.CreateReception("reception-2", "2024-03-10T21:00:00Z", pvz.ID, models.ReceptionStatusInProgress))
+
+	product, warning, err := services.Products.Add(pvz.ID, "", service.NewProduct{Type: "обувь", Barcode: "4601234567893"})
+	require.NoError(t, err)
+	assert.Empty(t, warning)
+	assert.Equal(t, reception.ID, product.ReceptionId)
+	_, _, err = services.Products.Add(pvz.ID, "", service.NewProduct{Type: "обувь", Barcode: "4601234567893"})
+	assert.True(t, errors.Is(err, service.ErrDuplicateScan))
+
+	report, err := services.PVZ.CloseLastReception(pvz.ID, "staff@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, report)
+	_, err = services.PVZ.CloseLastReception(pvz.ID, "staff@example.com")
+	assert.True(t, errors.Is(err, db.ErrNoOpenReception))
+	_, _, err = services.Products.Add("", reception.ID, service.NewProduct{Type: "обувь"})
+	assert.True(t, errors.Is(err, db.ErrReceptionClosed))
+	_, err = services.Receptions.GetOpen(reception.ID)
+	assert.True(t, errors.Is(err, db.ErrReceptionClosed))
+
+	_, err = services.Auth.Register("not-an-email", "secret", "employee")
+	assert.Equal(t, "invalid_email", apperr.From(err).Code)
+	_, err = services.Auth.Register("staff@example.com", "secret", "employee")
+	require.NoError(t, err)
+	_, _, err = services.Auth.Login("staff@example.com", "wrong")
+	assert.True(t, errors.Is(err, db.ErrInvalidCredentials))
+	token, user, err := services.Auth.Login("staff@example.com", "secret")
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, "employee", user.Role)
+}
+
+// The rules moved out of the handlers reject bad input before the database is touched.
+func TestServicesValidateInput(t *testing.T) {
+	require.NoError(t, db.InitDB(":memory:"))
+	services := service.New()
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+
+	_, err := services.Search.Search("  ", nil, 10)
+	assert.Equal(t, apperr.KindValidation, apperr.KindOf(err))
+	_, err = services.Search.Search("Москва", []string{"pvz", "parcel"}, 10)
+	assert.Equal(t, apperr.KindValidation, apperr.KindOf(err))
+
+	_, err = services.Storage.SetPeriod("обувь", service.MaxStoragePeriodDays+1)
+	assert.Equal(t, apperr.KindValidation, apperr.KindOf(err))
+	_, _, err = services.Storage.Expiring("pvz-1", -1, pagination.Request{Limit: 10})
+	assert.Equal(t, apperr.KindValidation, apperr.KindOf(err))
+
+	_, err = services.Manifests.Attach("reception-1", "", "staff@example.com", nil)
+	assert.Equal(t, apperr.KindValidation, apperr.KindOf(err))
+
+	csv := "pvzId,barcode,type,quantity\npvz-1,,обувь,2\npvz-1,,unknown,1\n"
+	result, err := services.Manifests.Import(service.ManifestFile{Format: manifest.FormatCSV, Body: strings.NewReader(csv), Source: "import"})
+	assert.True(t, errors.Is(err, service.ErrImportRejected))
+	require.NotNil(t, result)
+	assert.Equal(t, 2, result.Rows)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 3, result.Errors[0].Line)
+
+	csv = "pvzId,barcode,type,quantity\npvz-1,,обувь,2\n"
+	result, err = services.Manifests.Import(service.ManifestFile{Format: manifest.FormatCSV, Body: strings.NewReader(csv), DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, result.Receptions, 1)
+	assert.Equal(t, 2, result.Receptions[0].Expected)
+	assert.Empty(t, result.Receptions[0].ReceptionId)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/events"
+	"github.com/StepOne-ai/pvz_avito/internal/jobs"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleReceptions(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-2", "Казань", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-3", "Сочи", "2023-01-01T00:00:00Z"))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, db.CreateReception("reception-1", now.Add(-13*time.Hour).Format(time.RFC3339), "pvz-1", "in_progress"))
+	require.NoError(t, db.CreateProduct("product-1", now.Add(-13*time.Hour).Format(time.RFC3339), "одежда", "reception-1"))
+	require.NoError(t, db.CreateReception("reception-2", now.Add(-2*time.Hour).Format(time.RFC3339), "pvz-2", "in_progress"))
+	require.NoError(t, db.CreateReception("reception-3", now.Add(-2*time.Hour).Format(time.RFC3339), "pvz-3", "in_progress"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPut, "/pvz/pvz-2/reception_timeout", "Moderator", map[string]interface{}{"minutes": 60, "action": "delete"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/pvz/pvz-2/reception_timeout", "Moderator", map[string]interface{}{"minutes": 60, "action": "flag"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// The dry run lists what the job would do and leaves the receptions open
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/stale", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var stale []models.StaleReception
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stale))
+	require.Len(t, stale, 2)
+	assert.Equal(t, "reception-1", stale[0].ID)
+	assert.Equal(t, models.StaleActionClose, stale[0].Action)
+	assert.Equal(t, "reception-2", stale[1].ID)
+	assert.Equal(t, models.StaleActionFlag, stale[1].Action)
+	assert.Equal(t, 60, stale[1].TimeoutMinutes)
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/receptions/stale?limit=1&count=true", "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stale))
+	require.Len(t, stale, 1)
+	assert.Equal(t, "reception-1", stale[0].ID)
+	assert.Equal(t, "2", w.Header().Get("X-Total-Count"))
+	w = makeAuthorizedRequest(t, r, http.MethodGet, pageLink(w.Header(), "next"), "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stale))
+	require.Len(t, stale, 1)
+	assert.Equal(t, "reception-2", stale[0].ID)
+	assert.Empty(t, pageLink(w.Header(), "next"))
+
+	closedBefore := staleReceptionsClosed(t)
+	published := events.NewLog(nil)
+	job := &jobs.StaleReceptions{Events: published, Now: func() time.Time { return now }}
+	require.NoError(t, job.Run(context.Background()))
+	require.NoError(t, job.Run(context.Background()))
+
+	reception, err := db.GetReceptionByID("reception-1")
+	require.NoError(t, err)
+	assert.Equal(t, "close", reception.Status)
+	product, err := db.GetProductByID("product-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductStatusStored, product.Status)
+
+	reception, err = db.GetReceptionByID("reception-2")
+	require.NoError(t, err)
+	assert.Equal(t, "in_progress", reception.Status)
+
+	// A flagged reception is reported once, not on every run
+	require.Len(t, published.Events(), 2)
+	assert.Equal(t, jobs.EventReceptionAutoClosed, published.Events()[0].Type)
+	assert.Equal(t, jobs.EventReceptionStale, published.Events()[1].Type)
+	assert.Equal(t, closedBefore+1, staleReceptionsClosed(t))
+
+	entries, err := db.GetAuditLog("reception", "reception-1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "system", entries[0].Actor)
+}
+
+func staleReceptionsClosed(t *testing.T) float64 {
+	var metric dto.Metric
+	require.NoError(t, jobs.StaleReceptionsTotal.WithLabelValues(models.StaleActionClose).Write(&metric))
+	return metric.GetCounter().GetValue()
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/jobs"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/StepOne-ai/pvz_avito/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageDeadlineReturnBatches(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZWithDetails(&models.PVZ{
+		ID: "pvz-1", City: "Москва", RegistrationDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Phone: "+79990000000",
+	}))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-1", "in_progress"))
+	require.NoError(t, db.CreateProduct("product-1", "2023-01-02T00:01:00Z", "одежда", "reception-1"))
+	require.NoError(t, db.CreateProduct("product-2", "2023-01-02T00:02:00Z", "электроника", "reception-1"))
+	require.NoError(t, db.CloseLastReception("pvz-1"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPut, "/storage_periods/одежда", "PVZemployee", map[string]int{"days": 2})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/storage_periods/одежда", "Moderator", map[string]int{"days": 0})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = makeAuthorizedRequest(t, r, http.MethodPut, "/storage_periods/одежда", "Moderator", map[string]int{"days": 2})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Both products are long overdue today, so the listing returns them soonest first
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz/pvz-1/expiring?days=3", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var expiring []models.ExpiringProduct
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &expiring))
+	require.Len(t, expiring, 2)
+	assert.Equal(t, "product-1", expiring[0].ID)
+	assert.True(t, expiring[0].Overdue)
+
+	// Three days after intake only the clothes have run past their two-day period
+	stub := notify.NewStub(nil)
+	job := &jobs.StorageDeadline{
+		Notifier: stub,
+		Now:      func() time.Time { return time.Date(2023, 1, 5, 0, 0, 0, 0, time.UTC) },
+	}
+	require.NoError(t, job.Run(context.Background()))
+	require.NoError(t, job.Run(context.Background()))
+
+	batches, err := db.GetReturnBatchesByPVZ("pvz-1")
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, []string{"product-1"}, batches[0].ProductIds)
+	assert.Equal(t, models.ReturnBatchPending, batches[0].Status)
+	require.Len(t, stub.Messages(), 1)
+	assert.Equal(t, "+79990000000", stub.Messages()[0].Recipient)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/return_batches/"+batches[0].ID+"/dispatch", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/return_batches/"+batches[0].ID+"/dispatch", "PVZemployee", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	product, err := db.GetProductByID("product-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductStatusReturnedToSender, product.Status)
+
+	occupancy, err := db.GetPVZOccupancy("pvz-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, occupancy.Total)
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferBetweenPVZs(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-src", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-dst", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-src", "in_progress"))
+	for _, id := range []string{"product-1", "product-2", "product-3"} {
+		require.NoError(t, db.CreateProduct(id, "2023-01-02T00:01:00Z", "одежда", "reception-1"))
+	}
+	_, err := db.DB.Exec(`UPDATE products SET barcode = '4601234567893' WHERE id = 'product-1'`)
+	require.NoError(t, err)
+
+	body := map[string]interface{}{
+		"sourcePvzId": "pvz-src", "destinationPvzId": "pvz-dst", "productIds": []string{"product-1", "product-2"},
+	}
+
+	// Products of an open reception are not on the shelves yet
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/transfers", "PVZemployee", body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	require.NoError(t, db.CloseLastReception("pvz-src"))
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/transfers", "PVZemployee", body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var transfer models.Transfer
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfer))
+
+	occupancy, err := db.GetPVZOccupancy("pvz-src")
+	require.NoError(t, err)
+	assert.Equal(t, 1, occupancy.Total)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/transfers/"+transfer.ID+"/in_transit", "PVZemployee", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// The barcode travels with the product and cannot be received elsewhere meanwhile
+	require.NoError(t, db.CreateReception("reception-2", "2023-01-03T00:00:00Z", "pvz-dst", "in_progress"))
+	err = db.InsertProducts([]models.Product{{ID: "product-4", DateTime: time.Now(), Type: "одежда",
+		ReceptionId: "reception-2", Barcode: "4601234567893"}})
+	assert.ErrorIs(t, err, db.ErrBarcodeStored)
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions/reception-2/products:batch", "PVZemployee", map[string]interface{}{
+		"items": []map[string]string{{"type": "одежда", "barcode": "4601234567893"}, {"type": "одежда"}},
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	var summary models.BatchSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Rejected)
+	assert.Equal(t, models.BatchItemRejected, summary.Items[0].Status)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/transfers/"+transfer.ID+"/receive", "PVZemployee", map[string]interface{}{
+		"productIds": []string{"product-1", "product-3"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result struct {
+		Transfer    models.Transfer            `json:"transfer"`
+		Discrepancy models.TransferDiscrepancy `json:"discrepancy"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, models.TransferStatusReceived, result.Transfer.Status)
+	assert.Equal(t, []string{"product-2"}, result.Discrepancy.Missing)
+	assert.Equal(t, []string{"product-3"}, result.Discrepancy.Unexpected)
+
+	reception, err := db.GetReceptionByID(result.Transfer.ReceptionId)
+	require.NoError(t, err)
+	assert.Equal(t, "pvz-dst", reception.PvzId)
+	assert.Equal(t, models.ReceptionKindTransfer, reception.Kind)
+
+	occupancy, err = db.GetPVZOccupancy("pvz-dst")
+	require.NoError(t, err)
+	assert.Equal(t, 1, occupancy.Total)
+
+	// The product moved but still belongs to the reception it arrived with
+	received, err := db.GetProductByID("product-1")
+	require.NoError(t, err)
+	assert.Equal(t, "reception-1", received.ReceptionId)
+	location, err := db.GetProductLocation("product-1")
+	require.NoError(t, err)
+	assert.Equal(t, "pvz-dst", location.PvzId)
+
+	missing, err := db.GetProductByID("product-2")
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductStatusLost, missing.Status)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/transfers/"+transfer.ID+"/receive", "PVZemployee", map[string]interface{}{
+		"productIds": []string{"product-1"},
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestTransferRespectsDestinationCapacity(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-src", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.CreatePVZ("pvz-dst", "Москва", "2023-01-01T00:00:00Z"))
+	require.NoError(t, db.SetPVZCapacity("pvz-dst", models.Capacity{Total: 1, Policy: models.CapacityPolicyReject}))
+	require.NoError(t, db.CreateReception("reception-1", "2023-01-02T00:00:00Z", "pvz-src", "in_progress"))
+	for _, id := range []string{"product-1", "product-2"} {
+		require.NoError(t, db.CreateProduct(id, "2023-01-02T00:01:00Z", "одежда", "reception-1"))
+	}
+	require.NoError(t, db.CloseLastReception("pvz-src"))
+
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/transfers", "PVZemployee", map[string]interface{}{
+		"sourcePvzId": "pvz-src", "destinationPvzId": "pvz-dst", "productIds": []string{"product-1", "product-2"},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var transfer models.Transfer
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfer))
+
+	// Two products do not fit a PVZ that holds one; nothing is received
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/transfers/"+transfer.ID+"/receive", "PVZemployee", map[string]interface{}{
+		"productIds": []string{"product-1", "product-2"},
+	})
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	product, err := db.GetProductByID("product-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductStatusInTransit, product.Status)
+
+	// One arriving and one missing does fit
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/transfers/"+transfer.ID+"/receive", "PVZemployee", map[string]interface{}{
+		"productIds": []string{"product-1"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	occupancy, err := db.GetPVZOccupancy("pvz-dst")
+	require.NoError(t, err)
+	assert.Equal(t, 1, occupancy.Total)
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/StepOne-ai/pvz_avito/internal/db"
+	"github.com/StepOne-ai/pvz_avito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsOpenAt(t *testing.T) {
+	pvz := models.PVZ{
+		WorkingHours: []models.WorkingHours{
+			{Weekday: "monday", Open: "09:00", Close: "21:00"},
+		},
+		Holidays: []models.HolidayOverride{
+			{Date: "2024-01-08", Closed: true},
+		},
+	}
+
+	// 2024-01-15 is a Monday; times are given in UTC, Moscow is UTC+3
+	assert.True(t, pvz.IsOpenAt(time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)))
+	assert.False(t, pvz.IsOpenAt(time.Date(2024, 1, 15, 5, 59, 0, 0, time.UTC)))
+	assert.False(t, pvz.IsOpenAt(time.Date(2024, 1, 15, 18, 0, 0, 0, time.UTC)))
+	assert.False(t, pvz.IsOpenAt(time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC)))
+	assert.False(t, pvz.IsOpenAt(time.Date(2024, 1, 8, 7, 0, 0, 0, time.UTC)))
+
+	assert.True(t, (&models.PVZ{}).IsOpenAt(time.Now()))
+
+	// Without a weekly schedule a PVZ is open all day except on its holidays
+	holidaysOnly := models.PVZ{Holidays: []models.HolidayOverride{
+		{Date: "2024-01-08", Closed: true},
+		{Date: "2024-01-09", Open: "10:00", Close: "16:00"},
+	}}
+	assert.False(t, holidaysOnly.IsOpenAt(time.Date(2024, 1, 8, 7, 0, 0, 0, time.UTC)))
+	assert.True(t, holidaysOnly.IsOpenAt(time.Date(2024, 1, 9, 7, 0, 0, 0, time.UTC)))
+	assert.False(t, holidaysOnly.IsOpenAt(time.Date(2024, 1, 9, 14, 0, 0, 0, time.UTC)))
+	assert.True(t, holidaysOnly.IsOpenAt(time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC)))
+}
+
+func TestValidateSchedule(t *testing.T) {
+	assert.NoError(t, models.ValidateSchedule(
+		[]models.WorkingHours{{Weekday: "Friday", Open: "10:00", Close: "24:00"}},
+		[]models.HolidayOverride{{Date: "2024-12-31", Open: "10:00", Close: "16:00"}},
+	))
+	assert.Error(t, models.ValidateSchedule([]models.WorkingHours{{Weekday: "funday", Open: "10:00", Close: "20:00"}}, nil))
+	assert.Error(t, models.ValidateSchedule([]models.WorkingHours{{Weekday: "monday", Open: "20:00", Close: "10:00"}}, nil))
+	assert.Error(t, models.ValidateSchedule([]models.WorkingHours{{Weekday: "monday", Open: "9:00", Close: "10:00"}}, nil))
+	assert.Error(t, models.ValidateSchedule(nil, []models.HolidayOverride{{Date: "31.12.2024", Closed: true}}))
+}
+
+func TestCreatePVZWithDetails(t *testing.T) {
+	r := newTestRouter(t)
+
+	reqBody := map[string]interface{}{
+		"city":      "Казань",
+		"address":   "ул. Баумана, 1",
+		"latitude":  55.7887,
+		"longitude": 49.1221,
+		"phone":     "+78431234567",
+		"workingHours": []map[string]string{
+			{"weekday": "Monday", "open": "09:00", "close": "21:00"},
+		},
+		"holidays": []map[string]interface{}{
+			{"date": "2025-01-01", "closed": true},
+		},
+	}
+	w := makeAuthorizedRequest(t, r, http.MethodPost, "/pvz", "PVZemployee", reqBody)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created models.PVZ
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	w = makeAuthorizedRequest(t, r, http.MethodGet, "/pvz/"+created.ID, "Moderator", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.PVZ
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stored))
+	assert.Equal(t, "ул. Баумана, 1", stored.Address)
+	assert.Equal(t, 55.7887, stored.Latitude)
+	assert.Equal(t, "+78431234567", stored.Phone)
+	require.Len(t, stored.WorkingHours, 1)
+	assert.Equal(t, "monday", stored.WorkingHours[0].Weekday)
+	require.Len(t, stored.Holidays, 1)
+	assert.True(t, stored.Holidays[0].Closed)
+
+	reqBody["phone"] = "call me"
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/pvz", "PVZemployee", reqBody)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReceptionOutsideWorkingHours(t *testing.T) {
+	r := newTestRouter(t)
+	require.NoError(t, db.CreatePVZ("pvz-1", "Москва", "2023-01-01T00:00:00Z"))
+
+	today := time.Now().In(models.PVZLocation).Format("2006-01-02")
+	w := makeAuthorizedRequest(t, r, http.MethodPut, "/pvz/pvz-1/working_hours", "Moderator", map[string]interface{}{
+		"holidays": []map[string]interface{}{{"date": today, "closed": true}},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions", "PVZemployee", map[string]interface{}{"pvzId": "pvz-1"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions", "PVZemployee", map[string]interface{}{"pvzId": "pvz-1", "override": true})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The override goes by the role in the token, not the cookie the client sets
+	w = makeRequestWithRoles(t, r, http.MethodPost, "/receptions", "PVZemployee", "Moderator", map[string]interface{}{"pvzId": "pvz-1", "override": true})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, r, http.MethodPost, "/receptions", "Moderator", map[string]interface{}{"pvzId": "pvz-1", "override": true})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}